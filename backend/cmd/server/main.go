@@ -1,26 +1,117 @@
 package main
 
 import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
 	"log"
 	"mysql-admin-tool/internal/api"
+	"mysql-admin-tool/internal/api/middleware"
+	"mysql-admin-tool/internal/audit"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/history"
+	"mysql-admin-tool/internal/httpserver"
 	"mysql-admin-tool/internal/services"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
 func main() {
+	// "hash-password" prints the bcrypt hash of a password read from stdin for
+	// identity.users in the config file
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		hashPassword()
+		return
+	}
+
+	// Load configuration; PORT and FRONTEND_PATH override the config file
+	cfg, err := config.Load(getEnv("CONFIG_PATH", "/etc/go-dbadmin/config.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		if cfg.Server.Port, err = strconv.Atoi(port); err != nil {
+			log.Fatalf("Invalid PORT %q", port)
+		}
+	}
+	cfg.Frontend.Path = getEnv("FRONTEND_PATH", cfg.Frontend.Path)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Initialize authentication
 	services.InitAuth()
+	services.InitLoginLimiter(cfg.RateLimit.Login)
+
+	// Load saved connection profiles
+	if err := services.InitProfiles(getEnv("PROFILES_PATH", "./data/profiles.enc"), os.Getenv("PROFILES_KEY")); err != nil {
+		log.Fatalf("Failed to load connection profiles: %v", err)
+	}
+
+	// Load saved queries
+	if err := services.InitSavedQueries(cfg.SavedQueries.Path); err != nil {
+		log.Fatalf("Failed to load saved queries: %v", err)
+	}
+
+	// Application accounts and single sign-on
+	services.InitIdentity(cfg.Identity)
+
+	// Tokens signed with the public default secret could be forged to use stored
+	// credentials
+	if err := services.CheckJWTSecret(); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
+	// Statement policies of connections, e.g. read-only production servers
+	services.InitPolicies(cfg.Policies)
+	services.InitConfirmations(cfg.Confirm)
+
+	// Roll back transactions left open across requests once they are idle
+	services.InitTransactions(cfg.Transactions)
+	// Close console sessions of the SQL editor once they are idle
+	services.InitConsoles(cfg.Consoles)
+
+	// Record the statements run through the API
+	if err := audit.Init(cfg.Audit); err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer audit.Close()
+
+	// Keep each user's query history
+	if err := history.Init(cfg.History); err != nil {
+		log.Fatalf("Failed to open query history: %v", err)
+	}
+	defer history.Close()
+
+	// Snapshot tables before statements drop their data
+	if err := services.InitBackups(cfg.Backups); err != nil {
+		log.Fatalf("Failed to create backup directory: %v", err)
+	}
+
+	// Verify SSH tunnel hosts against this known_hosts file unless a connection
+	// provides its own
+	database.SetDefaultKnownHostsFile(os.Getenv("SSH_KNOWN_HOSTS"))
+
+	// Restrict the MySQL servers users may connect to
+	database.SetHostPolicy(cfg.Hosts)
 
 	// Database connection will be established per-user via login
 	// No need to initialize a default connection pool
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: len(cfg.Server.TrustedProxies) > 0,
+		TrustedProxies:          cfg.Server.TrustedProxies,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -35,24 +126,81 @@ func main() {
 	// Middleware
 	app.Use(recover.New())
 	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
-		AllowCredentials: true,
-	}))
+	if cfg.Server.TLS.Enabled && cfg.Server.TLS.HSTSMaxAge > 0 {
+		app.Use(middleware.HSTSMiddleware(cfg.Server.TLS.HSTSMaxAge, cfg.Server.TLS.HSTSIncludeSubdomains))
+	}
+	// Limit API requests per client IP
+	if cfg.RateLimit.API.MaxRequests > 0 {
+		app.Use("/api", limiter.New(limiter.Config{
+			Max:        cfg.RateLimit.API.MaxRequests,
+			Expiration: cfg.RateLimit.API.Window,
+			LimitReached: func(c *fiber.Ctx) error {
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"error": "Too many requests",
+				})
+			},
+		}))
+	}
+
+	// Cross-origin requests are only served for configured origins
+	if len(cfg.CORS.AllowOrigins) > 0 {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:     strings.Join(cfg.CORS.AllowOrigins, ","),
+			AllowMethods:     strings.Join(cfg.CORS.AllowMethods, ","),
+			AllowHeaders:     strings.Join(cfg.CORS.AllowHeaders, ","),
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}))
+	}
 
 	// Serve static files (frontend)
-	frontendPath := getEnv("FRONTEND_PATH", "./frontend/dist")
-	app.Static("/", frontendPath)
+	app.Static("/", cfg.Frontend.Path)
 
 	// Setup API routes
 	api.SetupRoutes(app)
 
 	// Start server
-	port := getEnv("PORT", "8090")
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(app.Listen(":" + port))
+	addr := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
+	if !cfg.Server.TLS.Enabled {
+		log.Printf("Server starting on %s", addr)
+		log.Fatal(app.Listen(addr))
+	}
+
+	certs, err := httpserver.NewCertReloader(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificate: %v", err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Server.TLS.RedirectHTTP {
+		redirectAddr := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.TLS.RedirectPort))
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", redirectAddr)
+			log.Fatal(httpserver.NewRedirectServer(redirectAddr, cfg.Server.Port).ListenAndServe())
+		}()
+	}
+
+	log.Printf("Server starting with TLS on %s", addr)
+	log.Fatal(app.Listener(tls.NewListener(ln, certs.TLSConfig())))
+}
+
+func hashPassword() {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		log.Fatal("Password must not be empty")
+	}
+	hash, err := services.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Failed to hash password: %v", err)
+	}
+	fmt.Println(hash)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/audit"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"mysql-admin-tool/internal/sqlstmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Audit actions of the API operations that run statements
+const (
+	auditQuery       = "query"
+	auditExplain     = "explain"
+	auditCreateTable = "create_table"
+	auditDropTable   = "drop_table"
+	auditTransaction = "transaction"
+
+	auditSetVariable     = "set_variable"
+	auditKillProcess     = "kill_process"
+	auditCreateUser      = "create_user"
+	auditDropUser        = "drop_user"
+	auditRenameUser      = "rename_user"
+	auditChangePassword  = "change_password"
+	auditLockUser        = "lock_user"
+	auditUnlockUser      = "unlock_user"
+	auditGrant           = "grant"
+	auditRevoke          = "revoke"
+	auditRestoreSnapshot = "restore_snapshot"
+)
+
+// GetAuditLog returns audit entries, newest first, filtered by the user,
+// connection, database, action, q (statement text), errors, since and until
+// query parameters
+func GetAuditLog(c *fiber.Ctx) error {
+	filter := audit.Filter{
+		User:       c.Query("user"),
+		Connection: c.Query("connection"),
+		Database:   c.Query("database"),
+		Action:     c.Query("action"),
+		Text:       c.Query("q"),
+		ErrorsOnly: c.QueryBool("errors"),
+		Limit:      c.QueryInt("limit", 100),
+	}
+	if filter.Limit < 1 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error: "Invalid " + name + " time, expected RFC 3339",
+			})
+		}
+		*t = parsed
+	}
+
+	entries, err := audit.Search(filter)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(entries)
+}
+
+// auditStatement records a statement run on the request's connection, in the
+// open transaction of the "transaction" local or the console of the "console"
+// local if set, or restored from the snapshot of the "snapshot" local, with the
+// parameters of the "query_params" local. rows is the number of rows
+// affected, or nil when the statement returned rows or failed.
+func auditStatement(c *fiber.Ctx, action, dbName, statement string, start time.Time, rows *int64, returned *int, errText string) {
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	user, method := requestUser(c)
+
+	entry := models.AuditEntry{
+		Time:           start.UTC(),
+		User:           user,
+		AuthMethod:     method,
+		SourceIP:       c.IP(),
+		Connection:     conn.ID,
+		ConnectionName: conn.Name,
+		Host:           conn.Config.Host,
+		Database:       dbName,
+		Action:         action,
+		Kind:           sqlstmt.Classify(statement).Kind,
+		Statement:      statement,
+		DurationMs:     float64(time.Since(start).Microseconds()) / 1000,
+		RowsAffected:   rows,
+		RowsReturned:   returned,
+		Error:          errText,
+	}
+	if conn.Config.Socket != "" {
+		entry.Host = conn.Config.Socket
+	}
+	if tx, ok := c.Locals("transaction").(string); ok {
+		entry.Transaction = tx
+	}
+	if console, ok := c.Locals("console").(*models.Console); ok {
+		entry.Console = console.ID
+	}
+	if snapshot, ok := c.Locals("snapshot").(string); ok {
+		entry.Snapshot = snapshot
+	}
+	if params, ok := c.Locals("query_params").([]models.QueryParam); ok {
+		entry.Params = params
+	}
+	audit.Record(entry)
+}
+
+// requestUser returns the application account of the request's session and its
+// login method, or the MySQL user of its connection
+func requestUser(c *fiber.Ctx) (string, string) {
+	session := c.Locals("session").(*services.Session)
+	if session.User != nil {
+		return session.User.Username, session.User.Method
+	}
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	return conn.Config.User, services.AuthMethodMySQL
+}
+
+// errorText returns the message of an error, or "" for nil
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
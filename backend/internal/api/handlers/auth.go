@@ -1,14 +1,27 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"mysql-admin-tool/internal/models"
 	"mysql-admin-tool/internal/services"
+	"net/url"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// oidcStateCookie holds the state of a pending OpenID Connect login
+const oidcStateCookie = "oidc_state"
+
 // Login handles user login with MySQL credentials
 func Login(c *fiber.Ctx) error {
+	if !services.MySQLLoginAllowed() {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Error: "Login with MySQL credentials is disabled",
+		})
+	}
+
 	var req models.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{
@@ -16,26 +29,35 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Set defaults
-	if req.Host == "" {
-		req.Host = "localhost"
+	config, storedPassword, err := services.ResolveLogin(req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
 	}
-	if req.Port == 0 {
-		req.Port = 3306
+	if storedPassword && !services.StoredPasswordLoginAllowed() {
+		return c.Status(403).JSON(models.ErrorResponse{
+			Error: "The stored password of a profile is not available to MySQL logins",
+		})
 	}
-	if req.Database == "" {
-		req.Database = "mysql"
+
+	// Refuse attempts while the client IP or the account is backing off; the
+	// attempt counts as failed until the credentials are validated
+	account := services.LoginAccount(config)
+	if err := services.ReserveLoginAttempt(c.IP(), account); err != nil {
+		return loginBlocked(c, err)
 	}
 
 	// Validate MySQL connection
-	if err := services.ValidateMySQLConnection(req.Host, req.Port, req.Username, req.Password, req.Database); err != nil {
+	if err := services.ValidateMySQLConnection(config); err != nil {
 		return c.Status(401).JSON(models.ErrorResponse{
 			Error: "Invalid MySQL credentials: " + err.Error(),
 		})
 	}
+	services.RecordLoginSuccess(c.IP(), account)
 
-	// Generate token with MySQL credentials
-	token, err := services.GenerateToken(req.Host, req.Port, req.Username, req.Password, req.Database)
+	// Generate token with MySQL credentials; stored passwords stay on the server
+	token, err := services.GenerateToken(config, req.Profile, storedPassword, nil)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: "Failed to generate token",
@@ -47,3 +69,165 @@ func Login(c *fiber.Ctx) error {
 	})
 }
 
+// AppLogin handles login with a local application account
+func AppLogin(c *fiber.Ctx) error {
+	var req models.AppLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	account := "app:" + req.Username
+	if err := services.ReserveLoginAttempt(c.IP(), account); err != nil {
+		return loginBlocked(c, err)
+	}
+
+	user, err := services.AuthenticateLocalUser(req.Username, req.Password)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	services.RecordLoginSuccess(c.IP(), account)
+
+	token, err := appToken(user, req.Profile)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(models.LoginResponse{
+		Token: token,
+	})
+}
+
+// OIDCLogin redirects the browser to the OpenID Connect provider
+func OIDCLogin(c *fiber.Ctx) error {
+	authURL, state, err := services.StartOIDCLogin(c.Query("profile"), c.IP())
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	// Bind the login to this browser
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback completes an OpenID Connect login and hands the token to the
+// frontend in the URL fragment
+func OIDCCallback(c *fiber.Ctx) error {
+	if e := c.Query("error"); e != "" {
+		return c.Status(401).JSON(models.ErrorResponse{
+			Error: "OpenID Connect login failed: " + e,
+		})
+	}
+
+	state := c.Query("state")
+	if state == "" || c.Cookies(oidcStateCookie) != state {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Login state mismatch",
+		})
+	}
+	c.ClearCookie(oidcStateCookie)
+
+	user, profile, err := services.FinishOIDCLogin(c.Context(), state, c.Query("code"))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	token, err := appToken(user, profile)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.Redirect("/login#token="+url.QueryEscape(token), fiber.StatusFound)
+}
+
+// appToken connects an application user through a granted profile and issues
+// the session token
+func appToken(user *services.AppUser, profile string) (string, error) {
+	config, profileID, err := services.ResolveAppLogin(user, profile)
+	if err != nil {
+		return "", err
+	}
+	if err := services.ValidateMySQLConnection(config); err != nil {
+		return "", err
+	}
+	return services.GenerateToken(config, profileID, true, user)
+}
+
+// GetAuthMethods returns the login methods offered by the server
+func GetAuthMethods(c *fiber.Ctx) error {
+	return c.JSON(services.GetAuthMethods())
+}
+
+// GetCurrentUser returns the identity of the logged-in session
+func GetCurrentUser(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	return c.JSON(services.CurrentUser(session))
+}
+
+// loginBlocked responds to a login refused by the brute-force protection
+func loginBlocked(c *fiber.Ctx, err error) error {
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	}
+	return c.Status(429).JSON(models.ErrorResponse{
+		Error: err.Error(),
+	})
+}
+
+// GetLoginLimits returns the failed login state of client IPs and accounts
+func GetLoginLimits(c *fiber.Ctx) error {
+	return c.JSON(services.ListLoginLimits())
+}
+
+// ClearLoginLimit lifts the login block of a client IP or account
+func ClearLoginLimit(c *fiber.Ctx) error {
+	key, err := url.PathUnescape(c.Params("key"))
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid key",
+		})
+	}
+
+	if err := services.ClearLoginLimit(key); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Login limit cleared successfully",
+	})
+}
+
+// GetCurrentPrivileges returns the effective privileges of the logged-in MySQL account
+func GetCurrentPrivileges(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	privileges, err := services.GetCurrentPrivileges(cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(privileges)
+}
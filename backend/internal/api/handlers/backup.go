@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"mysql-admin-tool/internal/sqlstmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetBackups returns the snapshots of tables in a database of the connection's
+// server, optionally of the table query parameter. Only administrators see the
+// snapshots taken by others.
+func GetBackups(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+	owner, admin := backupOwner(c)
+	if admin {
+		owner = ""
+	}
+
+	snapshots, err := services.ListBackups(services.BackupServer(cfg), c.Params("db"), c.Query("table"), owner)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(snapshots)
+}
+
+// GetAllBackups returns the snapshots of all servers, filtered by the server,
+// database and table query parameters
+func GetAllBackups(c *fiber.Ctx) error {
+	snapshots, err := services.ListBackups(c.Query("server"), c.Query("database"), c.Query("table"), "")
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(snapshots)
+}
+
+// RestoreBackup recreates the table of a snapshot in its database
+func RestoreBackup(c *fiber.Ctx) error {
+	var req models.RestoreBackupRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error: "Invalid request body",
+			})
+		}
+	}
+
+	cfg := connectionConfig(c)
+	dbName := c.Params("db")
+	owner, admin := backupOwner(c)
+
+	start := time.Now()
+	snapshot, statement, err := services.RestoreBackup(cfg, c.Params("id"), dbName, req.Table, owner, admin)
+	if statement != "" {
+		c.Locals("snapshot", c.Params("id"))
+		auditStatement(c, auditRestoreSnapshot, dbName, statement, start, nil, nil, errorText(err))
+	}
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	table := req.Table
+	if table == "" {
+		table = snapshot.Table
+	}
+	return c.JSON(fiber.Map{
+		"message":  "Snapshot restored successfully",
+		"table":    table,
+		"snapshot": snapshot,
+	})
+}
+
+// DeleteBackup removes a snapshot
+func DeleteBackup(c *fiber.Ctx) error {
+	if err := services.DeleteBackup(c.Params("id")); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Snapshot deleted successfully",
+	})
+}
+
+// snapshotBefore backs up the tables whose data the statements of query drop,
+// when backups are enabled
+func snapshotBefore(c *fiber.Ctx, dbName, query string) ([]models.BackupSnapshot, error) {
+	if !services.BackupsEnabled() {
+		return nil, nil
+	}
+	user, _ := requestUser(c)
+	owner, _ := backupOwner(c)
+	return services.SnapshotBeforeStatements(connectionConfig(c), dbName, sqlstmt.Parse(query), user, owner)
+}
+
+// backupOwner returns the owner key of the session and whether it has admin access
+func backupOwner(c *fiber.Ctx) (string, bool) {
+	session := c.Locals("session").(*services.Session)
+	return session.Owner(), session.Access() >= services.AccessAdmin
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// connectionConfig returns the credentials of the connection resolved by
+// middleware.ConnectionMiddleware
+func connectionConfig(c *fiber.Ctx) database.Config {
+	return c.Locals("connection").(database.Config)
+}
+
+// GetConnections returns the connections held by the session
+func GetConnections(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	return c.JSON(session.Connections())
+}
+
+// OpenConnection adds a named connection to the session
+func OpenConnection(c *fiber.Ctx) error {
+	var req models.OpenConnectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	session := c.Locals("session").(*services.Session)
+	conn, err := session.AddConnection(req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.Status(201).JSON(conn)
+}
+
+// CloseConnection removes a connection from the session, rolling back its open
+// transactions and closing its consoles
+func CloseConnection(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	if err := session.RemoveConnection(c.Params("conn")); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	services.RollbackConnectionTransactions(session.ID, c.Params("conn"))
+	services.CloseConnectionConsoles(session.ID, c.Params("conn"))
+
+	return c.JSON(fiber.Map{
+		"message": "Connection closed successfully",
+	})
+}
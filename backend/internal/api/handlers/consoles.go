@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetConsoles returns the console sessions of the connection
+func GetConsoles(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	return c.JSON(services.ListConsoles(session.ID, conn.ID))
+}
+
+// OpenConsole opens a console session keeping one MySQL session, e.g. for a tab
+// of the SQL editor
+func OpenConsole(c *fiber.Ctx) error {
+	var req models.OpenConsoleRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error: "Invalid request body",
+			})
+		}
+	}
+
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	console, err := services.OpenConsole(session.ID, conn, req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(console)
+}
+
+// GetConsole returns a console session with its current database
+func GetConsole(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	console, err := services.GetConsole(session.ID, conn.ID, c.Params("console"))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(console)
+}
+
+// CloseConsole closes a console session, discarding its MySQL session
+func CloseConsole(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	if err := services.CloseConsole(session.ID, conn.ID, c.Params("console")); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Console closed successfully",
+	})
+}
+
+// ExecuteConsoleQuery runs a query in the console session loaded by LoadConsole,
+// in its current database, and responds with the console's state after it
+func ExecuteConsoleQuery(c *fiber.Ctx) error {
+	console := c.Locals("console").(*models.Console)
+	req := c.Locals("query_request").(models.QueryRequest)
+
+	if req.Query == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Query is required",
+		})
+	}
+
+	query, args := req.Query, []interface{}(nil)
+	if len(req.Params) > 0 {
+		var err error
+		query, args, err = services.BindParams(req.Query, req.Params)
+		if err != nil {
+			return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		c.Locals("query_params", req.Params)
+	}
+
+	snapshots, err := snapshotBefore(c, console.Database, req.Query)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	session := c.Locals("session").(*services.Session)
+	start := time.Now()
+	result, state, err := services.ExecuteInConsole(session.ID, console.Connection, console.ID, console.Database, query, args)
+	recordQuery(c, console.Database, req.Query, start, result, err)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	result.Snapshots = snapshots
+	return c.JSON(models.ConsoleQueryResponse{
+		QueryResponse: *result,
+		Console:       *state,
+	})
+}
@@ -4,25 +4,33 @@ import (
 	"mysql-admin-tool/internal/models"
 	"mysql-admin-tool/internal/services"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // GetDatabases returns all databases
 func GetDatabases(c *fiber.Ctx) error {
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
-	database := c.Locals("db_database").(string)
+	cfg := connectionConfig(c)
 
-	databases, err := services.GetDatabases(host, port, user, password, database)
+	databases, err := services.GetDatabases(cfg)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
-	return c.JSON(databases)
+
+	// Hide databases the connection's policies do not allow
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	visible := make([]models.Database, 0, len(databases))
+	for _, db := range databases {
+		if services.SchemaVisible(conn, db.Name) {
+			services.RestrictPrivileges(session, conn, db.Name, db.Privileges)
+			visible = append(visible, db)
+		}
+	}
+	return c.JSON(visible)
 }
 
 // GetTables returns all tables in a database
@@ -34,17 +42,21 @@ func GetTables(c *fiber.Ctx) error {
 		})
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	cfg := connectionConfig(c)
 
-	tables, err := services.GetTables(dbName, host, port, user, password)
+	tables, err := services.GetTables(dbName, cfg)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
+
+	// Only offer what the session and the connection's policies allow
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	for _, table := range tables {
+		services.RestrictPrivileges(session, conn, dbName, table.Privileges)
+	}
 	return c.JSON(tables)
 }
 
@@ -59,12 +71,9 @@ func GetTableStructure(c *fiber.Ctx) error {
 		})
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	cfg := connectionConfig(c)
 
-	structure, err := services.GetTableStructure(dbName, tableName, host, port, user, password)
+	structure, err := services.GetTableStructure(dbName, tableName, cfg)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
@@ -96,12 +105,9 @@ func GetTableData(c *fiber.Ctx) error {
 		pageSize = 50
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	cfg := connectionConfig(c)
 
-	data, err := services.GetTableData(dbName, tableName, page, pageSize, sortBy, sortDir, host, port, user, password)
+	data, err := services.GetTableData(dbName, tableName, page, pageSize, sortBy, sortDir, cfg)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
@@ -131,20 +137,122 @@ func ExecuteQuery(c *fiber.Ctx) error {
 		})
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	if len(req.Params) == 0 {
+		return runQuery(c, req.Database, req.Transaction, req.Query, req.Query, nil)
+	}
+	query, args, err := services.BindParams(req.Query, req.Params)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	c.Locals("query_params", req.Params)
+	return runQuery(c, req.Database, req.Transaction, req.Query, query, args)
+}
+
+// ExplainQuery returns the parsed execution plan of a SQL statement
+func ExplainQuery(c *fiber.Ctx) error {
+	var req models.ExplainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	if req.Query == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Query is required",
+		})
+	}
+
+	if req.Database == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Database name is required",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	statement := req.Query
+	if req.Analyze {
+		statement = "EXPLAIN ANALYZE " + statement
+	} else {
+		statement = "EXPLAIN " + statement
+	}
+
+	start := time.Now()
+	plan, err := services.ExplainQuery(req.Database, req.Query, req.Analyze, cfg)
+	auditStatement(c, auditExplain, req.Database, statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(plan)
+}
+
+// runQuery executes a query on the request's connection, or in one of its open
+// transactions when tx is set, and records it as text in the audit log and query
+// history. Tables dropped outside transactions are snapshotted first.
+func runQuery(c *fiber.Ctx, dbName, tx, text, query string, args []interface{}) error {
+	if tx != "" {
+		session := c.Locals("session").(*services.Session)
+		conn := c.Locals("session_connection").(*services.SessionConnection)
+		c.Locals("transaction", tx)
+		start := time.Now()
+		result, err := services.ExecuteInTransaction(session.ID, conn.ID, tx, dbName, query, args)
+		return queryResult(c, dbName, text, start, result, err)
+	}
+
+	cfg := connectionConfig(c)
 
-	result, err := services.ExecuteQuery(req.Database, req.Query, host, port, user, password)
+	snapshots, err := snapshotBefore(c, dbName, text)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
+
+	start := time.Now()
+	result, err := services.ExecuteQuery(dbName, query, args, cfg)
+	if err == nil {
+		result.Snapshots = snapshots
+	}
+	return queryResult(c, dbName, text, start, result, err)
+}
+
+// queryResult records the outcome of a query started at start and responds with
+// it
+func queryResult(c *fiber.Ctx, dbName, text string, start time.Time, result *models.QueryResponse, err error) error {
+	recordQuery(c, dbName, text, start, result, err)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
 	return c.JSON(result)
 }
 
+// recordQuery records the outcome of a query started at start in the audit log
+// and query history
+func recordQuery(c *fiber.Ctx, dbName, text string, start time.Time, result *models.QueryResponse, err error) {
+	switch {
+	case err != nil:
+		auditStatement(c, auditQuery, dbName, text, start, nil, nil, err.Error())
+		recordHistory(c, dbName, text, start, 0, err.Error())
+	case result.Error != "":
+		auditStatement(c, auditQuery, dbName, text, start, nil, nil, result.Error)
+		recordHistory(c, dbName, text, start, 0, result.Error)
+	case result.Columns != nil:
+		returned := len(result.Rows)
+		auditStatement(c, auditQuery, dbName, text, start, nil, &returned, "")
+		recordHistory(c, dbName, text, start, int64(returned), "")
+	default:
+		auditStatement(c, auditQuery, dbName, text, start, &result.Affected, nil, "")
+		recordHistory(c, dbName, text, start, result.Affected, "")
+	}
+}
+
 // CreateTable creates a new table
 func CreateTable(c *fiber.Ctx) error {
 	dbName := c.Params("db")
@@ -165,13 +273,13 @@ func CreateTable(c *fiber.Ctx) error {
 		})
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	cfg := connectionConfig(c)
 
-	if err := services.CreateTable(dbName, req.TableName, req.CreateSQL, host, port, user, password); err != nil {
-		return c.Status(500).JSON(models.ErrorResponse{
+	start := time.Now()
+	err := services.CreateTable(dbName, req.TableName, req.CreateSQL, cfg)
+	auditStatement(c, auditCreateTable, dbName, req.CreateSQL, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
@@ -192,19 +300,31 @@ func DropTable(c *fiber.Ctx) error {
 		})
 	}
 
-	host := c.Locals("db_host").(string)
-	port := c.Locals("db_port").(int)
-	user := c.Locals("db_user").(string)
-	password := c.Locals("db_password").(string)
+	cfg := connectionConfig(c)
+	statement := services.DropTableStatement(dbName, tableName)
 
-	if err := services.DropTable(dbName, tableName, host, port, user, password); err != nil {
+	snapshots, err := snapshotBefore(c, dbName, statement)
+	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	start := time.Now()
+	err = services.DropTable(dbName, tableName, cfg)
+	auditStatement(c, auditDropTable, dbName, statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	response := fiber.Map{
 		"message": "Table dropped successfully",
-	})
+	}
+	if len(snapshots) > 0 {
+		response["snapshots"] = snapshots
+	}
+	return c.JSON(response)
 }
 
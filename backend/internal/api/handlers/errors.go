@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/history"
+	"mysql-admin-tool/internal/services"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// accessDeniedCodes lists MySQL error numbers caused by missing privileges
+var accessDeniedCodes = map[uint16]bool{
+	1044: true, // ER_DBACCESS_DENIED_ERROR
+	1045: true, // ER_ACCESS_DENIED_ERROR
+	1095: true, // ER_KILL_DENIED_ERROR
+	1142: true, // ER_TABLEACCESS_DENIED_ERROR
+	1143: true, // ER_COLUMNACCESS_DENIED_ERROR
+	1227: true, // ER_SPECIFIC_ACCESS_DENIED_ERROR
+	1370: true, // ER_PROCACCESS_DENIED_ERROR
+}
+
+// errorStatus maps a service error to an HTTP status code
+func errorStatus(err error) int {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.Is(err, services.ErrNotFound), errors.Is(err, history.ErrNotFound):
+		return 404
+	case errors.Is(err, services.ErrInvalidInput), errors.Is(err, services.ErrReadOnlyVariable):
+		return 400
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return 401
+	case errors.Is(err, database.ErrHostNotAllowed), errors.Is(err, services.ErrForbidden):
+		return 403
+	case errors.Is(err, services.ErrTooManyAttempts), errors.Is(err, services.ErrTooManyLogins):
+		return 429
+	case errors.As(err, &mysqlErr) && accessDeniedCodes[mysqlErr.Number]:
+		return 403
+	}
+	return 500
+}
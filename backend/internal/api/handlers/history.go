@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/history"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetHistory returns a page of the user's query history, newest first, filtered
+// by the q (query text), database, connection (ID or name), pinned and errors
+// query parameters
+func GetHistory(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	filter := history.Filter{
+		Text:       c.Query("q"),
+		Database:   c.Query("database"),
+		Connection: c.Query("connection"),
+		PinnedOnly: c.QueryBool("pinned"),
+		ErrorsOnly: c.QueryBool("errors"),
+		Page:       c.QueryInt("page", 1),
+		PageSize:   c.QueryInt("pageSize", 50),
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 500 {
+		filter.PageSize = 50
+	}
+
+	page, err := history.Search(session.Owner(), filter)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(page)
+}
+
+// PinHistoryEntry keeps a history entry from being removed for newer ones
+func PinHistoryEntry(c *fiber.Ctx) error {
+	return setHistoryPinned(c, true)
+}
+
+// UnpinHistoryEntry lets a history entry be removed for newer ones again
+func UnpinHistoryEntry(c *fiber.Ctx) error {
+	return setHistoryPinned(c, false)
+}
+
+// DeleteHistoryEntry removes an entry from the user's query history
+func DeleteHistoryEntry(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid history entry ID",
+		})
+	}
+
+	if err := history.Delete(session.Owner(), id); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "History entry deleted successfully",
+	})
+}
+
+// ClearHistory removes the user's query history, keeping pinned entries unless
+// the pinned query parameter is true
+func ClearHistory(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	removed, err := history.Clear(session.Owner(), c.QueryBool("pinned"))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "History cleared successfully",
+		"removed": removed,
+	})
+}
+
+// setHistoryPinned pins or unpins the history entry of the :id route parameter
+func setHistoryPinned(c *fiber.Ctx, pinned bool) error {
+	session := c.Locals("session").(*services.Session)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid history entry ID",
+		})
+	}
+
+	entry, err := history.SetPinned(session.Owner(), id, pinned)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(entry)
+}
+
+// recordHistory adds a query run on the request's connection, with the
+// parameters of the "query_params" local, to the user's history. rows is the
+// number of rows returned or affected.
+func recordHistory(c *fiber.Ctx, dbName, query string, start time.Time, rows int64, errText string) {
+	if !history.Enabled() {
+		return
+	}
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	params, _ := c.Locals("query_params").([]models.QueryParam)
+	history.Record(session.Owner(), models.HistoryEntry{
+		Query:          query,
+		Params:         params,
+		Database:       dbName,
+		Connection:     conn.ID,
+		ConnectionName: conn.Name,
+		ExecutedAt:     start.UTC(),
+		DurationMs:     float64(time.Since(start).Microseconds()) / 1000,
+		Rows:           rows,
+		Success:        errText == "",
+		Error:          errText,
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetProfileSummaries returns the profiles selectable on the login screen
+func GetProfileSummaries(c *fiber.Ctx) error {
+	return c.JSON(services.ListProfileSummaries())
+}
+
+// GetProfiles returns all saved connection profiles
+func GetProfiles(c *fiber.Ctx) error {
+	return c.JSON(services.ListProfiles())
+}
+
+// GetProfile returns a saved connection profile
+func GetProfile(c *fiber.Ctx) error {
+	profile, err := services.GetProfile(c.Params("id"))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(profile)
+}
+
+// CreateProfile saves a new connection profile
+func CreateProfile(c *fiber.Ctx) error {
+	var req models.ProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	profile, err := services.CreateProfile(req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.Status(201).JSON(profile)
+}
+
+// UpdateProfile updates a saved connection profile
+func UpdateProfile(c *fiber.Ctx) error {
+	var req models.ProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	profile, err := services.UpdateProfile(c.Params("id"), req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(profile)
+}
+
+// DeleteProfile deletes a saved connection profile
+func DeleteProfile(c *fiber.Ctx) error {
+	if err := services.DeleteProfile(c.Params("id")); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Profile deleted successfully",
+	})
+}
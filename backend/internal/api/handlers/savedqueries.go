@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSavedQueries returns the saved queries of the user and those shared with
+// them, filtered by the tag and q (name, description or SQL) query parameters
+func GetSavedQueries(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	return c.JSON(services.ListSavedQueries(session, c.Query("tag"), c.Query("q")))
+}
+
+// GetSavedQuery returns a saved query
+func GetSavedQuery(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	saved, err := services.GetSavedQuery(session, c.Params("id"))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(saved)
+}
+
+// CreateSavedQuery saves a new query owned by the user
+func CreateSavedQuery(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	var req models.SavedQueryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	saved, err := services.CreateSavedQuery(session, req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.Status(201).JSON(saved)
+}
+
+// UpdateSavedQuery updates a saved query of the user
+func UpdateSavedQuery(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	var req models.SavedQueryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	saved, err := services.UpdateSavedQuery(session, c.Params("id"), req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(saved)
+}
+
+// DeleteSavedQuery deletes a saved query of the user
+func DeleteSavedQuery(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+
+	if err := services.DeleteSavedQuery(session, c.Params("id")); err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Saved query deleted successfully",
+	})
+}
+
+// ExecuteSavedQuery runs the saved query loaded by LoadSavedQuery with the
+// parameter values of the request bound to its placeholders
+func ExecuteSavedQuery(c *fiber.Ctx) error {
+	saved := c.Locals("saved_query").(*models.SavedQuery)
+	query := c.Locals("query_request").(models.QueryRequest)
+
+	var req models.ExecuteSavedQueryRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error: "Invalid request body",
+			})
+		}
+	}
+
+	if query.Database == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Database name is required",
+		})
+	}
+
+	bound, args, err := services.BindSavedQuery(saved, req.Params)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	if len(req.Params) > 0 {
+		c.Locals("query_params", namedParams(req.Params))
+	}
+	return runQuery(c, query.Database, "", saved.Query, bound, args)
+}
+
+// namedParams lists the parameter values of a saved query run by name
+func namedParams(values map[string]models.QueryParam) []models.QueryParam {
+	params := make([]models.QueryParam, 0, len(values))
+	for name, p := range values {
+		p.Name = name
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
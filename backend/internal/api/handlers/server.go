@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetServerInfo returns server version and uptime
+func GetServerInfo(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	info, err := services.GetServerInfo(cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(info)
+}
+
+// GetServerStatus returns global status variables
+func GetServerStatus(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	status, err := services.GetGlobalStatus(c.Query("search"), cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(status)
+}
+
+// GetServerVariables returns global system variables
+func GetServerVariables(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	variables, err := services.GetVariables(c.Query("search"), cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(variables)
+}
+
+// SetServerVariable changes a dynamic global system variable
+func SetServerVariable(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if name == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Variable name required",
+		})
+	}
+
+	var req models.SetVariableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	start := time.Now()
+	variable, err := services.SetGlobalVariable(name, req.Value, cfg)
+	if variable != nil {
+		name = variable.Name
+	}
+	auditStatement(c, auditSetVariable, "", services.SetGlobalStatement(name, req.Value), start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(variable)
+}
+
+// GetServerMetrics returns computed server metrics sampled over time
+func GetServerMetrics(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	metrics, err := services.GetServerMetrics(cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(metrics)
+}
+
+// GetProcesses returns the server process list
+func GetProcesses(c *fiber.Ctx) error {
+	var filter models.ProcessFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid query parameters",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	processes, err := services.ListProcesses(filter, cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(processes)
+}
+
+// KillProcess kills a connection or the query it is running
+func KillProcess(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid process ID",
+		})
+	}
+
+	var req models.KillProcessRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{
+				Error: "Invalid request body",
+			})
+		}
+	}
+
+	cfg := connectionConfig(c)
+
+	start := time.Now()
+	err = services.KillProcess(id, req.QueryOnly, cfg)
+	auditStatement(c, auditKillProcess, "", services.KillStatement(id, req.QueryOnly), start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	message := "Connection killed successfully"
+	if req.QueryOnly {
+		message = "Query killed successfully"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BeginTransaction begins a transaction held open on a dedicated connection.
+// Queries run in it by passing its ID as the transaction of a query request.
+func BeginTransaction(c *fiber.Ctx) error {
+	var req models.BeginTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	if req.Database == "" {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Database name is required",
+		})
+	}
+
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	start := time.Now()
+	tx, err := services.BeginTransaction(session.ID, conn, req)
+	if err != nil {
+		auditStatement(c, auditTransaction, req.Database, "START TRANSACTION", start, nil, nil, err.Error())
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	c.Locals("transaction", tx.ID)
+	auditStatement(c, auditTransaction, req.Database, "START TRANSACTION", start, nil, nil, "")
+	return c.JSON(tx)
+}
+
+// GetTransactions returns the open transactions of the connection
+func GetTransactions(c *fiber.Ctx) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	return c.JSON(services.ListTransactions(session.ID, conn.ID))
+}
+
+// CommitTransaction commits an open transaction
+func CommitTransaction(c *fiber.Ctx) error {
+	return endTransaction(c, "COMMIT", services.CommitTransaction)
+}
+
+// RollbackTransaction rolls back an open transaction
+func RollbackTransaction(c *fiber.Ctx) error {
+	return endTransaction(c, "ROLLBACK", services.RollbackTransaction)
+}
+
+// endTransaction ends the transaction of the :tx route parameter with the
+// statement's service function
+func endTransaction(c *fiber.Ctx, statement string, end func(sessionID, connectionID, id string) (*models.Transaction, error)) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	id := c.Params("tx")
+
+	start := time.Now()
+	tx, err := end(session.ID, conn.ID, id)
+	if tx == nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	// The transaction has ended even when committing it failed
+	c.Locals("transaction", id)
+	auditStatement(c, auditTransaction, tx.Database, statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(tx)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountParams returns the decoded :user and :host route parameters
+func accountParams(c *fiber.Ctx) (string, string, bool) {
+	user, err := url.PathUnescape(c.Params("user"))
+	if err != nil || user == "" {
+		return "", "", false
+	}
+	host, err := url.PathUnescape(c.Params("host"))
+	if err != nil || host == "" {
+		return "", "", false
+	}
+	return user, host, true
+}
+
+// GetUsers returns all MySQL accounts
+func GetUsers(c *fiber.Ctx) error {
+	cfg := connectionConfig(c)
+
+	users, err := services.ListUsers(cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(users)
+}
+
+// CreateUser creates a MySQL account
+func CreateUser(c *fiber.Ctx) error {
+	var req models.CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	cfg := connectionConfig(c)
+	statement, err := services.CreateUserStatement(req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	start := time.Now()
+	err = services.CreateUser(req, cfg)
+	auditStatement(c, auditCreateUser, "", statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User created successfully",
+	})
+}
+
+// DropUser drops a MySQL account
+func DropUser(c *fiber.Ctx) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	start := time.Now()
+	err := services.DropUser(targetUser, targetHost, cfg)
+	auditStatement(c, auditDropUser, "", services.DropUserStatement(targetUser, targetHost), start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User dropped successfully",
+	})
+}
+
+// RenameUser renames a MySQL account
+func RenameUser(c *fiber.Ctx) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	var req models.RenameUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	cfg := connectionConfig(c)
+	statement, err := services.RenameUserStatement(targetUser, targetHost, req)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	start := time.Now()
+	err = services.RenameUser(targetUser, targetHost, req, cfg)
+	auditStatement(c, auditRenameUser, "", statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User renamed successfully",
+	})
+}
+
+// ChangeUserPassword changes the password of a MySQL account
+func ChangeUserPassword(c *fiber.Ctx) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	start := time.Now()
+	err := services.ChangePassword(targetUser, targetHost, req.Password, cfg)
+	auditStatement(c, auditChangePassword, "", services.ChangePasswordStatement(targetUser, targetHost, req.Password), start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password changed successfully",
+	})
+}
+
+// LockUser locks a MySQL account
+func LockUser(c *fiber.Ctx) error {
+	return setAccountLocked(c, true)
+}
+
+// UnlockUser unlocks a MySQL account
+func UnlockUser(c *fiber.Ctx) error {
+	return setAccountLocked(c, false)
+}
+
+// setAccountLocked locks or unlocks the account named by the route parameters
+func setAccountLocked(c *fiber.Ctx, locked bool) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	cfg := connectionConfig(c)
+	action := auditUnlockUser
+	if locked {
+		action = auditLockUser
+	}
+
+	start := time.Now()
+	err := services.SetAccountLocked(targetUser, targetHost, locked, cfg)
+	auditStatement(c, action, "", services.AccountLockStatement(targetUser, targetHost, locked), start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	message := "User unlocked successfully"
+	if locked {
+		message = "User locked successfully"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
+
+// GetUserGrants returns the grants of a MySQL account
+func GetUserGrants(c *fiber.Ctx) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	cfg := connectionConfig(c)
+
+	grants, err := services.GetUserGrants(targetUser, targetHost, cfg)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(grants)
+}
+
+// GrantPrivileges grants privileges to a MySQL account
+func GrantPrivileges(c *fiber.Ctx) error {
+	return changePrivileges(c, true)
+}
+
+// RevokePrivileges revokes privileges from a MySQL account
+func RevokePrivileges(c *fiber.Ctx) error {
+	return changePrivileges(c, false)
+}
+
+// changePrivileges grants or revokes privileges for the account named by the route parameters
+func changePrivileges(c *fiber.Ctx, grant bool) error {
+	targetUser, targetHost, ok := accountParams(c)
+	if !ok {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "User and host required",
+		})
+	}
+
+	var req models.PrivilegeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	cfg := connectionConfig(c)
+	statement, err := services.PrivilegeStatement(targetUser, targetHost, req, grant)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	start := time.Now()
+	action, message := auditGrant, "Privileges granted successfully"
+	if grant {
+		err = services.GrantPrivileges(targetUser, targetHost, req, cfg)
+	} else {
+		err = services.RevokePrivileges(targetUser, targetHost, req, cfg)
+		action, message = auditRevoke, "Privileges revoked successfully"
+	}
+	auditStatement(c, action, "", statement, start, nil, nil, errorText(err))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
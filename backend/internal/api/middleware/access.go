@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"mysql-admin-tool/internal/sqlstmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAccess refuses sessions below an access level
+func RequireAccess(level services.AccessLevel) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session := c.Locals("session").(*services.Session)
+		if err := services.RequireAccess(session, level); err != nil {
+			return c.Status(403).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Next()
+	}
+}
+
+// Authorize checks the operation of a route, described by a representative
+// statement such as "DROP TABLE", against the session's access level and the
+// connection's policies. The :db route parameter names the database it acts on.
+// It must run after ConnectionMiddleware.
+func Authorize(statement string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stmt := sqlstmt.Classify(statement)
+		if db := c.Params("db"); db != "" {
+			stmt.Schemas = append(stmt.Schemas, db)
+		}
+		return authorize(c, []sqlstmt.Statement{stmt}, "")
+	}
+}
+
+// AuthorizeIn checks an operation like Authorize, in the database named by the
+// "database" field of the request body, e.g. opening a transaction
+func AuthorizeIn(statement string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Database string `json:"database"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			// The handler reports malformed requests
+			return c.Next()
+		}
+		stmt := sqlstmt.Classify(statement)
+		if req.Database != "" {
+			stmt.Schemas = append(stmt.Schemas, req.Database)
+		}
+		return authorize(c, []sqlstmt.Statement{stmt}, "")
+	}
+}
+
+// AuthorizeQuery classifies the statements of a query request, or of a saved
+// query, before they run
+func AuthorizeQuery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, ok := queryRequest(c)
+		if !ok {
+			// The handler reports malformed requests
+			return c.Next()
+		}
+		return authorize(c, sqlstmt.Parse(req.Query), req.Database)
+	}
+}
+
+// AuthorizeExplain classifies a statement to be explained; EXPLAIN ANALYZE runs it
+func AuthorizeExplain() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.ExplainRequest
+		if err := c.BodyParser(&req); err != nil || req.Query == "" {
+			return c.Next()
+		}
+		prefix := "EXPLAIN "
+		if req.Analyze {
+			prefix = "EXPLAIN ANALYZE "
+		}
+		return authorize(c, sqlstmt.Parse(prefix+req.Query), req.Database)
+	}
+}
+
+// AuthorizeCreateTable classifies the SQL of a create table request, which runs
+// in the database of the :db route parameter
+func AuthorizeCreateTable() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			CreateSQL string `json:"createSQL"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Next()
+		}
+		statements := sqlstmt.Parse(req.CreateSQL)
+		if len(statements) == 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "createSQL must hold a CREATE TABLE statement",
+			})
+		}
+		return authorize(c, statements, c.Params("db"))
+	}
+}
+
+// AuthorizePrivileges classifies the GRANT, or REVOKE when grant is false, that a
+// privilege request for the account of the :user and :host route parameters runs,
+// so that its ON clause is checked against the connection's schemas
+func AuthorizePrivileges(grant bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PrivilegeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Next()
+		}
+		statement, err := services.PrivilegeStatement(c.Params("user"), c.Params("host"), req, grant)
+		if err != nil {
+			// The handler reports invalid requests
+			return c.Next()
+		}
+		return authorize(c, sqlstmt.Parse(statement), "")
+	}
+}
+
+// authorize responds with 403 when the session may not run the statements on the
+// current connection
+func authorize(c *fiber.Ctx, statements []sqlstmt.Statement, defaultDB string) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+
+	if err := services.AuthorizeStatements(session, conn, statements, defaultDB); err != nil {
+		return c.Status(403).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Next()
+}
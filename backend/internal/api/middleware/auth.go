@@ -6,7 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// AuthMiddleware validates JWT token and stores MySQL credentials in context
+// AuthMiddleware validates JWT token and stores the session in context
 func AuthMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -29,13 +29,42 @@ func AuthMiddleware() fiber.Handler {
 			})
 		}
 
-		// Store MySQL credentials in context
-		c.Locals("db_host", claims.DBHost)
-		c.Locals("db_port", claims.DBPort)
-		c.Locals("db_user", claims.DBUser)
-		c.Locals("db_password", claims.DBPassword)
-		c.Locals("db_database", claims.DBDatabase)
+		// The profile of the token may be gone, or the settings of a login
+		// without one lost with a restart
+		session, err := services.SessionFromClaims(tokenString, claims)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Locals("session", session)
 		return c.Next()
 	}
 }
 
+// ConnectionMiddleware resolves the connection named by the :conn route parameter,
+// or the login connection when the route has none, and stores its credentials in
+// context
+func ConnectionMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session := c.Locals("session").(*services.Session)
+
+		id := c.Params("conn")
+		if id == "" {
+			id = services.PrimaryConnectionID
+		}
+
+		conn, err := session.Connection(id)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Connection not found",
+			})
+		}
+
+		c.Locals("connection_id", conn.ID)
+		c.Locals("session_connection", conn)
+		c.Locals("connection", conn.Config)
+		return c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+	"mysql-admin-tool/internal/sqlstmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConfirmTokenHeader carries the token confirming a destructive operation
+const ConfirmTokenHeader = "X-Confirm-Token"
+
+// ConfirmQuery requires confirmation before destructive statements of a query
+// request, or of a saved query, run
+func ConfirmQuery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, ok := queryRequest(c)
+		if !ok {
+			return c.Next()
+		}
+		return confirmDestructive(c, req.Database, req.Query)
+	}
+}
+
+// ConfirmDropTable requires confirmation before the table of the :db and :table
+// route parameters is dropped
+func ConfirmDropTable() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dbName := c.Params("db")
+		return confirmDestructive(c, dbName, services.DropTableStatement(dbName, c.Params("table")))
+	}
+}
+
+// confirmDestructive lets the request through when its statements are harmless or
+// it presents a token issued for the same SQL; otherwise it responds with 428, the
+// impact of the destructive statements and a new token
+func confirmDestructive(c *fiber.Ctx, dbName, query string) error {
+	session := c.Locals("session").(*services.Session)
+	conn := c.Locals("session_connection").(*services.SessionConnection)
+	operation := dbName + "\x00" + query
+
+	message := "Confirmation required"
+	if token := c.Get(ConfirmTokenHeader); token != "" {
+		err := services.ConfirmOperation(token, session.ID, conn.ID, operation)
+		if err == nil {
+			return c.Next()
+		}
+		message = err.Error()
+	}
+
+	impact, err := services.AssessImpact(conn.Config, dbName, sqlstmt.Parse(query))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to assess the impact of the statement: " + err.Error(),
+		})
+	}
+	if len(impact) == 0 {
+		return c.Next()
+	}
+
+	token, expiresAt, err := services.RequestConfirmation(session.ID, conn.ID, operation)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to issue a confirmation token",
+		})
+	}
+	return c.Status(fiber.StatusPreconditionRequired).JSON(models.ConfirmationRequired{
+		Error:     message,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Impact:    impact,
+	})
+}
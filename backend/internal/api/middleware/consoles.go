@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LoadConsole resolves the console session of the :console route parameter. The
+// query of the request body stands in for a query request in the console's
+// current database, so that AuthorizeQuery and ConfirmQuery check it there. It
+// must run after ConnectionMiddleware.
+func LoadConsole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session := c.Locals("session").(*services.Session)
+		conn := c.Locals("session_connection").(*services.SessionConnection)
+
+		console, err := services.GetConsole(session.ID, conn.ID, c.Params("console"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var req models.ConsoleQueryRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		c.Locals("console", console)
+		c.Locals("query_request", models.QueryRequest{Database: console.Database, Query: req.Query, Params: req.Params})
+		return c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LoadSavedQuery resolves the saved query of the :id route parameter among those
+// visible to the session. It stands in for the query of the request, in the
+// database of the request body or the saved default, so that AuthorizeQuery and
+// ConfirmQuery check it.
+func LoadSavedQuery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session := c.Locals("session").(*services.Session)
+
+		saved, err := services.GetSavedQuery(session, c.Params("id"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var req models.ExecuteSavedQueryRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+		if req.Database == "" {
+			req.Database = saved.Database
+		}
+
+		c.Locals("saved_query", saved)
+		c.Locals("query_request", models.QueryRequest{Database: req.Database, Query: saved.Query})
+		return c.Next()
+	}
+}
+
+// queryRequest returns the query a request runs: the saved query loaded by
+// LoadSavedQuery, or the body of a query request
+func queryRequest(c *fiber.Ctx) (models.QueryRequest, bool) {
+	if req, ok := c.Locals("query_request").(models.QueryRequest); ok {
+		return req, true
+	}
+	var req models.QueryRequest
+	if err := c.BodyParser(&req); err != nil || req.Query == "" {
+		return req, false
+	}
+	return req, true
+}
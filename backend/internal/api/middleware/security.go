@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HSTSMiddleware sets the Strict-Transport-Security header on every response
+func HSTSMiddleware(maxAge int, includeSubdomains bool) fiber.Handler {
+	value := "max-age=" + strconv.Itoa(maxAge)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderStrictTransportSecurity, value)
+		return c.Next()
+	}
+}
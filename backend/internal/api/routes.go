@@ -3,6 +3,7 @@ package api
 import (
 	"mysql-admin-tool/internal/api/handlers"
 	"mysql-admin-tool/internal/api/middleware"
+	"mysql-admin-tool/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,22 +13,118 @@ func SetupRoutes(app *fiber.App) {
 	// Public routes
 	api := app.Group("/api")
 	api.Post("/auth/login", handlers.Login)
+	api.Get("/auth/profiles", handlers.GetProfileSummaries)
+	api.Get("/auth/methods", handlers.GetAuthMethods)
+	api.Post("/auth/app-login", handlers.AppLogin)
+	api.Get("/auth/oidc/login", handlers.OIDCLogin)
+	api.Get("/auth/oidc/callback", handlers.OIDCCallback)
 
 	// Protected routes
 	protected := api.Group("", middleware.AuthMiddleware())
 	{
-		// Database routes
-		protected.Get("/databases", handlers.GetDatabases)
-		protected.Get("/databases/:db/tables", handlers.GetTables)
-		protected.Get("/databases/:db/tables/:table", handlers.GetTableStructure)
-		protected.Get("/databases/:db/tables/:table/data", handlers.GetTableData)
-
-		// Query routes
-		protected.Post("/query", handlers.ExecuteQuery)
-
-		// Table management routes
-		protected.Post("/databases/:db/tables", handlers.CreateTable)
-		protected.Delete("/databases/:db/tables/:table", handlers.DropTable)
+		protected.Get("/auth/me", handlers.GetCurrentUser)
+
+		// Connection profile routes
+		protected.Get("/profiles", handlers.GetProfiles)
+		protected.Post("/profiles", middleware.RequireAccess(services.AccessAdmin), handlers.CreateProfile)
+		protected.Get("/profiles/:id", handlers.GetProfile)
+		protected.Put("/profiles/:id", middleware.RequireAccess(services.AccessAdmin), handlers.UpdateProfile)
+		protected.Delete("/profiles/:id", middleware.RequireAccess(services.AccessAdmin), handlers.DeleteProfile)
+
+		// Query history routes
+		protected.Get("/history", handlers.GetHistory)
+		protected.Delete("/history", handlers.ClearHistory)
+		protected.Post("/history/:id/pin", handlers.PinHistoryEntry)
+		protected.Post("/history/:id/unpin", handlers.UnpinHistoryEntry)
+		protected.Delete("/history/:id", handlers.DeleteHistoryEntry)
+
+		// Saved query routes; saved queries run on a connection below
+		protected.Get("/saved-queries", handlers.GetSavedQueries)
+		protected.Post("/saved-queries", handlers.CreateSavedQuery)
+		protected.Get("/saved-queries/:id", handlers.GetSavedQuery)
+		protected.Put("/saved-queries/:id", handlers.UpdateSavedQuery)
+		protected.Delete("/saved-queries/:id", handlers.DeleteSavedQuery)
+
+		// Operator routes
+		admin := protected.Group("/admin", middleware.RequireAccess(services.AccessAdmin))
+		admin.Get("/login-limits", handlers.GetLoginLimits)
+		admin.Delete("/login-limits/:key", handlers.ClearLoginLimit)
+		admin.Get("/audit", handlers.GetAuditLog)
+		admin.Get("/backups", handlers.GetAllBackups)
+		admin.Delete("/backups/:id", handlers.DeleteBackup)
+
+		// Session connection routes
+		protected.Get("/connections", handlers.GetConnections)
+		protected.Post("/connections", handlers.OpenConnection)
+		protected.Delete("/connections/:conn", handlers.CloseConnection)
+
+		// Routes scoped to a session connection; the unscoped forms act on the
+		// login connection
+		setupConnectionRoutes(protected.Group("/connections/:conn"), middleware.ConnectionMiddleware())
+		setupConnectionRoutes(protected, middleware.ConnectionMiddleware())
 	}
 }
 
+// setupConnectionRoutes configures the routes that operate on a MySQL connection.
+// Each route is authorized as the statement it runs, or the statements of the
+// request for raw SQL, before reaching the handler. Destructive statements also
+// need a confirmation token.
+func setupConnectionRoutes(router fiber.Router, connection fiber.Handler) {
+	authorize := middleware.Authorize
+
+	// Current account routes
+	router.Get("/auth/privileges", connection, authorize("SHOW GRANTS"), handlers.GetCurrentPrivileges)
+
+	// Database routes
+	router.Get("/databases", connection, authorize("SHOW DATABASES"), handlers.GetDatabases)
+	router.Get("/databases/:db/tables", connection, authorize("SHOW TABLES"), handlers.GetTables)
+	router.Get("/databases/:db/tables/:table", connection, authorize("SHOW COLUMNS"), handlers.GetTableStructure)
+	router.Get("/databases/:db/tables/:table/data", connection, authorize("SELECT"), handlers.GetTableData)
+
+	// Query routes
+	router.Post("/query", connection, middleware.AuthorizeQuery(), middleware.ConfirmQuery(), handlers.ExecuteQuery)
+	router.Post("/query/explain", connection, middleware.AuthorizeExplain(), handlers.ExplainQuery)
+	router.Post("/saved-queries/:id/execute", connection, middleware.LoadSavedQuery(), middleware.AuthorizeQuery(), middleware.ConfirmQuery(), handlers.ExecuteSavedQuery)
+
+	// Transaction routes; queries run in a transaction through /query
+	router.Get("/transactions", connection, authorize("START TRANSACTION"), handlers.GetTransactions)
+	router.Post("/transactions", connection, middleware.AuthorizeIn("START TRANSACTION"), handlers.BeginTransaction)
+	router.Post("/transactions/:tx/commit", connection, authorize("COMMIT"), handlers.CommitTransaction)
+	router.Post("/transactions/:tx/rollback", connection, authorize("ROLLBACK"), handlers.RollbackTransaction)
+
+	// Console session routes; each console keeps its MySQL session across queries
+	router.Get("/consoles", connection, authorize("USE"), handlers.GetConsoles)
+	router.Post("/consoles", connection, middleware.AuthorizeIn("USE"), handlers.OpenConsole)
+	router.Get("/consoles/:console", connection, authorize("USE"), handlers.GetConsole)
+	router.Delete("/consoles/:console", connection, handlers.CloseConsole)
+	router.Post("/consoles/:console/query", connection, middleware.LoadConsole(), middleware.AuthorizeQuery(), middleware.ConfirmQuery(), handlers.ExecuteConsoleQuery)
+
+	// Table management routes
+	router.Post("/databases/:db/tables", connection, middleware.AuthorizeCreateTable(), handlers.CreateTable)
+	router.Delete("/databases/:db/tables/:table", connection, authorize("DROP TABLE"), middleware.ConfirmDropTable(), handlers.DropTable)
+
+	// Table snapshot routes
+	router.Get("/databases/:db/backups", connection, authorize("SELECT"), handlers.GetBackups)
+	router.Post("/databases/:db/backups/:id/restore", connection, authorize("CREATE TABLE"), handlers.RestoreBackup)
+
+	// Server routes
+	router.Get("/server/info", connection, authorize("SHOW VARIABLES"), handlers.GetServerInfo)
+	router.Get("/server/status", connection, authorize("SHOW STATUS"), handlers.GetServerStatus)
+	router.Get("/server/variables", connection, authorize("SHOW VARIABLES"), handlers.GetServerVariables)
+	router.Put("/server/variables/:name", connection, authorize("SET GLOBAL"), handlers.SetServerVariable)
+	router.Get("/server/metrics", connection, authorize("SHOW STATUS"), handlers.GetServerMetrics)
+	router.Get("/server/processes", connection, authorize("SHOW PROCESSLIST"), handlers.GetProcesses)
+	router.Post("/server/processes/:id/kill", connection, authorize("KILL"), handlers.KillProcess)
+
+	// MySQL account routes
+	router.Get("/users", connection, authorize("SELECT FROM mysql.user"), handlers.GetUsers)
+	router.Post("/users", connection, authorize("CREATE USER"), handlers.CreateUser)
+	router.Delete("/users/:user/:host", connection, authorize("DROP USER"), handlers.DropUser)
+	router.Post("/users/:user/:host/rename", connection, authorize("RENAME USER"), handlers.RenameUser)
+	router.Put("/users/:user/:host/password", connection, authorize("ALTER USER"), handlers.ChangeUserPassword)
+	router.Post("/users/:user/:host/lock", connection, authorize("ALTER USER"), handlers.LockUser)
+	router.Post("/users/:user/:host/unlock", connection, authorize("ALTER USER"), handlers.UnlockUser)
+	router.Get("/users/:user/:host/grants", connection, authorize("SHOW GRANTS"), handlers.GetUserGrants)
+	router.Post("/users/:user/:host/grants", connection, middleware.AuthorizePrivileges(true), handlers.GrantPrivileges)
+	router.Post("/users/:user/:host/grants/revoke", connection, middleware.AuthorizePrivileges(false), handlers.RevokePrivileges)
+}
@@ -0,0 +1,164 @@
+// Package audit records the statements run through the API in a rotating
+// JSON-lines file.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	mu   sync.Mutex
+	cfg  config.AuditConfig
+	file *os.File
+	size int64
+)
+
+// Init opens the audit log for appending. Nothing is recorded when the audit log
+// is disabled.
+func Init(c config.AuditConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg = c
+	if !c.Enabled {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0750); err != nil {
+		return err
+	}
+	return open()
+}
+
+// Close closes the audit log
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg.Enabled = false
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Record appends an entry to the audit log, redacting literals when configured
+// and passwords always.
+// Failures are logged since a statement that already ran cannot be undone; an
+// entry that cannot be written is logged in full.
+func Record(entry models.AuditEntry) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.RedactLiterals {
+		entry.Statement = sqlstmt.Redact(entry.Statement)
+		entry.Params = redactParams(entry.Params)
+		entry.Redacted = true
+	} else {
+		entry.Statement, entry.Redacted = sqlstmt.RedactPasswords(entry.Statement)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit: failed to encode entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	// A file that could not be opened is retried with every entry
+	if file == nil {
+		if err := open(); err != nil {
+			log.Printf("audit: failed to open %s: %v; dropped entry: %s", cfg.Path, err, line)
+			return
+		}
+	}
+	if size > 0 && size+int64(len(line)) > int64(cfg.MaxSizeMB)<<20 {
+		if err := rotate(); err != nil {
+			log.Printf("audit: failed to rotate %s, writing to the current file: %v", cfg.Path, err)
+		}
+	}
+	n, err := file.Write(line)
+	size += int64(n)
+	if err != nil {
+		log.Printf("audit: failed to write %s: %v; dropped entry: %s", cfg.Path, err, line)
+	}
+}
+
+// redactParams replaces the values of parameters with ?, keeping their names and
+// types
+func redactParams(params []models.QueryParam) []models.QueryParam {
+	if params == nil {
+		return nil
+	}
+	redacted := make([]models.QueryParam, len(params))
+	for i, p := range params {
+		redacted[i] = models.QueryParam{Name: p.Name, Type: p.Type, Value: "?"}
+	}
+	return redacted
+}
+
+// open opens the current file. The caller must hold mu.
+func open() error {
+	f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	file = f
+	size = info.Size()
+	return nil
+}
+
+// rotate renames the current file to .1, shifting older files up and dropping the
+// oldest, and opens a new file. Without backups the file is truncated instead.
+// The current file stays open until the new one is, so that entries keep being
+// written when rotating fails. The caller must hold mu.
+func rotate() error {
+	if cfg.MaxBackups == 0 {
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		size = 0
+		return nil
+	}
+
+	os.Remove(backupPath(cfg.MaxBackups))
+	for i := cfg.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(i), backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(cfg.Path, backupPath(1)); err != nil {
+		return err
+	}
+
+	// The renamed file is still open; it is written to until the new file opens
+	old, oldSize := file, size
+	if err := open(); err != nil {
+		file, size = old, oldSize
+		return err
+	}
+	old.Close()
+	return nil
+}
+
+// backupPath returns the path of the nth rotated file
+func backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", cfg.Path, n)
+}
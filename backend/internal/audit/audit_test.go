@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAfterFailedRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := Init(config.AuditConfig{Enabled: true, Path: path, MaxSizeMB: 1, MaxBackups: 1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close() })
+
+	// A directory that is not empty cannot be removed or renamed over
+	if err := os.MkdirAll(filepath.Join(backupPath(1), "keep"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	Record(models.AuditEntry{Statement: "SELECT 1"})
+	mu.Lock()
+	size = 1 << 20
+	mu.Unlock()
+	Record(models.AuditEntry{Statement: "SELECT 2"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "SELECT 1") || !strings.Contains(string(data), "SELECT 2") {
+		t.Errorf("audit log = %q, want both entries in the current file", data)
+	}
+}
+
+func TestRecordRetriesOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := Init(config.AuditConfig{Enabled: true, Path: path, MaxSizeMB: 1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close() })
+
+	mu.Lock()
+	file.Close()
+	file = nil
+	mu.Unlock()
+	Record(models.AuditEntry{Statement: "SELECT 1"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "SELECT 1") {
+		t.Errorf("audit log = %q, want the entry written after reopening", data)
+	}
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"mysql-admin-tool/internal/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxLineSize bounds the length of an audit line, i.e. of a recorded statement
+const maxLineSize = 16 << 20
+
+// Filter selects audit entries. Empty fields match everything.
+type Filter struct {
+	User       string
+	Connection string
+	Database   string
+	Action     string
+	// Text matches statements containing it, ignoring case
+	Text       string
+	ErrorsOnly bool
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// Search returns the newest entries matching the filter, newest first, reading
+// the current file and then the rotated ones
+func Search(f Filter) ([]models.AuditEntry, error) {
+	mu.Lock()
+	c := cfg
+	mu.Unlock()
+
+	result := []models.AuditEntry{}
+	if !c.Enabled {
+		return result, nil
+	}
+
+	paths := []string{c.Path}
+	for i := 1; i <= c.MaxBackups; i++ {
+		paths = append(paths, backupPath(i))
+	}
+	for _, path := range paths {
+		if len(result) >= f.Limit {
+			break
+		}
+		entries, err := searchFile(path, f, f.Limit-len(result))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entries...)
+	}
+	return result, nil
+}
+
+// searchFile returns the last limit matching entries of one file, newest first
+func searchFile(path string, f Filter, limit int) ([]models.AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var matches []models.AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip lines cut short by a crash
+			continue
+		}
+		if !f.matches(entry) {
+			continue
+		}
+		matches = append(matches, entry)
+		if len(matches) > limit {
+			matches = matches[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e models.AuditEntry) bool {
+	switch {
+	case f.User != "" && !strings.EqualFold(e.User, f.User):
+		return false
+	case f.Connection != "" && e.Connection != f.Connection && !strings.EqualFold(e.ConnectionName, f.Connection):
+		return false
+	case f.Database != "" && e.Database != f.Database:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.ErrorsOnly && e.Error == "":
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	case f.Text != "" && !strings.Contains(strings.ToLower(e.Statement), strings.ToLower(f.Text)):
+		return false
+	}
+	return true
+}
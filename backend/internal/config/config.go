@@ -0,0 +1,609 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the application configuration read from the config file
+type Config struct {
+	Server       ServerConfig      `yaml:"server"`
+	CORS         CORSConfig        `yaml:"cors"`
+	RateLimit    RateLimitConfig   `yaml:"rate_limit"`
+	Hosts        HostsConfig       `yaml:"hosts"`
+	Identity     IdentityConfig    `yaml:"identity"`
+	Policies     []PolicyConfig    `yaml:"policies"`
+	Audit        AuditConfig       `yaml:"audit"`
+	Confirm      ConfirmConfig     `yaml:"confirmations"`
+	Backups      BackupConfig      `yaml:"backups"`
+	History      HistoryConfig     `yaml:"history"`
+	SavedQueries SavedQueryConfig  `yaml:"saved_queries"`
+	Transactions TransactionConfig `yaml:"transactions"`
+	Consoles     ConsoleConfig     `yaml:"consoles"`
+	Frontend     FrontendConfig    `yaml:"frontend"`
+}
+
+// ServerConfig holds the HTTP server settings
+type ServerConfig struct {
+	Host string    `yaml:"host"`
+	Port int       `yaml:"port"`
+	TLS  TLSConfig `yaml:"tls"`
+	// ProxyHeader names the header holding the client IP when requests come
+	// through one of TrustedProxies, e.g. X-Forwarded-For
+	ProxyHeader    string   `yaml:"proxy_header"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// TLSConfig holds the settings for serving HTTPS. The certificate and key are
+// reloaded when the files change.
+type TLSConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	CertFile              string `yaml:"cert_file"`
+	KeyFile               string `yaml:"key_file"`
+	RedirectHTTP          bool   `yaml:"redirect_http"`
+	RedirectPort          int    `yaml:"redirect_port"`
+	HSTSMaxAge            int    `yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains"`
+}
+
+// CORSConfig holds the cross-origin settings. Without allowed origins only
+// same-origin requests are served.
+type CORSConfig struct {
+	AllowOrigins     []string `yaml:"allow_origins"`
+	AllowMethods     []string `yaml:"allow_methods"`
+	AllowHeaders     []string `yaml:"allow_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age"`
+}
+
+// RateLimitConfig holds the limits for login attempts and API requests
+type RateLimitConfig struct {
+	Login LoginLimitConfig `yaml:"login"`
+	API   APILimitConfig   `yaml:"api"`
+}
+
+// LoginLimitConfig holds the brute-force protection of the login endpoint. Failed
+// logins are counted per client IP and per account; after FreeAttempts failures
+// each further attempt is delayed exponentially from BaseDelay up to MaxDelay, and
+// after LockoutAfter failures the key is locked for LockoutDuration. Failures are
+// forgotten ResetAfter the last one.
+type LoginLimitConfig struct {
+	FreeAttempts    int           `yaml:"free_attempts"`
+	BaseDelay       time.Duration `yaml:"base_delay"`
+	MaxDelay        time.Duration `yaml:"max_delay"`
+	LockoutAfter    int           `yaml:"lockout_after"`
+	LockoutDuration time.Duration `yaml:"lockout_duration"`
+	ResetAfter      time.Duration `yaml:"reset_after"`
+}
+
+// APILimitConfig holds the per-IP limit for all API requests. A zero MaxRequests
+// disables the limit.
+type APILimitConfig struct {
+	MaxRequests int           `yaml:"max_requests"`
+	Window      time.Duration `yaml:"window"`
+}
+
+// HostsConfig restricts the MySQL servers users may connect to. Entries are host
+// names (optionally with a leading "*." wildcard), IP addresses, CIDR ranges or
+// absolute Unix socket paths. An empty Allow list allows every host and socket
+// that is not denied; Deny always wins.
+// When Pinned is set every connection goes to that server.
+type HostsConfig struct {
+	Allow  []string      `yaml:"allow"`
+	Deny   []string      `yaml:"deny"`
+	Ports  []int         `yaml:"ports"`
+	Pinned *PinnedServer `yaml:"pinned"`
+}
+
+// PinnedServer is the single MySQL server users are pinned to
+type PinnedServer struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// IdentityConfig holds the application accounts. Local users and OpenID Connect
+// logins are granted roles, and each role may connect through stored profiles
+// without seeing their MySQL credentials. MySQLLoginAccess is the access level of
+// sessions logged in with MySQL credentials; when empty they are admins, or get
+// no access at all once Roles are configured. AllowStoredPasswordLogin lets
+// MySQL logins use the password stored in a profile while no application
+// accounts are configured.
+type IdentityConfig struct {
+	AllowMySQLLogin          bool              `yaml:"allow_mysql_login"`
+	AllowStoredPasswordLogin bool              `yaml:"allow_stored_password_login"`
+	MySQLLoginAccess         string            `yaml:"mysql_login_access"`
+	Roles                    []RoleConfig      `yaml:"roles"`
+	Users                    []LocalUserConfig `yaml:"users"`
+	OIDC                     OIDCConfig        `yaml:"oidc"`
+}
+
+// Access levels of application roles
+const (
+	// AccessNone may not run statements; only valid for MySQL logins
+	AccessNone = "none"
+	// AccessViewer may read data and metadata
+	AccessViewer = "viewer"
+	// AccessEditor may also modify rows and schema objects
+	AccessEditor = "editor"
+	// AccessAdmin may also manage accounts, the server and connection profiles
+	AccessAdmin = "admin"
+)
+
+// RoleConfig maps an application role to its access level and the profiles, by
+// ID or name, it may use. Roles without an access level are viewers.
+type RoleConfig struct {
+	Name     string   `yaml:"name"`
+	Access   string   `yaml:"access"`
+	Profiles []string `yaml:"profiles"`
+}
+
+// LocalUserConfig is an application user with a bcrypt password hash
+type LocalUserConfig struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"password_hash"`
+	Roles        []string `yaml:"roles"`
+}
+
+// OIDCConfig holds the OpenID Connect provider. Values of RolesClaim in the ID
+// token are mapped to roles through RoleMapping; DefaultRoles are granted to
+// every OIDC user.
+type OIDCConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Issuer        string            `yaml:"issuer"`
+	ClientID      string            `yaml:"client_id"`
+	ClientSecret  string            `yaml:"client_secret"`
+	RedirectURL   string            `yaml:"redirect_url"`
+	Scopes        []string          `yaml:"scopes"`
+	UsernameClaim string            `yaml:"username_claim"`
+	RolesClaim    string            `yaml:"roles_claim"`
+	RoleMapping   map[string]string `yaml:"role_mapping"`
+	DefaultRoles  []string          `yaml:"default_roles"`
+}
+
+// PolicyConfig restricts the statements run on the connections it matches. A
+// policy matches connections through one of Profiles (by ID or name, "*" for all)
+// or to one of Hosts, written like HostsConfig entries; names also match the
+// servers they resolve to. Schemas are database
+// names or glob patterns such as "app_*"; when set, statements may only refer to
+// those databases. DenyStatements lists statement keywords or kinds, e.g. DROP,
+// TRUNCATE or "DROP TABLE". Every matching policy applies.
+type PolicyConfig struct {
+	Name           string   `yaml:"name"`
+	Profiles       []string `yaml:"profiles"`
+	Hosts          []string `yaml:"hosts"`
+	ReadOnly       bool     `yaml:"read_only"`
+	DenyStatements []string `yaml:"deny_statements"`
+	Schemas        []string `yaml:"schemas"`
+}
+
+// AuditConfig holds the audit log of statements run through the API, written as
+// JSON lines. The file is rotated when it reaches MaxSizeMB, keeping MaxBackups
+// older files. RedactLiterals replaces string and number literals with ?.
+type AuditConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Path           string `yaml:"path"`
+	MaxSizeMB      int    `yaml:"max_size_mb"`
+	MaxBackups     int    `yaml:"max_backups"`
+	RedactLiterals bool   `yaml:"redact_literals"`
+}
+
+// ConfirmConfig holds the two-step confirmation of destructive statements. A
+// confirmation token is valid for TTL. ALTER TABLE needs confirmation on tables
+// with an estimated LargeTableRows rows or more.
+type ConfirmConfig struct {
+	TTL            time.Duration `yaml:"ttl"`
+	LargeTableRows int64         `yaml:"large_table_rows"`
+}
+
+// BackupConfig holds the snapshots taken of tables before statements that drop
+// their data. Snapshots older than MaxAge are removed, as are all but the newest
+// MaxCount; zero disables either limit. Tables larger than MaxTableBytes are not
+// snapshotted; zero snapshots tables of any size.
+type BackupConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Dir           string        `yaml:"dir"`
+	MaxAge        time.Duration `yaml:"max_age"`
+	MaxCount      int           `yaml:"max_count"`
+	MaxTableBytes int64         `yaml:"max_table_bytes"`
+}
+
+// HistoryConfig holds the per-user history of executed queries, kept in an
+// embedded database file. Each user keeps the newest MaxEntries entries besides
+// pinned ones.
+type HistoryConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Path       string `yaml:"path"`
+	MaxEntries int    `yaml:"max_entries"`
+}
+
+// SavedQueryConfig holds the location of the saved query store
+type SavedQueryConfig struct {
+	Path string `yaml:"path"`
+}
+
+// TransactionConfig holds the transactions kept open across API requests. A
+// transaction idle for IdleTimeout is rolled back; a session connection may hold
+// at most MaxPerConnection of them, and all sessions together MaxTotal.
+type TransactionConfig struct {
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`
+	MaxPerConnection int           `yaml:"max_per_connection"`
+	MaxTotal         int           `yaml:"max_total"`
+}
+
+// ConsoleConfig holds the console sessions of the SQL editor, each keeping a
+// MySQL session across queries. A console idle for IdleTimeout is closed; a
+// session connection may hold at most MaxPerConnection of them, and all sessions
+// together MaxTotal.
+type ConsoleConfig struct {
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`
+	MaxPerConnection int           `yaml:"max_per_connection"`
+	MaxTotal         int           `yaml:"max_total"`
+}
+
+// FrontendConfig holds the location of the frontend assets
+type FrontendConfig struct {
+	Path string `yaml:"path"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port: 8090,
+			TLS: TLSConfig{
+				RedirectPort: 8080,
+				HSTSMaxAge:   31536000,
+			},
+		},
+		CORS: CORSConfig{
+			AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Confirm-Token"},
+		},
+		RateLimit: RateLimitConfig{
+			Login: LoginLimitConfig{
+				FreeAttempts:    3,
+				BaseDelay:       time.Second,
+				MaxDelay:        5 * time.Minute,
+				LockoutAfter:    10,
+				LockoutDuration: 15 * time.Minute,
+				ResetAfter:      time.Hour,
+			},
+			API: APILimitConfig{
+				MaxRequests: 600,
+				Window:      time.Minute,
+			},
+		},
+		Identity: IdentityConfig{
+			AllowMySQLLogin: true,
+			OIDC: OIDCConfig{
+				Scopes:        []string{"openid", "profile", "email"},
+				UsernameClaim: "email",
+				RolesClaim:    "groups",
+			},
+		},
+		Audit: AuditConfig{
+			Enabled:    true,
+			Path:       "./data/audit.log",
+			MaxSizeMB:  100,
+			MaxBackups: 10,
+		},
+		Confirm: ConfirmConfig{
+			TTL:            2 * time.Minute,
+			LargeTableRows: 100000,
+		},
+		Backups: BackupConfig{
+			Dir:           "./data/backups",
+			MaxAge:        7 * 24 * time.Hour,
+			MaxCount:      50,
+			MaxTableBytes: 1 << 30,
+		},
+		History: HistoryConfig{
+			Enabled:    true,
+			Path:       "./data/history.db",
+			MaxEntries: 1000,
+		},
+		SavedQueries: SavedQueryConfig{
+			Path: "./data/saved-queries.json",
+		},
+		Transactions: TransactionConfig{
+			IdleTimeout:      5 * time.Minute,
+			MaxPerConnection: 4,
+			MaxTotal:         100,
+		},
+		Consoles: ConsoleConfig{
+			IdleTimeout:      30 * time.Minute,
+			MaxPerConnection: 8,
+			MaxTotal:         200,
+		},
+		Frontend: FrontendConfig{
+			Path: "./frontend/dist",
+		},
+	}
+}
+
+// Load reads the config file at path over the defaults. A missing file is not an
+// error.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration for consistency
+func (c *Config) Validate() error {
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		return fmt.Errorf("server.port %d out of range", c.Server.Port)
+	}
+	if err := c.CORS.validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.validate(); err != nil {
+		return err
+	}
+	if err := c.Hosts.validate(); err != nil {
+		return err
+	}
+	if err := c.Identity.validate(); err != nil {
+		return err
+	}
+	if err := c.Audit.validate(); err != nil {
+		return err
+	}
+	if c.Confirm.TTL <= 0 || c.Confirm.LargeTableRows < 0 {
+		return errors.New("confirmations.ttl must be positive and confirmations.large_table_rows not negative")
+	}
+	if err := c.Backups.validate(); err != nil {
+		return err
+	}
+	if err := c.History.validate(); err != nil {
+		return err
+	}
+	if c.SavedQueries.Path == "" {
+		return errors.New("saved_queries.path is required")
+	}
+	if c.Transactions.IdleTimeout <= 0 || c.Transactions.MaxPerConnection < 1 || c.Transactions.MaxTotal < 1 {
+		return errors.New("transactions.idle_timeout, transactions.max_per_connection and transactions.max_total must be positive")
+	}
+	if c.Consoles.IdleTimeout <= 0 || c.Consoles.MaxPerConnection < 1 || c.Consoles.MaxTotal < 1 {
+		return errors.New("consoles.idle_timeout, consoles.max_per_connection and consoles.max_total must be positive")
+	}
+	for i, p := range c.Policies {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("policies[%d]: %w", i, err)
+		}
+	}
+
+	tls := c.Server.TLS
+	if !tls.Enabled {
+		return nil
+	}
+	if tls.CertFile == "" || tls.KeyFile == "" {
+		return errors.New("server.tls.cert_file and server.tls.key_file are required when TLS is enabled")
+	}
+	if tls.RedirectHTTP {
+		if tls.RedirectPort < 1 || tls.RedirectPort > 65535 {
+			return fmt.Errorf("server.tls.redirect_port %d out of range", tls.RedirectPort)
+		}
+		if tls.RedirectPort == c.Server.Port {
+			return errors.New("server.tls.redirect_port must differ from server.port")
+		}
+	}
+	if tls.HSTSMaxAge < 0 {
+		return errors.New("server.tls.hsts_max_age must not be negative")
+	}
+	return nil
+}
+
+// validate checks the CORS origins, refusing a wildcard together with credentials
+func (c CORSConfig) validate() error {
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return errors.New("cors.allow_origins must not contain \"*\" when cors.allow_credentials is set")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return fmt.Errorf("cors.allow_origins: invalid origin %q", origin)
+		}
+	}
+	if c.MaxAge < 0 {
+		return errors.New("cors.max_age must not be negative")
+	}
+	return nil
+}
+
+// validate checks that the limits are usable
+func (c RateLimitConfig) validate() error {
+	login := c.Login
+	if login.FreeAttempts < 0 || login.LockoutAfter < 0 {
+		return errors.New("rate_limit.login attempt counts must not be negative")
+	}
+	if login.BaseDelay < 0 || login.MaxDelay < login.BaseDelay || login.LockoutDuration < 0 || login.ResetAfter <= 0 {
+		return errors.New("rate_limit.login requires 0 <= base_delay <= max_delay, a non-negative lockout_duration and a positive reset_after")
+	}
+	if c.API.MaxRequests < 0 {
+		return errors.New("rate_limit.api.max_requests must not be negative")
+	}
+	if c.API.MaxRequests > 0 && c.API.Window <= 0 {
+		return errors.New("rate_limit.api.window must be positive")
+	}
+	return nil
+}
+
+// validate checks the host entries and ports
+func (c HostsConfig) validate() error {
+	for _, entry := range append(append([]string{}, c.Allow...), c.Deny...) {
+		if entry == "" || strings.ContainsAny(entry, " \t") {
+			return fmt.Errorf("hosts: invalid entry %q", entry)
+		}
+		if strings.Contains(entry, "/") && !strings.HasPrefix(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("hosts: invalid CIDR %q", entry)
+			}
+		}
+	}
+	for _, port := range c.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("hosts.ports: port %d out of range", port)
+		}
+	}
+	if c.Pinned != nil {
+		if c.Pinned.Host == "" {
+			return errors.New("hosts.pinned.host is required")
+		}
+		if c.Pinned.Port < 0 || c.Pinned.Port > 65535 {
+			return fmt.Errorf("hosts.pinned.port %d out of range", c.Pinned.Port)
+		}
+	}
+	return nil
+}
+
+// validate checks that users and mappings refer to defined roles
+func (c IdentityConfig) validate() error {
+	roles := make(map[string]bool, len(c.Roles))
+	for _, role := range c.Roles {
+		if role.Name == "" {
+			return errors.New("identity.roles: role name is required")
+		}
+		if roles[role.Name] {
+			return fmt.Errorf("identity.roles: duplicate role %q", role.Name)
+		}
+		if role.Access != "" && !validAccess(role.Access) {
+			return fmt.Errorf("identity.roles.%s: unknown access level %q", role.Name, role.Access)
+		}
+		roles[role.Name] = true
+	}
+	if c.MySQLLoginAccess != "" && c.MySQLLoginAccess != AccessNone && !validAccess(c.MySQLLoginAccess) {
+		return fmt.Errorf("identity.mysql_login_access: unknown access level %q", c.MySQLLoginAccess)
+	}
+	checkRoles := func(where string, names []string) error {
+		for _, name := range names {
+			if !roles[name] {
+				return fmt.Errorf("%s: unknown role %q", where, name)
+			}
+		}
+		return nil
+	}
+
+	users := make(map[string]bool, len(c.Users))
+	for _, user := range c.Users {
+		if user.Username == "" || user.PasswordHash == "" {
+			return errors.New("identity.users: username and password_hash are required")
+		}
+		if users[user.Username] {
+			return fmt.Errorf("identity.users: duplicate user %q", user.Username)
+		}
+		users[user.Username] = true
+		if err := checkRoles("identity.users."+user.Username, user.Roles); err != nil {
+			return err
+		}
+	}
+
+	if !c.OIDC.Enabled {
+		return nil
+	}
+	if c.OIDC.Issuer == "" || c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "" {
+		return errors.New("identity.oidc: issuer, client_id and redirect_url are required")
+	}
+	for _, role := range c.OIDC.RoleMapping {
+		if err := checkRoles("identity.oidc.role_mapping", []string{role}); err != nil {
+			return err
+		}
+	}
+	return checkRoles("identity.oidc.default_roles", c.OIDC.DefaultRoles)
+}
+
+// validate checks the audit file settings
+func (c AuditConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Path == "" {
+		return errors.New("audit.path is required when the audit log is enabled")
+	}
+	if c.MaxSizeMB < 1 {
+		return errors.New("audit.max_size_mb must be positive")
+	}
+	if c.MaxBackups < 0 {
+		return errors.New("audit.max_backups must not be negative")
+	}
+	return nil
+}
+
+// validate checks the snapshot directory and retention
+func (c BackupConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Dir == "" {
+		return errors.New("backups.dir is required when backups are enabled")
+	}
+	if c.MaxAge < 0 || c.MaxCount < 0 || c.MaxTableBytes < 0 {
+		return errors.New("backups.max_age, backups.max_count and backups.max_table_bytes must not be negative")
+	}
+	return nil
+}
+
+// validate checks the history file settings
+func (c HistoryConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Path == "" {
+		return errors.New("history.path is required when the query history is enabled")
+	}
+	if c.MaxEntries < 1 {
+		return errors.New("history.max_entries must be positive")
+	}
+	return nil
+}
+
+// validAccess reports whether level names an access level
+func validAccess(level string) bool {
+	return level == AccessViewer || level == AccessEditor || level == AccessAdmin
+}
+
+// validate checks that the policy matches connections and has valid patterns
+func (p PolicyConfig) validate() error {
+	if len(p.Profiles) == 0 && len(p.Hosts) == 0 {
+		return errors.New("profiles or hosts is required")
+	}
+	if err := (HostsConfig{Allow: p.Hosts}).validate(); err != nil {
+		return err
+	}
+	for _, schema := range p.Schemas {
+		if _, err := path.Match(schema, ""); err != nil || schema == "" {
+			return fmt.Errorf("invalid schema pattern %q", schema)
+		}
+	}
+	for _, statement := range p.DenyStatements {
+		if strings.TrimSpace(statement) == "" {
+			return errors.New("deny_statements must not contain empty entries")
+		}
+	}
+	return nil
+}
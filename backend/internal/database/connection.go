@@ -2,11 +2,13 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
+	"mysql-admin-tool/internal/models"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 var (
@@ -23,6 +25,12 @@ type Config struct {
 	Database string
 	MaxConns int
 	MaxIdle  int
+	Socket   string
+	TLS      models.TLSOptions
+	SSH      *models.SSHTunnelOptions
+	Options  models.ConnectionOptions
+	// ReadOnly makes every session of the connection read-only on the server
+	ReadOnly bool
 }
 
 // Init initializes the database connection pool
@@ -30,15 +38,7 @@ func Init(config Config) error {
 	var err error
 	
 	dbOnce.Do(func() {
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-			config.User,
-			config.Password,
-			config.Host,
-			config.Port,
-			config.Database,
-		)
-
-		db, err = sql.Open("mysql", dsn)
+		db, err = openDB(config.Database, config)
 		if err != nil {
 			return
 		}
@@ -78,17 +78,65 @@ func Close() error {
 	return nil
 }
 
-// ConnectToDatabase connects to a specific database using credentials from context
-func ConnectToDatabase(dbName string, host string, port int, user, password string) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-		user,
-		password,
-		host,
-		port,
-		dbName,
-	)
-
-	return sql.Open("mysql", dsn)
+// ConnectToDatabase connects to a specific database using the credentials of a
+// session connection
+func ConnectToDatabase(dbName string, config Config) (*sql.DB, error) {
+	return openDB(dbName, config)
+}
+
+// openDB opens a connection pool to dbName. The SQL mode of every session is
+// checked, and sessions of read-only connections are made read-only before they
+// are used.
+func openDB(dbName string, config Config) (*sql.DB, error) {
+	cfg, err := driverConfig(dbName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	connector = sqlModeConnector{connector}
+	if config.ReadOnly {
+		connector = readOnlyConnector{connector}
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// driverConfig builds the driver configuration for a connection to dbName
+func driverConfig(dbName string, config Config) (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = config.User
+	cfg.Passwd = config.Password
+	cfg.DBName = dbName
+	cfg.ParseTime = true
+
+	switch {
+	case config.Socket != "":
+		cfg.Net = "unix"
+		cfg.Addr = config.Socket
+	case config.SSH != nil:
+		// Connections through an SSH tunnel use the tunnel's registered dialer
+		addr, err := sshTunnelAddr(*config.SSH, net.JoinHostPort(config.Host, strconv.Itoa(config.Port)))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Net = sshNetworkName
+		cfg.Addr = addr
+	default:
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	}
+
+	if err := applyTLS(cfg, config.TLS, config.Host); err != nil {
+		return nil, err
+	}
+	if err := applyConnectionOptions(cfg, config.Options); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
 
 var dbConfig *Config
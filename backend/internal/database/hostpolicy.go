@@ -0,0 +1,387 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrHostNotAllowed is returned when a connection targets a host or port that the
+// host policy does not allow
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// hostRules holds parsed host names and networks of a policy list
+type hostRules struct {
+	names    []string
+	wildcard []string
+	nets     []*net.IPNet
+	sockets  []string
+}
+
+// hostPolicy restricts the servers connections may be made to
+type hostPolicy struct {
+	allow  hostRules
+	deny   hostRules
+	ports  map[int]bool
+	pinned *config.PinnedServer
+}
+
+var (
+	hostPolicyMu sync.RWMutex
+	policy       = &hostPolicy{}
+	dialOnce     sync.Once
+)
+
+// SetHostPolicy installs the host restrictions and enforces them when dialing
+func SetHostPolicy(cfg config.HostsConfig) {
+	p := &hostPolicy{
+		allow: parseHostRules(cfg.Allow),
+		deny:  parseHostRules(cfg.Deny),
+	}
+	if len(cfg.Ports) > 0 {
+		p.ports = make(map[int]bool, len(cfg.Ports))
+		for _, port := range cfg.Ports {
+			p.ports[port] = true
+		}
+	}
+	if cfg.Pinned != nil {
+		pinned := *cfg.Pinned
+		if pinned.Port == 0 {
+			pinned.Port = 3306
+		}
+		p.pinned = &pinned
+	}
+
+	hostPolicyMu.Lock()
+	policy = p
+	hostPolicyMu.Unlock()
+
+	// Check resolved addresses too, so that names cannot be pointed at denied networks
+	dialOnce.Do(func() { mysql.RegisterDialContext("tcp", policyDial) })
+}
+
+// PinnedServer returns the server all connections are pinned to, if any
+func PinnedServer() (string, int, bool) {
+	p := currentPolicy()
+	if p.pinned == nil {
+		return "", 0, false
+	}
+	return p.pinned.Host, p.pinned.Port, true
+}
+
+// CheckHost reports whether connections to host and port are allowed. Host names
+// are resolved and their addresses checked against the configured networks.
+func CheckHost(host string, port int) error {
+	p := currentPolicy()
+	if err := p.checkServer(host, port); err != nil {
+		return err
+	}
+	return p.checkResolved(host)
+}
+
+// CheckSSHHost reports whether SSH tunnels through host are allowed. The port and
+// pinning rules only apply to MySQL servers.
+func CheckSSHHost(host string) error {
+	return currentPolicy().checkResolved(host)
+}
+
+// CheckRemoteHost checks a host that is resolved elsewhere, such as the MySQL host
+// behind an SSH tunnel. Its addresses are unknown here, so a host name must be
+// allowed by name when an allowlist is configured.
+func CheckRemoteHost(host string, port int) error {
+	p := currentPolicy()
+	if err := p.checkServer(host, port); err != nil {
+		return err
+	}
+	if err := p.checkName(host); err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(host, ip)
+	}
+	if !p.allow.empty() && !p.allow.matchName(host) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+// CheckSocket reports whether connections through a Unix socket are allowed. When
+// an allowlist is configured the socket path must be listed in it.
+func CheckSocket(path string) error {
+	p := currentPolicy()
+	if p.pinned != nil {
+		return fmt.Errorf("%w: connections are pinned to %s", ErrHostNotAllowed, net.JoinHostPort(p.pinned.Host, strconv.Itoa(p.pinned.Port)))
+	}
+	if p.deny.matchSocket(path) || (!p.allow.empty() && !p.allow.matchSocket(path)) {
+		return fmt.Errorf("%w: socket %s", ErrHostNotAllowed, path)
+	}
+	return nil
+}
+
+// MatchesServer reports whether entries, written like the host policy's, name the
+// server of a connection. Host names are also compared by the addresses they
+// resolve to, so that a server cannot escape an entry by being addressed by IP or
+// another name; wildcards only match names. Connections pinned to a server are
+// matched by the pinned host. Sockets reach the local server, so they also match
+// entries for localhost and loopback addresses.
+func MatchesServer(entries []string, cfg Config) bool {
+	rules := parseHostRules(entries)
+	if rules.empty() {
+		return false
+	}
+	if cfg.Socket != "" {
+		return rules.matchSocket(cfg.Socket) || rules.matchName("localhost") ||
+			rules.matchIP(net.IPv4(127, 0, 0, 1)) || rules.matchIP(net.IPv6loopback)
+	}
+
+	host := cfg.Host
+	if pinnedHost, _, ok := PinnedServer(); ok {
+		host = pinnedHost
+	}
+	if host == "" {
+		return false
+	}
+	if rules.matchName(host) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Hosts behind an SSH tunnel are resolved on the remote side
+	var ips []net.IP
+	if cfg.SSH == nil {
+		ips, _ = lookupHost(ctx, host)
+	} else if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	}
+	for _, ip := range ips {
+		if rules.matchIP(ip) {
+			return true
+		}
+	}
+	for _, name := range rules.names {
+		addrs, err := lookupHost(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			for _, ip := range ips {
+				if addr.Equal(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// currentPolicy returns the installed host policy
+func currentPolicy() *hostPolicy {
+	hostPolicyMu.RLock()
+	defer hostPolicyMu.RUnlock()
+
+	return policy
+}
+
+// policyDial dials a MySQL server after checking every address it resolves to
+func policyDial(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return dialAllowed(ctx, dialer, addr, true)
+}
+
+// dialAllowed dials a TCP address whose host passes the policy, connecting only to
+// checked addresses rather than resolving the name again. SSH hosts are dialed
+// without the server port and pinning rules.
+func dialAllowed(ctx context.Context, dialer *net.Dialer, addr string, server bool) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := currentPolicy()
+	if server {
+		if err := p.checkServer(host, port); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.checkName(host); err != nil {
+		return nil, err
+	}
+	ips, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		if err := p.checkIP(host, ip); err != nil {
+			return nil, err
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), portStr))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// checkServer applies the pinned server and the allowed ports
+func (p *hostPolicy) checkServer(host string, port int) error {
+	if p.pinned != nil && (!strings.EqualFold(host, p.pinned.Host) || port != p.pinned.Port) {
+		return fmt.Errorf("%w: connections are pinned to %s", ErrHostNotAllowed, net.JoinHostPort(p.pinned.Host, strconv.Itoa(p.pinned.Port)))
+	}
+	if p.ports != nil && !p.ports[port] {
+		return fmt.Errorf("%w: port %d", ErrHostNotAllowed, port)
+	}
+	return nil
+}
+
+// checkResolved applies the name rules and the network rules to every address the
+// host resolves to
+func (p *hostPolicy) checkResolved(host string) error {
+	if err := p.checkName(host); err != nil {
+		return err
+	}
+	if p.allow.empty() && len(p.deny.nets) == 0 {
+		return nil
+	}
+
+	ips, err := lookupHost(context.Background(), host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if err := p.checkIP(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkName applies the denied names
+func (p *hostPolicy) checkName(host string) error {
+	if p.deny.matchName(host) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+// checkIP applies the network rules to an address a host resolves to. Hosts
+// allowed by name need not also be in an allowed network.
+func (p *hostPolicy) checkIP(host string, ip net.IP) error {
+	if p.deny.matchIP(ip) {
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("%w: %s (%s)", ErrHostNotAllowed, host, ip)
+		}
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if !p.allow.empty() && !p.allow.matchName(host) && !p.allow.matchIP(ip) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+// parseHostRules splits policy entries into names, wildcards, networks and socket
+// paths
+func parseHostRules(entries []string) hostRules {
+	var r hostRules
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.HasPrefix(entry, "/") {
+			r.sockets = append(r.sockets, filepath.Clean(entry))
+			continue
+		}
+		entry = strings.ToLower(entry)
+		switch {
+		case strings.Contains(entry, "/"):
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+				r.nets = append(r.nets, ipNet)
+			}
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			r.nets = append(r.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case strings.HasPrefix(entry, "*."):
+			r.wildcard = append(r.wildcard, entry[1:])
+		default:
+			r.names = append(r.names, entry)
+		}
+	}
+	return r
+}
+
+// empty reports whether the rules contain no entries
+func (r hostRules) empty() bool {
+	return len(r.names) == 0 && len(r.wildcard) == 0 && len(r.nets) == 0 && len(r.sockets) == 0
+}
+
+// matchName reports whether a host name matches a name or wildcard rule
+func (r hostRules) matchName(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range r.names {
+		if host == name {
+			return true
+		}
+	}
+	for _, suffix := range r.wildcard {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSocket reports whether a socket path is one of the listed sockets
+func (r hostRules) matchSocket(path string) bool {
+	path = filepath.Clean(path)
+	for _, socket := range r.sockets {
+		if path == socket {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIP reports whether an address is in one of the networks
+func (r hostRules) matchIP(ip net.IP) bool {
+	for _, n := range r.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupHost resolves a host to its addresses; IP literals are returned as is
+func lookupHost(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"mysql-admin-tool/internal/models"
+	"testing"
+)
+
+func TestMatchesServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		cfg     Config
+		want    bool
+	}{
+		{"name", []string{"db.internal"}, Config{Host: "DB.internal."}, true},
+		{"wildcard", []string{"*.prod.example.com"}, Config{Host: "db1.prod.example.com"}, true},
+		{"network", []string{"10.0.0.0/8"}, Config{Host: "10.1.2.3"}, true},
+		{"address of a name", []string{"localhost"}, Config{Host: "127.0.0.1"}, true},
+		{"name of an address", []string{"127.0.0.1"}, Config{Host: "localhost"}, true},
+		{"other host", []string{"db.internal", "10.0.0.0/8"}, Config{Host: "192.168.1.1"}, false},
+		{"socket", []string{"/run/mysqld/mysqld.sock"}, Config{Socket: "/run/mysqld/../mysqld/mysqld.sock"}, true},
+		{"socket reaches localhost", []string{"127.0.0.1"}, Config{Socket: "/tmp/mysql.sock"}, true},
+		{"socket and remote host", []string{"db.internal"}, Config{Socket: "/tmp/mysql.sock"}, false},
+		{"tunnel address", []string{"10.0.0.0/8"}, Config{Host: "10.1.2.3", SSH: &models.SSHTunnelOptions{}}, true},
+		{"no entries", nil, Config{Host: "db.internal"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesServer(tt.entries, tt.cfg); got != tt.want {
+				t.Errorf("MatchesServer(%q, %+v) = %v, want %v", tt.entries, tt.cfg, got, tt.want)
+			}
+		})
+	}
+}
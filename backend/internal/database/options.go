@@ -0,0 +1,121 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/models"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// collationPattern matches collation names such as utf8mb4_unicode_ci
+	collationPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// timeZonePattern matches named time zones and offsets such as +02:00
+	timeZonePattern = regexp.MustCompile(`^[A-Za-z0-9_/+:-]+$`)
+	// sqlModePattern matches a comma-separated list of SQL modes
+	sqlModePattern = regexp.MustCompile(`^[A-Za-z_]+(,[A-Za-z_]+)*$`)
+)
+
+// quotingSQLModes change how MySQL reads quotes and backslashes. Statements are
+// classified and literals quoted assuming the default rules, so these modes
+// could smuggle SQL past both.
+var quotingSQLModes = map[string]bool{
+	"ANSI_QUOTES": true, "NO_BACKSLASH_ESCAPES": true,
+	// Combination modes that include ANSI_QUOTES
+	"ANSI": true, "DB2": true, "MAXDB": true, "MSSQL": true, "ORACLE": true, "POSTGRESQL": true,
+}
+
+// CheckSQLMode returns an error when a comma-separated list of SQL modes holds
+// one that changes quoting
+func CheckSQLMode(modes string) error {
+	for _, mode := range strings.Split(modes, ",") {
+		if mode = strings.ToUpper(strings.TrimSpace(mode)); quotingSQLModes[mode] {
+			return fmt.Errorf("SQL mode %s is not supported", mode)
+		}
+	}
+	return nil
+}
+
+// ValidateConnectionOptions checks advanced connection options, including their
+// combination as accepted by the driver
+func ValidateConnectionOptions(opts models.ConnectionOptions) error {
+	cfg := mysql.NewConfig()
+	if err := applyConnectionOptions(cfg, opts); err != nil {
+		return err
+	}
+	// The driver rejects collations unsafe for interpolateParams
+	if _, err := mysql.ParseDSN(cfg.FormatDSN()); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "invalid DSN: "))
+	}
+	return nil
+}
+
+// applyConnectionOptions sets advanced connection options on a driver configuration
+func applyConnectionOptions(cfg *mysql.Config, opts models.ConnectionOptions) error {
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+
+	// SET NAMES would override the collation negotiated in the handshake
+	if opts.Collation != "" {
+		if !collationPattern.MatchString(opts.Collation) {
+			return fmt.Errorf("invalid collation %q", opts.Collation)
+		}
+		cfg.Collation = opts.Collation
+	} else {
+		cfg.Params["charset"] = "utf8mb4"
+	}
+
+	if opts.Location != "" {
+		loc, err := time.LoadLocation(opts.Location)
+		if err != nil {
+			return fmt.Errorf("invalid location %q", opts.Location)
+		}
+		cfg.Loc = loc
+	}
+
+	var err error
+	if cfg.ReadTimeout, err = parseTimeout("read", opts.ReadTimeout); err != nil {
+		return err
+	}
+	if cfg.WriteTimeout, err = parseTimeout("write", opts.WriteTimeout); err != nil {
+		return err
+	}
+
+	cfg.AllowCleartextPasswords = opts.AllowCleartextPasswords
+	cfg.InterpolateParams = opts.InterpolateParams
+
+	// Session variables are sent by the driver as SET name=value after connecting
+	if opts.TimeZone != "" {
+		if !timeZonePattern.MatchString(opts.TimeZone) {
+			return fmt.Errorf("invalid time zone %q", opts.TimeZone)
+		}
+		cfg.Params["time_zone"] = "'" + opts.TimeZone + "'"
+	}
+	if opts.SQLMode != "" {
+		if !sqlModePattern.MatchString(opts.SQLMode) {
+			return fmt.Errorf("invalid SQL mode %q", opts.SQLMode)
+		}
+		if err := CheckSQLMode(opts.SQLMode); err != nil {
+			return err
+		}
+		cfg.Params["sql_mode"] = "'" + opts.SQLMode + "'"
+	}
+	return nil
+}
+
+// parseTimeout parses an optional non-negative duration
+func parseTimeout(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s timeout %q", name, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// readOnlyStatement makes all later transactions of a session read-only, so the
+// server refuses writes even from statements the API did not recognize
+const readOnlyStatement = "SET SESSION TRANSACTION READ ONLY"
+
+// readOnlyConnector opens driver connections whose sessions are read-only
+type readOnlyConnector struct {
+	driver.Connector
+}
+
+// Connect opens a connection and makes its session read-only
+func (c readOnlyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("driver connection cannot execute statements")
+	}
+	if _, err := execer.ExecContext(ctx, readOnlyStatement, nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
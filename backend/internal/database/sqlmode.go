@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sqlModeQuery reads the SQL mode of a session after the driver applied the
+// configured one, which falls back to the server's global mode
+const sqlModeQuery = "SELECT @@SESSION.sql_mode"
+
+// sqlModeConnector opens driver connections whose sessions do not use a SQL mode
+// that changes quoting, wherever the mode came from
+type sqlModeConnector struct {
+	driver.Connector
+}
+
+// Connect opens a connection and checks the SQL mode of its session
+func (c sqlModeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSessionSQLMode(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// checkSessionSQLMode returns an error when the session uses a SQL mode that
+// changes quoting
+func checkSessionSQLMode(ctx context.Context, conn driver.Conn) error {
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return errors.New("driver connection cannot run queries")
+	}
+	rows, err := queryer.QueryContext(ctx, sqlModeQuery, nil)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]driver.Value, 1)
+	if err := rows.Next(values); err != nil {
+		if err == io.EOF {
+			return errors.New("the server did not return its SQL mode")
+		}
+		return err
+	}
+
+	var mode string
+	switch v := values[0].(type) {
+	case []byte:
+		mode = string(v)
+	case string:
+		mode = v
+	}
+	if err := CheckSQLMode(mode); err != nil {
+		return fmt.Errorf("%w in the server's SQL mode; set another with the SQL mode connection option", err)
+	}
+	return nil
+}
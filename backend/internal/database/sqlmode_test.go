@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// modeConn is a driver connection whose session uses a fixed SQL mode
+type modeConn struct {
+	driver.Conn
+	mode string
+}
+
+func (c modeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &modeRows{mode: c.mode}, nil
+}
+
+// modeRows returns the SQL mode as the driver does, in a single row
+type modeRows struct {
+	mode string
+	done bool
+}
+
+func (r *modeRows) Columns() []string { return []string{"@@SESSION.sql_mode"} }
+func (r *modeRows) Close() error      { return nil }
+
+func (r *modeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = []byte(r.mode)
+	return nil
+}
+
+func TestCheckSessionSQLMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{"ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION", false},
+		{"", false},
+		{"REAL_AS_FLOAT,PIPES_AS_CONCAT,ANSI_QUOTES,IGNORE_SPACE,ONLY_FULL_GROUP_BY,ANSI", true},
+		{"STRICT_TRANS_TABLES,NO_BACKSLASH_ESCAPES", true},
+	}
+
+	for _, tt := range tests {
+		err := checkSessionSQLMode(context.Background(), modeConn{mode: tt.mode})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkSessionSQLMode(%q) error = %v, want error %v", tt.mode, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/models"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// sshNetworkName is the driver network of connections through SSH tunnels.
+	// Their address names the tunnel, e.g. "ssh-0123abcd/db.internal:3306".
+	sshNetworkName = "ssh-tunnel"
+	// sshDialTimeout bounds establishing the SSH connection to the bastion
+	sshDialTimeout = 10 * time.Second
+	// sshIdleTimeout closes SSH clients without forwarded connections after this long
+	sshIdleTimeout = 10 * time.Minute
+)
+
+// sshTunnel is a shared SSH client forwarding MySQL connections. Its client
+// configuration, with the parsed known hosts, is built once.
+type sshTunnel struct {
+	opts     models.SSHTunnelOptions
+	config   *ssh.ClientConfig
+	mu       sync.Mutex
+	client   *ssh.Client
+	active   int64
+	lastUsed time.Time
+}
+
+var (
+	sshTunnelsMu      sync.Mutex
+	sshTunnels        = make(map[string]*sshTunnel)
+	sshJanitorOnce    sync.Once
+	sshDialOnce       sync.Once
+	defaultKnownHosts string
+)
+
+// SetDefaultKnownHostsFile sets the known_hosts file used to verify SSH hosts when
+// tunnel options carry no known hosts of their own
+func SetDefaultKnownHostsFile(path string) {
+	defaultKnownHosts = path
+}
+
+// ValidateSSHOptions checks that tunnel options are complete and parseable
+func ValidateSSHOptions(opts models.SSHTunnelOptions) error {
+	if opts.Host == "" || opts.User == "" {
+		return errors.New("SSH host and user are required")
+	}
+	if opts.Password == "" && opts.PrivateKey == "" {
+		return errors.New("SSH password or private key is required")
+	}
+	if _, err := sshClientConfig(opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sshTunnelAddr returns the driver address of addr through the SSH tunnel of the
+// options, to be used with sshNetworkName
+func sshTunnelAddr(opts models.SSHTunnelOptions, addr string) (string, error) {
+	key, err := sshTunnelKey(opts)
+	if err != nil {
+		return "", err
+	}
+	return key + "/" + addr, nil
+}
+
+// sshTunnelKey returns the key of the shared tunnel for the options, creating the
+// tunnel when needed
+func sshTunnelKey(opts models.SSHTunnelOptions) (string, error) {
+	if opts.Port == 0 {
+		opts.Port = 22
+	}
+	sum := sha256.Sum256([]byte(opts.Host + "\x00" + strconv.Itoa(opts.Port) + "\x00" + opts.User + "\x00" +
+		opts.Password + "\x00" + opts.PrivateKey + "\x00" + opts.Passphrase + "\x00" + opts.KnownHosts))
+	name := "ssh-" + hex.EncodeToString(sum[:12])
+
+	sshTunnelsMu.Lock()
+	defer sshTunnelsMu.Unlock()
+
+	if t, ok := sshTunnels[name]; ok {
+		t.mu.Lock()
+		t.lastUsed = time.Now()
+		t.mu.Unlock()
+		return name, nil
+	}
+
+	if opts.Host == "" || opts.User == "" {
+		return "", errors.New("SSH host and user are required")
+	}
+	if opts.Password == "" && opts.PrivateKey == "" {
+		return "", errors.New("SSH password or private key is required")
+	}
+	config, err := sshClientConfig(opts)
+	if err != nil {
+		return "", err
+	}
+
+	sshTunnels[name] = &sshTunnel{opts: opts, config: config, lastUsed: time.Now()}
+	// The driver keeps dialers for good, so a single dialer looks up the tunnel
+	// named in the address, which may have been evicted since
+	sshDialOnce.Do(func() { mysql.RegisterDialContext(sshNetworkName, dialSSH) })
+	sshJanitorOnce.Do(func() { go closeIdleTunnels() })
+	return name, nil
+}
+
+// dialSSH dials an address returned by sshTunnelAddr
+func dialSSH(ctx context.Context, addr string) (net.Conn, error) {
+	name, target, ok := strings.Cut(addr, "/")
+	if !ok {
+		return nil, fmt.Errorf("ssh tunnel: invalid address %q", addr)
+	}
+	return dialTunnel(ctx, name, target)
+}
+
+// dialTunnel opens a connection to addr through the SSH tunnel registered as name
+func dialTunnel(ctx context.Context, name, addr string) (net.Conn, error) {
+	sshTunnelsMu.Lock()
+	t, ok := sshTunnels[name]
+	sshTunnelsMu.Unlock()
+	if !ok {
+		return nil, errors.New("ssh tunnel: the tunnel was closed, please retry")
+	}
+	return t.dial(ctx, addr)
+}
+
+// dial opens a connection to addr through the SSH tunnel, reconnecting once if the
+// shared client has gone away
+func (t *sshTunnel) dial(ctx context.Context, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckRemoteHost(host, port); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		client, err := t.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := client.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			atomic.AddInt64(&t.active, 1)
+			return &tunnelConn{Conn: conn, tunnel: t}, nil
+		}
+		if attempt > 0 || ctx.Err() != nil {
+			return nil, fmt.Errorf("ssh tunnel: %w", err)
+		}
+		t.reset(client)
+	}
+}
+
+// connect returns the shared SSH client, establishing it if necessary
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastUsed = time.Now()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	addr := net.JoinHostPort(t.opts.Host, strconv.Itoa(t.opts.Port))
+	dialer := &net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialAllowed(ctx, dialer, addr, false)
+	if err != nil {
+		return nil, fmt.Errorf("ssh tunnel: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, t.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh tunnel: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	t.client = ssh.NewClient(c, chans, reqs)
+	return t.client, nil
+}
+
+// reset drops a broken client so that the next dial reconnects
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == client {
+		t.client.Close()
+		t.client = nil
+	}
+}
+
+// closeIdleTunnels periodically closes idle tunnels
+func closeIdleTunnels() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		evictIdleTunnels(sshIdleTimeout)
+	}
+}
+
+// evictIdleTunnels closes the SSH clients of tunnels that have forwarded no
+// connections for idle and forgets the tunnels with their credentials. Tunnels
+// of expired sessions go this way; sessions still using one recreate it.
+func evictIdleTunnels(idle time.Duration) {
+	sshTunnelsMu.Lock()
+	defer sshTunnelsMu.Unlock()
+
+	for name, t := range sshTunnels {
+		t.mu.Lock()
+		if atomic.LoadInt64(&t.active) == 0 && time.Since(t.lastUsed) > idle {
+			if t.client != nil {
+				t.client.Close()
+				t.client = nil
+			}
+			delete(sshTunnels, name)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// tunnelConn tracks forwarded connections so idle clients can be closed
+type tunnelConn struct {
+	net.Conn
+	tunnel *sshTunnel
+	once   sync.Once
+}
+
+// Close closes the forwarded connection
+func (c *tunnelConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.tunnel.active, -1)
+	})
+	return c.Conn.Close()
+}
+
+// sshClientConfig builds the SSH client configuration, including host key
+// verification against the known hosts
+func sshClientConfig(opts models.SSHTunnelOptions) (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if opts.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if opts.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(opts.PrivateKey), []byte(opts.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(opts.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if opts.Password != "" {
+		auth = append(auth, ssh.Password(opts.Password))
+	}
+
+	hostKeyCallback, err := knownHostsCallback(opts.KnownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, nil
+}
+
+// knownHostsCallback returns a host key callback for known_hosts content, falling
+// back to the default known_hosts file
+func knownHostsCallback(content string) (ssh.HostKeyCallback, error) {
+	if content == "" {
+		if defaultKnownHosts == "" {
+			return nil, errors.New("SSH known hosts are required to verify the tunnel host")
+		}
+		return knownhosts.New(defaultKnownHosts)
+	}
+
+	// knownhosts only reads files; the content is parsed once and the file removed
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH known hosts: %w", err)
+	}
+	return callback, nil
+}
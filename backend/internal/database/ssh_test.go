@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"mysql-admin-tool/internal/models"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startEchoServer listens on a local port and echoes what it receives
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// startSSHServer runs an in-process SSH server accepting the password of user
+// and forwarding direct-tcpip channels. It returns its address and host key.
+func startSSHServer(t *testing.T, user, password string) (string, ssh.PublicKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+	return l.Addr().String(), signer.PublicKey()
+}
+
+// serveSSH forwards the direct-tcpip channels of one SSH connection
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		forwarded, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			forwarded.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			defer channel.Close()
+			defer forwarded.Close()
+			go io.Copy(forwarded, channel)
+			io.Copy(channel, forwarded)
+		}()
+	}
+}
+
+func TestSSHTunnel(t *testing.T) {
+	echoAddr := startEchoServer(t)
+	sshAddr, hostKey := startSSHServer(t, "tunnel", "secret")
+	host, portStr, _ := net.SplitHostPort(sshAddr)
+	port, _ := strconv.Atoi(portStr)
+	knownHost := knownhosts.Line([]string{knownhosts.Normalize(sshAddr)}, hostKey)
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+	wrongHost := knownhosts.Line([]string{knownhosts.Normalize(sshAddr)}, otherSigner.PublicKey())
+
+	tests := []struct {
+		name    string
+		opts    models.SSHTunnelOptions
+		wantErr bool
+	}{
+		{
+			name: "forwards through the tunnel",
+			opts: models.SSHTunnelOptions{Host: host, Port: port, User: "tunnel", Password: "secret", KnownHosts: knownHost},
+		},
+		{
+			name:    "rejects an unknown host key",
+			opts:    models.SSHTunnelOptions{Host: host, Port: port, User: "tunnel", Password: "secret", KnownHosts: wrongHost},
+			wantErr: true,
+		},
+		{
+			name:    "rejects a wrong password",
+			opts:    models.SSHTunnelOptions{Host: host, Port: port, User: "tunnel", Password: "wrong", KnownHosts: knownHost},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := sshTunnelAddr(tt.opts, echoAddr)
+			if err != nil {
+				t.Fatalf("sshTunnelAddr: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialSSH(ctx, addr)
+			if tt.wantErr {
+				if err == nil {
+					conn.Close()
+					t.Fatal("dial succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				t.Fatal(err)
+			}
+			if string(buf) != "ping" {
+				t.Fatalf("read %q through the tunnel, want ping", buf)
+			}
+		})
+	}
+}
+
+func TestSSHTunnelReuseAndEviction(t *testing.T) {
+	sshAddr, hostKey := startSSHServer(t, "tunnel", "secret")
+	host, portStr, _ := net.SplitHostPort(sshAddr)
+	port, _ := strconv.Atoi(portStr)
+	opts := models.SSHTunnelOptions{
+		Host:       host,
+		Port:       port,
+		User:       "tunnel",
+		Password:   "secret",
+		KnownHosts: knownhosts.Line([]string{knownhosts.Normalize(sshAddr)}, hostKey),
+	}
+
+	name, err := sshTunnelKey(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshTunnelsMu.Lock()
+	first := sshTunnels[name]
+	sshTunnelsMu.Unlock()
+
+	// Later requests reuse the tunnel and its parsed known hosts
+	if again, err := sshTunnelKey(opts); err != nil || again != name {
+		t.Fatalf("sshTunnelKey again = %q, %v; want %q", again, err, name)
+	}
+	sshTunnelsMu.Lock()
+	reused := sshTunnels[name] == first
+	sshTunnelsMu.Unlock()
+	if !reused {
+		t.Fatal("tunnel was rebuilt for the same options")
+	}
+
+	evictIdleTunnels(0)
+	sshTunnelsMu.Lock()
+	_, kept := sshTunnels[name]
+	sshTunnelsMu.Unlock()
+	if kept {
+		t.Fatal("idle tunnel was not evicted")
+	}
+	if _, err := dialTunnel(context.Background(), name, "127.0.0.1:1"); err == nil {
+		t.Fatal("dial through an evicted tunnel succeeded")
+	}
+}
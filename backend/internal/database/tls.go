@@ -0,0 +1,131 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TLS modes accepted in models.TLSOptions
+const (
+	TLSDisabled       = "disabled"
+	TLSPreferred      = "preferred"
+	TLSRequired       = "required"
+	TLSVerifyCA       = "verify-ca"
+	TLSVerifyIdentity = "verify-identity"
+)
+
+// ValidateTLSOptions checks the mode and PEM data of TLS options
+func ValidateTLSOptions(opts models.TLSOptions) error {
+	switch opts.Mode {
+	case "", TLSDisabled, TLSPreferred, TLSRequired, TLSVerifyCA, TLSVerifyIdentity:
+	default:
+		return fmt.Errorf("unknown TLS mode %q", opts.Mode)
+	}
+	_, err := buildTLSConfig(opts, "")
+	return err
+}
+
+// applyTLS selects the TLS settings of a connection on a driver configuration.
+// Custom TLS configurations are set on the configuration itself rather than
+// registered with the driver, whose registry would keep every CA and client
+// certificate ever used.
+func applyTLS(cfg *mysql.Config, opts models.TLSOptions, host string) error {
+	custom := opts.CA != "" || opts.Cert != "" || opts.ServerName != ""
+
+	switch opts.Mode {
+	case "", TLSDisabled:
+		return nil
+	case TLSPreferred:
+		if !custom {
+			cfg.TLSConfig = "preferred"
+			return nil
+		}
+	case TLSRequired:
+		if !custom {
+			cfg.TLSConfig = "skip-verify"
+			return nil
+		}
+	case TLSVerifyCA, TLSVerifyIdentity:
+	default:
+		return fmt.Errorf("unknown TLS mode %q", opts.Mode)
+	}
+
+	config, err := buildTLSConfig(opts, host)
+	if err != nil {
+		return err
+	}
+	cfg.TLS = config
+	cfg.AllowFallbackToPlaintext = opts.Mode == TLSPreferred
+	return nil
+}
+
+// buildTLSConfig creates the tls.Config for the options
+func buildTLSConfig(opts models.TLSOptions, host string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var roots *x509.CertPool
+	if opts.CA != "" {
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM([]byte(opts.CA)) {
+			return nil, errors.New("invalid CA certificate")
+		}
+	}
+
+	if opts.Cert != "" || opts.Key != "" {
+		cert, err := tls.X509KeyPair([]byte(opts.Cert), []byte(opts.Key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	switch opts.Mode {
+	case TLSVerifyIdentity:
+		config.RootCAs = roots
+		config.ServerName = opts.ServerName
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+	case TLSVerifyCA:
+		// Verify the chain against the CA but not the host name
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		}
+	default:
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// verifyChain verifies a peer certificate chain against roots, or the system pool
+// when roots is nil, without checking the host name
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
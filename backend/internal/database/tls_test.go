@@ -0,0 +1,36 @@
+package database
+
+import (
+	"mysql-admin-tool/internal/models"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestApplyTLS(t *testing.T) {
+	tests := []struct {
+		opts           models.TLSOptions
+		wantName       string
+		wantServerName string
+	}{
+		{opts: models.TLSOptions{Mode: TLSDisabled}},
+		{opts: models.TLSOptions{Mode: TLSRequired}, wantName: "skip-verify"},
+		{opts: models.TLSOptions{Mode: TLSPreferred}, wantName: "preferred"},
+		{opts: models.TLSOptions{Mode: TLSVerifyIdentity}, wantServerName: "db.internal"},
+		{opts: models.TLSOptions{Mode: TLSVerifyIdentity, ServerName: "mysql.example.com"}, wantServerName: "mysql.example.com"},
+	}
+
+	for _, tt := range tests {
+		cfg := mysql.NewConfig()
+		if err := applyTLS(cfg, tt.opts, "db.internal"); err != nil {
+			t.Fatalf("applyTLS(%+v): %v", tt.opts, err)
+		}
+		if cfg.TLSConfig != tt.wantName {
+			t.Errorf("applyTLS(%+v) TLSConfig = %q, want %q", tt.opts, cfg.TLSConfig, tt.wantName)
+		}
+		// Custom configurations are not registered with the driver by name
+		if tt.wantServerName != "" && (cfg.TLS == nil || cfg.TLS.ServerName != tt.wantServerName) {
+			t.Errorf("applyTLS(%+v) TLS = %+v, want server name %s", tt.opts, cfg.TLS, tt.wantServerName)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package history keeps the queries each user ran in an embedded bbolt database,
+// one bucket per user keyed by entry ID.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned for history entries the user does not have
+var ErrNotFound = errors.New("history entry not found")
+
+// usersBucket holds a bucket of entries per user
+var usersBucket = []byte("users")
+
+var (
+	mu  sync.Mutex
+	cfg config.HistoryConfig
+	db  *bolt.DB
+)
+
+// Filter selects history entries. Empty fields match everything.
+type Filter struct {
+	// Text matches queries containing it, ignoring case
+	Text       string
+	Database   string
+	Connection string
+	PinnedOnly bool
+	ErrorsOnly bool
+	Page       int
+	PageSize   int
+}
+
+// Init opens the history database. Nothing is recorded when the history is
+// disabled.
+func Init(c config.HistoryConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg = c
+	if !c.Enabled {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0750); err != nil {
+		return err
+	}
+
+	// Another server holding the file would block Open forever
+	store, err := bolt.Open(c.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	err = store.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(usersBucket)
+		return err
+	})
+	if err != nil {
+		store.Close()
+		return err
+	}
+	db = store
+	return nil
+}
+
+// Close closes the history database
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if db == nil {
+		return nil
+	}
+	err := db.Close()
+	db = nil
+	return err
+}
+
+// Enabled reports whether queries are recorded
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return db != nil
+}
+
+// Record adds an entry to the history of a user and removes the oldest unpinned
+// entries beyond the configured maximum. Passwords in the query are redacted.
+// Failures are logged since the query has already run.
+func Record(user string, entry models.HistoryEntry) {
+	store, c := current()
+	if store == nil {
+		return
+	}
+	entry.Query, _ = sqlstmt.RedactPasswords(entry.Query)
+
+	err := store.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(usersBucket).CreateBucketIfNotExists([]byte(user))
+		if err != nil {
+			return err
+		}
+		entry.ID, err = bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := put(bucket, entry); err != nil {
+			return err
+		}
+		return prune(bucket, c.MaxEntries)
+	})
+	if err != nil {
+		log.Printf("Failed to record query history: %v", err)
+	}
+}
+
+// Search returns a page of the entries of a user matching the filter, newest
+// first
+func Search(user string, f Filter) (*models.HistoryPage, error) {
+	page := &models.HistoryPage{
+		Entries:  []models.HistoryEntry{},
+		Page:     f.Page,
+		PageSize: f.PageSize,
+	}
+	store, _ := current()
+	if store == nil {
+		return page, nil
+	}
+
+	text := strings.ToLower(f.Text)
+	skip := (f.Page - 1) * f.PageSize
+	err := store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var entry models.HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if !f.matches(entry, text) {
+				continue
+			}
+			if page.Total >= skip && len(page.Entries) < f.PageSize {
+				page.Entries = append(page.Entries, entry)
+			}
+			page.Total++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// SetPinned pins or unpins an entry of a user. Pinned entries are never removed
+// to make room for new ones.
+func SetPinned(user string, id uint64, pinned bool) (*models.HistoryEntry, error) {
+	store, _ := current()
+	if store == nil {
+		return nil, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+
+	var entry models.HistoryEntry
+	err := store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if bucket == nil {
+			return fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		v := bucket.Get(key(id))
+		if v == nil {
+			return fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		entry.Pinned = pinned
+		return put(bucket, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Delete removes an entry of a user
+func Delete(user string, id uint64) error {
+	store, _ := current()
+	if store == nil {
+		return fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+
+	return store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if bucket == nil || bucket.Get(key(id)) == nil {
+			return fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		return bucket.Delete(key(id))
+	})
+}
+
+// Clear removes the entries of a user, keeping pinned ones unless includePinned
+// is set, and returns the number removed
+func Clear(user string, includePinned bool) (int, error) {
+	store, _ := current()
+	if store == nil {
+		return 0, nil
+	}
+
+	removed := 0
+	err := store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket).Bucket([]byte(user))
+		if bucket == nil {
+			return nil
+		}
+
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var entry models.HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if !entry.Pinned || includePinned {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	return removed, err
+}
+
+// matches reports whether an entry passes the filter; text is the lowercased
+// filter text
+func (f Filter) matches(entry models.HistoryEntry, text string) bool {
+	switch {
+	case f.Database != "" && entry.Database != f.Database:
+		return false
+	case f.Connection != "" && entry.Connection != f.Connection && entry.ConnectionName != f.Connection:
+		return false
+	case f.PinnedOnly && !entry.Pinned:
+		return false
+	case f.ErrorsOnly && entry.Success:
+		return false
+	case text != "" && !strings.Contains(strings.ToLower(entry.Query), text):
+		return false
+	}
+	return true
+}
+
+// prune removes the oldest unpinned entries of a user bucket until at most max
+// unpinned entries remain
+func prune(bucket *bolt.Bucket, max int) error {
+	var unpinned [][]byte
+	err := bucket.ForEach(func(k, v []byte) error {
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		if !entry.Pinned {
+			unpinned = append(unpinned, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keys are in ascending ID order, i.e. oldest first
+	for len(unpinned) > max {
+		if err := bucket.Delete(unpinned[0]); err != nil {
+			return err
+		}
+		unpinned = unpinned[1:]
+	}
+	return nil
+}
+
+// put stores an entry under its ID
+func put(bucket *bolt.Bucket, entry models.HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key(entry.ID), data)
+}
+
+// key encodes an entry ID so that keys sort by ID
+func key(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+// current returns the open database and the configuration
+func current() (*bolt.DB, config.HistoryConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return db, cfg
+}
@@ -0,0 +1,136 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certCheckInterval limits how often the certificate files are checked for changes
+const certCheckInterval = 5 * time.Second
+
+// Timeouts of the redirect server, which only reads request headers, so that idle
+// or slow clients cannot hold connections open
+const (
+	redirectReadTimeout = 10 * time.Second
+	redirectIdleTimeout = 60 * time.Second
+)
+
+// CertReloader serves a certificate and key pair, reloading it when either file
+// changes on disk
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader loads the certificate and key pair
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for use as tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.lastCheck) >= certCheckInterval {
+		r.lastCheck = time.Now()
+		if r.changed() {
+			// Keep serving the previous certificate if the new files are incomplete
+			if err := r.load(); err != nil {
+				log.Printf("Failed to reload TLS certificate: %v", err)
+			} else {
+				log.Printf("Reloaded TLS certificate from %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// TLSConfig returns a server TLS configuration using the reloader
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// changed reports whether the certificate or key file was modified since loading
+func (r *CertReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(r.certMod) || !keyInfo.ModTime().Equal(r.keyMod)
+}
+
+// load reads the certificate and key pair and records the file modification times
+func (r *CertReloader) load() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+	return nil
+}
+
+// NewRedirectServer returns a server on addr redirecting plain HTTP requests to
+// HTTPS on the given port
+func NewRedirectServer(addr string, httpsPort int) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           RedirectHandler(httpsPort),
+		ReadHeaderTimeout: redirectReadTimeout,
+		ReadTimeout:       redirectReadTimeout,
+		WriteTimeout:      redirectReadTimeout,
+		IdleTimeout:       redirectIdleTimeout,
+	}
+}
+
+// RedirectHandler redirects plain HTTP requests to HTTPS on the given port
+func RedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
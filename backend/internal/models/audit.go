@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AuditEntry records a statement run through the API
+type AuditEntry struct {
+	Time           time.Time `json:"time"`
+	User           string    `json:"user"`
+	AuthMethod     string    `json:"authMethod"`
+	SourceIP       string    `json:"sourceIp"`
+	Connection     string    `json:"connection"`
+	ConnectionName string    `json:"connectionName"`
+	Host           string    `json:"host"`
+	Database       string    `json:"database"`
+	// Transaction is the open transaction the statement ran in
+	Transaction string `json:"transaction,omitempty"`
+	// Console is the console session the statement ran in
+	Console string `json:"console,omitempty"`
+	// Snapshot is the snapshot a restore recreated its table from
+	Snapshot string `json:"snapshot,omitempty"`
+	// Action is the API operation, e.g. query, drop_table or grant
+	Action    string `json:"action"`
+	Kind      string `json:"kind,omitempty"`
+	Statement string `json:"statement"`
+	// Params are the values bound to the placeholders of the statement
+	Params       []QueryParam `json:"params,omitempty"`
+	Redacted     bool         `json:"redacted,omitempty"`
+	DurationMs   float64      `json:"durationMs"`
+	RowsAffected *int64       `json:"rowsAffected,omitempty"`
+	RowsReturned *int         `json:"rowsReturned,omitempty"`
+	Error        string       `json:"error,omitempty"`
+}
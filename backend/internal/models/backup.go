@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// BackupSnapshot describes a table snapshot taken before a statement that drops
+// its data
+type BackupSnapshot struct {
+	ID       string `json:"id"`
+	Server   string `json:"server"`
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	User     string `json:"user"`
+	// Owner is the owner key of the session that took the snapshot
+	Owner     string    `json:"owner,omitempty"`
+	Reason    string    `json:"reason"`
+	Statement string    `json:"statement"`
+	Rows      int64     `json:"rows"`
+	SizeBytes int64     `json:"sizeBytes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RestoreBackupRequest represents a request to restore a snapshot. Table defaults
+// to the name of the snapshotted table.
+type RestoreBackupRequest struct {
+	Table string `json:"table"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ConfirmationRequired is returned instead of running destructive statements. The
+// request runs when repeated with Token in the X-Confirm-Token header.
+type ConfirmationRequired struct {
+	Error     string          `json:"error"`
+	Token     string          `json:"token"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Impact    []ImpactSummary `json:"impact"`
+}
+
+// ImpactSummary describes what a destructive statement affects
+type ImpactSummary struct {
+	Statement string         `json:"statement"`
+	Kind      string         `json:"kind"`
+	Reason    string         `json:"reason"`
+	Objects   []ObjectImpact `json:"objects"`
+}
+
+// ObjectImpact describes a database, table or view affected by a statement.
+// Row counts are the server's estimates.
+type ObjectImpact struct {
+	Type          string   `json:"type"`
+	Database      string   `json:"database"`
+	Name          string   `json:"name,omitempty"`
+	Exists        bool     `json:"exists"`
+	Tables        int      `json:"tables,omitempty"`
+	EstimatedRows int64    `json:"estimatedRows"`
+	SizeBytes     int64    `json:"sizeBytes"`
+	ForeignKeys   []string `json:"foreignKeys"`
+	Views         []string `json:"views"`
+}
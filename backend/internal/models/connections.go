@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// SessionConnection represents a named MySQL connection held by the session
+type SessionConnection struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	Socket    string    `json:"socket,omitempty"`
+	Username  string    `json:"username"`
+	Database  string    `json:"database"`
+	Profile   string    `json:"profile,omitempty"`
+	Color     string    `json:"color,omitempty"`
+	Tag       string    `json:"tag,omitempty"`
+	ReadOnly  bool      `json:"readOnly"`
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OpenConnectionRequest represents a request to add a connection to the session.
+// Connection settings are given as for login, directly or through a profile.
+type OpenConnectionRequest struct {
+	Name string `json:"name"`
+	LoginRequest
+}
+
+// TLSOptions represents the TLS settings of a MySQL connection. CA, Cert and Key
+// hold PEM-encoded data.
+type TLSOptions struct {
+	Mode       string `json:"mode"`
+	CA         string `json:"ca,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	ServerName string `json:"serverName,omitempty"`
+	HasKey     bool   `json:"hasKey,omitempty"`
+}
+
+// SSHTunnelOptions represents an SSH tunnel through which a MySQL connection is
+// dialed. PrivateKey and KnownHosts hold the contents of the respective files.
+type SSHTunnelOptions struct {
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	User          string `json:"user"`
+	Password      string `json:"password,omitempty"`
+	PrivateKey    string `json:"privateKey,omitempty"`
+	Passphrase    string `json:"passphrase,omitempty"`
+	KnownHosts    string `json:"knownHosts,omitempty"`
+	HasPassword   bool   `json:"hasPassword,omitempty"`
+	HasPrivateKey bool   `json:"hasPrivateKey,omitempty"`
+}
+
+// ConnectionOptions represents advanced driver settings of a MySQL connection.
+// Timeouts are Go duration strings such as "30s".
+type ConnectionOptions struct {
+	Collation               string `json:"collation,omitempty"`
+	Location                string `json:"loc,omitempty"`
+	TimeZone                string `json:"timeZone,omitempty"`
+	ReadTimeout             string `json:"readTimeout,omitempty"`
+	WriteTimeout            string `json:"writeTimeout,omitempty"`
+	AllowCleartextPasswords bool   `json:"allowCleartextPasswords,omitempty"`
+	InterpolateParams       bool   `json:"interpolateParams,omitempty"`
+	SQLMode                 string `json:"sqlMode,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// OpenConsoleRequest represents a request to open a console session, starting in
+// Database if set
+type OpenConsoleRequest struct {
+	Name     string `json:"name"`
+	Database string `json:"database"`
+}
+
+// Console is a console session keeping one MySQL session across queries.
+// Database is its current default database, changed by USE.
+type Console struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Connection string    `json:"connection"`
+	Database   string    `json:"database"`
+	Statements int       `json:"statements"`
+	OpenedAt   time.Time `json:"openedAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	// ExpiresAt is when the console is closed unless it is used again
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ConsoleQueryRequest represents a query run in a console session. Params are
+// bound as for QueryRequest.
+type ConsoleQueryRequest struct {
+	Query  string       `json:"query"`
+	Params []QueryParam `json:"params"`
+}
+
+// ConsoleQueryResponse is the result of a console query with the console's state
+// after it
+type ConsoleQueryResponse struct {
+	QueryResponse
+	Console Console `json:"console"`
+}
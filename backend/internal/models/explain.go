@@ -0,0 +1,68 @@
+package models
+
+// ExplainRequest represents a request to explain a SQL statement
+type ExplainRequest struct {
+	Database string `json:"database"`
+	Query    string `json:"query"`
+	Analyze  bool   `json:"analyze"`
+}
+
+// PlanCost holds the optimizer cost estimates of a plan node
+type PlanCost struct {
+	Query  float64 `json:"query,omitempty"`
+	Read   float64 `json:"read,omitempty"`
+	Eval   float64 `json:"eval,omitempty"`
+	Prefix float64 `json:"prefix,omitempty"`
+	Total  float64 `json:"total,omitempty"`
+}
+
+// PlanActual holds the measured execution statistics of a plan node (EXPLAIN ANALYZE)
+type PlanActual struct {
+	FirstRowMs float64 `json:"firstRowMs"`
+	AllRowsMs  float64 `json:"allRowsMs"`
+	Rows       float64 `json:"rows"`
+	Loops      int64   `json:"loops"`
+	Executed   bool    `json:"executed"`
+}
+
+// PlanNode represents a single node of an execution plan tree
+type PlanNode struct {
+	Operation      string      `json:"operation"`
+	SelectID       int         `json:"selectId,omitempty"`
+	Table          string      `json:"table,omitempty"`
+	AccessType     string      `json:"accessType,omitempty"`
+	PossibleKeys   []string    `json:"possibleKeys,omitempty"`
+	Key            string      `json:"key,omitempty"`
+	UsedKeyParts   []string    `json:"usedKeyParts,omitempty"`
+	KeyLength      string      `json:"keyLength,omitempty"`
+	Ref            []string    `json:"ref,omitempty"`
+	Rows           *float64    `json:"rows,omitempty"`
+	RowsProduced   *float64    `json:"rowsProduced,omitempty"`
+	Filtered       *float64    `json:"filtered,omitempty"`
+	Cost           *PlanCost   `json:"cost,omitempty"`
+	Actual         *PlanActual `json:"actual,omitempty"`
+	Condition      string      `json:"condition,omitempty"`
+	Message        string      `json:"message,omitempty"`
+	UsingIndex     bool        `json:"usingIndex,omitempty"`
+	UsingFilesort  bool        `json:"usingFilesort,omitempty"`
+	UsingTemporary bool        `json:"usingTemporary,omitempty"`
+	Dependent      bool        `json:"dependent,omitempty"`
+	Children       []*PlanNode `json:"children,omitempty"`
+}
+
+// PlanWarning flags a potentially expensive part of an execution plan
+type PlanWarning struct {
+	Type    string `json:"type"`
+	Table   string `json:"table,omitempty"`
+	Message string `json:"message"`
+}
+
+// ExplainResponse represents a parsed execution plan
+type ExplainResponse struct {
+	Format        string        `json:"format"`
+	Analyze       bool          `json:"analyze"`
+	ServerVersion string        `json:"serverVersion"`
+	Plan          *PlanNode     `json:"plan"`
+	Warnings      []PlanWarning `json:"warnings"`
+	Raw           string        `json:"raw"`
+}
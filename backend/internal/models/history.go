@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// HistoryEntry is a query run by a user
+type HistoryEntry struct {
+	ID    uint64 `json:"id"`
+	Query string `json:"query"`
+	// Params are the values bound to the placeholders of the query
+	Params         []QueryParam `json:"params,omitempty"`
+	Database       string       `json:"database"`
+	Connection     string       `json:"connection"`
+	ConnectionName string       `json:"connectionName"`
+	ExecutedAt     time.Time    `json:"executedAt"`
+	DurationMs     float64      `json:"durationMs"`
+	// Rows is the number of rows returned, or affected by statements that return
+	// none
+	Rows    int64  `json:"rows"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Pinned  bool   `json:"pinned"`
+}
+
+// HistoryPage represents a page of query history, newest first
+type HistoryPage struct {
+	Entries  []HistoryEntry `json:"entries"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+}
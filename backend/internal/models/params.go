@@ -0,0 +1,42 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// QueryParam is a value bound to a placeholder of a query: positional ? when
+// Name is empty, :name otherwise. Type is an optional hint such as "decimal",
+// "date" or "binary". In JSON a bare value stands for a parameter without name
+// and type.
+type QueryParam struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// UnmarshalJSON accepts a parameter object or a bare value. Numbers are kept as
+// json.Number so that decimals and large integers keep their precision.
+func (p *QueryParam) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	object, ok := value.(map[string]interface{})
+	if _, hasValue := object["value"]; !ok || !hasValue {
+		*p = QueryParam{Value: value}
+		return nil
+	}
+
+	*p = QueryParam{Value: object["value"]}
+	if name, ok := object["name"].(string); ok {
+		p.Name = name
+	}
+	if typ, ok := object["type"].(string); ok {
+		p.Type = typ
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// ConnectionProfile represents a saved, named MySQL connection
+type ConnectionProfile struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Host        string             `json:"host"`
+	Port        int                `json:"port"`
+	Database    string             `json:"database"`
+	Socket      string             `json:"socket,omitempty"`
+	Username    string             `json:"username"`
+	Password    string             `json:"password,omitempty"`
+	HasPassword bool               `json:"hasPassword"`
+	TLS         *TLSOptions        `json:"tls,omitempty"`
+	SSH         *SSHTunnelOptions  `json:"ssh,omitempty"`
+	Options     *ConnectionOptions `json:"options,omitempty"`
+	ReadOnly    bool               `json:"readOnly"`
+	Color       string             `json:"color,omitempty"`
+	Tag         string             `json:"tag,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+// ProfileSummary represents the public part of a profile shown on the login screen
+type ProfileSummary struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+	HasPassword bool   `json:"hasPassword"`
+	ReadOnly    bool   `json:"readOnly"`
+}
+
+// ProfileRequest represents a request to create or update a profile. An empty
+// password keeps the stored one unless ClearPassword is set.
+type ProfileRequest struct {
+	Name          string             `json:"name"`
+	Host          string             `json:"host"`
+	Port          int                `json:"port"`
+	Database      string             `json:"database"`
+	Socket        string             `json:"socket"`
+	Username      string             `json:"username"`
+	Password      string             `json:"password"`
+	ClearPassword bool               `json:"clearPassword"`
+	TLS           *TLSOptions        `json:"tls"`
+	SSH           *SSHTunnelOptions  `json:"ssh"`
+	Options       *ConnectionOptions `json:"options"`
+	ReadOnly      bool               `json:"readOnly"`
+	Color         string             `json:"color"`
+	Tag           string             `json:"tag"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// SavedQuery is a named query kept on the server. Its :name placeholders are
+// bound to values when it is executed.
+type SavedQuery struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Query       string   `json:"query"`
+	// Database is the default database the query runs in
+	Database   string       `json:"database"`
+	Parameters []string     `json:"parameters"`
+	Owner      string       `json:"owner"`
+	SharedWith QuerySharing `json:"sharedWith"`
+	// Owned is set when the requesting user owns the query and may change it
+	Owned     bool      `json:"owned"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// QuerySharing lists who besides the owner may see and run a saved query. Users
+// are local application usernames, OpenID Connect users (oidc:issuer#subject) or
+// MySQL accounts (user@host:port or user@unix:socket); roles are application
+// roles, i.e. teams.
+type QuerySharing struct {
+	Users    []string `json:"users"`
+	Roles    []string `json:"roles"`
+	Everyone bool     `json:"everyone"`
+}
+
+// SavedQueryRequest represents a request to create or update a saved query
+type SavedQueryRequest struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Tags        []string     `json:"tags"`
+	Query       string       `json:"query"`
+	Database    string       `json:"database"`
+	SharedWith  QuerySharing `json:"sharedWith"`
+}
+
+// ExecuteSavedQueryRequest represents a request to run a saved query. Database
+// overrides the saved default; Params holds a value, or a value with a type hint,
+// for each placeholder.
+type ExecuteSavedQueryRequest struct {
+	Database string                `json:"database"`
+	Params   map[string]QueryParam `json:"params"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// ServerInfo represents general information about the MySQL server
+type ServerInfo struct {
+	Version        string `json:"version"`
+	VersionComment string `json:"versionComment"`
+	Hostname       string `json:"hostname"`
+	Port           int    `json:"port"`
+	CurrentUser    string `json:"currentUser"`
+	UptimeSeconds  int64  `json:"uptimeSeconds"`
+	StartedAt      string `json:"startedAt"`
+}
+
+// ServerVariable represents a status or system variable
+type ServerVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SetVariableRequest represents a request to change a global system variable
+type SetVariableRequest struct {
+	Value string `json:"value"`
+}
+
+// ServerMetrics represents metrics computed from a GLOBAL STATUS sample
+type ServerMetrics struct {
+	Timestamp           time.Time `json:"timestamp"`
+	IntervalSeconds     float64   `json:"intervalSeconds"`
+	QPS                 float64   `json:"qps"`
+	SelectsPerSec       float64   `json:"selectsPerSec"`
+	WritesPerSec        float64   `json:"writesPerSec"`
+	SlowQueries         int64     `json:"slowQueries"`
+	BufferPoolHitRatio  float64   `json:"bufferPoolHitRatio"`
+	ConnectionsInUse    int64     `json:"connectionsInUse"`
+	ThreadsRunning      int64     `json:"threadsRunning"`
+	MaxConnections      int64     `json:"maxConnections"`
+	MaxUsedConnections  int64     `json:"maxUsedConnections"`
+	ConnectionUsage     float64   `json:"connectionUsage"`
+	BytesReceivedPerSec float64   `json:"bytesReceivedPerSec"`
+	BytesSentPerSec     float64   `json:"bytesSentPerSec"`
+}
+
+// ServerMetricsResponse represents the latest metrics and the sampled history
+type ServerMetricsResponse struct {
+	Current ServerMetrics   `json:"current"`
+	History []ServerMetrics `json:"history"`
+}
+
+// Process represents a server thread from the process list
+type Process struct {
+	ID      int64   `json:"id"`
+	User    string  `json:"user"`
+	Host    string  `json:"host"`
+	DB      *string `json:"db"`
+	Command string  `json:"command"`
+	Time    int64   `json:"time"`
+	State   *string `json:"state"`
+	Info    *string `json:"info"`
+}
+
+// ProcessFilter represents filters applied to the process list
+type ProcessFilter struct {
+	User    string `query:"user"`
+	DB      string `query:"db"`
+	Command string `query:"command"`
+	MinTime int64  `query:"minTime"`
+}
+
+// KillProcessRequest represents a request to kill a connection or its running query
+type KillProcessRequest struct {
+	QueryOnly bool `json:"queryOnly"`
+}
+
+// LoginLimit represents the failed login state of a client IP or account
+type LoginLimit struct {
+	Key          string     `json:"key"`
+	Failures     int        `json:"failures"`
+	LastFailure  time.Time  `json:"lastFailure"`
+	BlockedUntil *time.Time `json:"blockedUntil,omitempty"`
+	Locked       bool       `json:"locked"`
+}
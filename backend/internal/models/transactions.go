@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// BeginTransactionRequest represents a request to begin a transaction in a
+// database. Isolation is READ UNCOMMITTED, READ COMMITTED, REPEATABLE READ or
+// SERIALIZABLE; empty uses the server default.
+type BeginTransactionRequest struct {
+	Database  string `json:"database"`
+	Isolation string `json:"isolation"`
+	ReadOnly  bool   `json:"readOnly"`
+}
+
+// Transaction is a transaction held open across requests
+type Transaction struct {
+	ID         string    `json:"id"`
+	Connection string    `json:"connection"`
+	Database   string    `json:"database"`
+	Isolation  string    `json:"isolation,omitempty"`
+	ReadOnly   bool      `json:"readOnly"`
+	Statements int       `json:"statements"`
+	StartedAt  time.Time `json:"startedAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	// ExpiresAt is when the transaction is rolled back unless it is used again
+	ExpiresAt time.Time `json:"expiresAt"`
+}
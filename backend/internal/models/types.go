@@ -1,28 +1,40 @@
 package models
 
+// ObjectPrivileges represents what the logged-in account may do with an object
+type ObjectPrivileges struct {
+	Select bool `json:"select"`
+	Insert bool `json:"insert"`
+	Update bool `json:"update"`
+	Delete bool `json:"delete"`
+	Alter  bool `json:"alter"`
+	Drop   bool `json:"drop"`
+}
+
 // Database represents a MySQL database
 type Database struct {
-	Name string `json:"name"`
+	Name       string            `json:"name"`
+	Privileges *ObjectPrivileges `json:"privileges,omitempty"`
 }
 
 // Table represents a MySQL table
 type Table struct {
-	Name    string `json:"name"`
-	Engine  string `json:"engine"`
-	Rows    int64  `json:"rows"`
-	Size    string `json:"size"`
-	Comment string `json:"comment"`
+	Name       string            `json:"name"`
+	Engine     string            `json:"engine"`
+	Rows       int64             `json:"rows"`
+	Size       string            `json:"size"`
+	Comment    string            `json:"comment"`
+	Privileges *ObjectPrivileges `json:"privileges,omitempty"`
 }
 
 // Column represents a table column
 type Column struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Null         string `json:"null"`
-	Key          string `json:"key"`
-	Default      *string `json:"default"`
-	Extra        string `json:"extra"`
-	Comment      string `json:"comment"`
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Null    string  `json:"null"`
+	Key     string  `json:"key"`
+	Default *string `json:"default"`
+	Extra   string  `json:"extra"`
+	Comment string  `json:"comment"`
 }
 
 // TableStructure represents the structure of a table
@@ -38,18 +50,23 @@ type Index struct {
 	Unique  bool     `json:"unique"`
 }
 
-// QueryRequest represents a SQL query request
+// QueryRequest represents a SQL query request. Params are bound to the ? or :name
+// placeholders of the query. Transaction runs the query in an open transaction.
 type QueryRequest struct {
-	Database string `json:"database"`
-	Query    string `json:"query"`
+	Database    string       `json:"database"`
+	Query       string       `json:"query"`
+	Params      []QueryParam `json:"params"`
+	Transaction string       `json:"transaction"`
 }
 
 // QueryResponse represents a SQL query response
 type QueryResponse struct {
-	Columns []string        `json:"columns"`
-	Rows    [][]interface{} `json:"rows"`
-	Affected int64          `json:"affected"`
-	Error   string          `json:"error,omitempty"`
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	Affected int64           `json:"affected"`
+	Error    string          `json:"error,omitempty"`
+	// Snapshots are the tables backed up before the query dropped their data
+	Snapshots []BackupSnapshot `json:"snapshots,omitempty"`
 }
 
 // TableDataRequest represents a request for table data
@@ -64,20 +81,28 @@ type TableDataRequest struct {
 
 // TableDataResponse represents paginated table data
 type TableDataResponse struct {
-	Columns   []string        `json:"columns"`
-	Rows      [][]interface{} `json:"rows"`
-	Total     int64           `json:"total"`
-	Page      int             `json:"page"`
-	PageSize  int             `json:"pageSize"`
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	Total    int64           `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
 }
 
 // LoginRequest represents a login request
 type LoginRequest struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Database string `json:"database"`
+	Profile  string             `json:"profile"`
+	Host     string             `json:"host"`
+	Port     int                `json:"port"`
+	Username string             `json:"username"`
+	Password string             `json:"password"`
+	Database string             `json:"database"`
+	Socket   string             `json:"socket"`
+	TLS      *TLSOptions        `json:"tls"`
+	SSH      *SSHTunnelOptions  `json:"ssh"`
+	Options  *ConnectionOptions `json:"options"`
+	// ReadOnly restricts the connection to reading; profiles can only make a
+	// login stricter
+	ReadOnly bool `json:"readOnly"`
 }
 
 // LoginResponse represents a login response
@@ -90,3 +115,25 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// AppLoginRequest represents a login with an application account
+type AppLoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Profile  string `json:"profile"`
+}
+
+// AuthMethods represents the login methods offered by the server
+type AuthMethods struct {
+	MySQL bool `json:"mysql"`
+	Local bool `json:"local"`
+	OIDC  bool `json:"oidc"`
+}
+
+// CurrentUser represents the identity of the logged-in session
+type CurrentUser struct {
+	Username string           `json:"username"`
+	Method   string           `json:"method"`
+	Roles    []string         `json:"roles"`
+	Access   string           `json:"access"`
+	Profiles []ProfileSummary `json:"profiles"`
+}
@@ -0,0 +1,84 @@
+package models
+
+// MySQLUser represents an account from mysql.user
+type MySQLUser struct {
+	User            string `json:"user"`
+	Host            string `json:"host"`
+	Plugin          string `json:"plugin"`
+	AccountLocked   bool   `json:"accountLocked"`
+	PasswordExpired bool   `json:"passwordExpired"`
+}
+
+// CreateUserRequest represents a request to create a MySQL account
+type CreateUserRequest struct {
+	User     string `json:"user"`
+	Host     string `json:"host"`
+	Password string `json:"password"`
+}
+
+// RenameUserRequest represents a request to rename a MySQL account
+type RenameUserRequest struct {
+	User string `json:"user"`
+	Host string `json:"host"`
+}
+
+// ChangePasswordRequest represents a request to change an account password
+type ChangePasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// Grant privilege levels
+const (
+	GrantLevelGlobal   = "global"
+	GrantLevelDatabase = "database"
+	GrantLevelTable    = "table"
+	GrantLevelColumn   = "column"
+	GrantLevelRoutine  = "routine"
+	GrantLevelProxy    = "proxy"
+	GrantLevelRole     = "role"
+)
+
+// GrantPrivilege represents a single privilege of a grant, optionally limited to columns
+type GrantPrivilege struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+// Grant represents one parsed SHOW GRANTS statement
+type Grant struct {
+	Level           string           `json:"level"`
+	Privileges      []GrantPrivilege `json:"privileges,omitempty"`
+	Database        string           `json:"database,omitempty"`
+	Table           string           `json:"table,omitempty"`
+	RoutineType     string           `json:"routineType,omitempty"`
+	Roles           []string         `json:"roles,omitempty"`
+	WithGrantOption bool             `json:"withGrantOption"`
+	Statement       string           `json:"statement"`
+}
+
+// UserGrants represents the grants held by an account
+type UserGrants struct {
+	Account string   `json:"account"`
+	Grants  []Grant  `json:"grants"`
+	Raw     []string `json:"raw"`
+}
+
+// PrivilegeRequest represents a request to grant or revoke privileges. The level is
+// derived from the fields set: none for global, database for database level, table
+// for table level and columns for column level.
+type PrivilegeRequest struct {
+	Privileges      []string `json:"privileges"`
+	Database        string   `json:"database"`
+	Table           string   `json:"table"`
+	Columns         []string `json:"columns"`
+	WithGrantOption bool     `json:"withGrantOption"`
+}
+
+// CurrentPrivileges represents the effective privileges of the logged-in account
+type CurrentPrivileges struct {
+	Account string   `json:"account"`
+	Global  []string `json:"global"`
+	Roles   []string `json:"roles"`
+	Grants  []Grant  `json:"grants"`
+	Raw     []string `json:"raw"`
+}
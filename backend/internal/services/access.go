@@ -0,0 +1,258 @@
+package services
+
+import (
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// AccessLevel is what a session may do, from reading to administration
+type AccessLevel int
+
+// Access levels in increasing order
+const (
+	AccessNone AccessLevel = iota
+	AccessViewer
+	AccessEditor
+	AccessAdmin
+)
+
+// levelCategories lists the statement categories each access level may run
+var levelCategories = map[AccessLevel]map[sqlstmt.Category]bool{
+	AccessViewer: {
+		sqlstmt.Read: true, sqlstmt.Session: true, sqlstmt.Transaction: true,
+	},
+	AccessEditor: {
+		sqlstmt.Read: true, sqlstmt.Session: true, sqlstmt.Transaction: true,
+		sqlstmt.Write: true, sqlstmt.DDL: true,
+	},
+	AccessAdmin: {
+		sqlstmt.Read: true, sqlstmt.Session: true, sqlstmt.Transaction: true,
+		sqlstmt.Write: true, sqlstmt.DDL: true, sqlstmt.Admin: true, sqlstmt.Other: true,
+	},
+}
+
+// readOnlyCategories lists the statement categories allowed by read-only policies
+var readOnlyCategories = levelCategories[AccessViewer]
+
+// systemSchemas may be read under schema restrictions since they only expose
+// metadata the account can see anyway
+var systemSchemas = map[string]bool{
+	"information_schema": true,
+	"performance_schema": true,
+}
+
+var (
+	policiesMu sync.RWMutex
+	policies   []config.PolicyConfig
+)
+
+// String returns the configuration name of the level
+func (l AccessLevel) String() string {
+	switch l {
+	case AccessViewer:
+		return config.AccessViewer
+	case AccessEditor:
+		return config.AccessEditor
+	case AccessAdmin:
+		return config.AccessAdmin
+	}
+	return "none"
+}
+
+// parseAccessLevel converts a configured access level; roles without one are
+// viewers
+func parseAccessLevel(s string) AccessLevel {
+	switch s {
+	case config.AccessEditor:
+		return AccessEditor
+	case config.AccessAdmin:
+		return AccessAdmin
+	}
+	return AccessViewer
+}
+
+// InitPolicies sets the per-connection statement policies
+func InitPolicies(cfg []config.PolicyConfig) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	policies = cfg
+}
+
+// Access returns the access level of the session: the highest level of the
+// user's roles, or the configured level for MySQL logins
+func (s *Session) Access() AccessLevel {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	if s.User == nil {
+		return mysqlLoginAccess()
+	}
+	level := AccessNone
+	for _, role := range s.User.Roles {
+		if l, ok := roleAccess[role]; ok && l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// mysqlLoginAccess returns the access level of MySQL logins. Without a configured
+// level they are administrators, as before access levels existed, and once roles
+// are configured they get no access. The caller holds identityMu.
+func mysqlLoginAccess() AccessLevel {
+	switch identity.MySQLLoginAccess {
+	case "":
+		if len(identity.Roles) > 0 {
+			return AccessNone
+		}
+		return AccessAdmin
+	case config.AccessNone:
+		return AccessNone
+	}
+	return parseAccessLevel(identity.MySQLLoginAccess)
+}
+
+// RequireAccess returns ErrForbidden when the session is below an access level
+func RequireAccess(session *Session, level AccessLevel) error {
+	if session.Access() < level {
+		return fmt.Errorf("%w: %s access is required", ErrForbidden, level)
+	}
+	return nil
+}
+
+// AuthorizeStatements checks statements against the session's access level and
+// the policies of the connection they run on. defaultDB is the database selected
+// before the first statement; Parse resolves USE statements within the batch.
+func AuthorizeStatements(session *Session, conn *SessionConnection, statements []sqlstmt.Statement, defaultDB string) error {
+	level := session.Access()
+	matched := connectionPolicies(conn)
+
+	for _, stmt := range statements {
+		name := stmt.Kind
+		if name == "" {
+			name = "unrecognized"
+		}
+		if !levelCategories[level][stmt.Category] {
+			return fmt.Errorf("%w: %s statements are not allowed with %s access", ErrForbidden, name, level)
+		}
+
+		if conn.Config.ReadOnly && !readOnlyAllowed(stmt) {
+			return fmt.Errorf("%w: connection %s is read-only", ErrForbidden, conn.Name)
+		}
+
+		// Prepared statements run SQL that is not known here, possibly prepared
+		// by an earlier request
+		if len(matched) > 0 && (stmt.Keyword == "PREPARE" || stmt.Keyword == "EXECUTE") {
+			return fmt.Errorf("%w: prepared statements are not allowed on connection %s", ErrForbidden, conn.Name)
+		}
+
+		schemas := stmt.Schemas
+		if stmt.UsesDefault && defaultDB != "" {
+			schemas = append(append([]string{}, schemas...), defaultDB)
+		}
+		for _, p := range matched {
+			if p.ReadOnly && !readOnlyCategories[stmt.Category] {
+				return fmt.Errorf("%w: connection %s is read-only", ErrForbidden, conn.Name)
+			}
+			for _, denied := range p.DenyStatements {
+				denied = strings.TrimSpace(denied)
+				if strings.EqualFold(denied, stmt.Keyword) || strings.EqualFold(denied, stmt.Kind) {
+					return fmt.Errorf("%w: %s statements are denied on connection %s", ErrForbidden, name, conn.Name)
+				}
+			}
+			for _, schema := range schemas {
+				if !schemaAllowed(p, schema) && !(systemSchemas[strings.ToLower(schema)] && stmt.Category == sqlstmt.Read) {
+					return fmt.Errorf("%w: database %s is not allowed on connection %s", ErrForbidden, schema, conn.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SchemaVisible reports whether the policies of a connection allow a database,
+// for hiding the others from listings
+func SchemaVisible(conn *SessionConnection, schema string) bool {
+	if systemSchemas[strings.ToLower(schema)] {
+		return true
+	}
+	for _, p := range connectionPolicies(conn) {
+		if !schemaAllowed(p, schema) {
+			return false
+		}
+	}
+	return true
+}
+
+// RestrictPrivileges clears the capabilities of a database or table that the
+// session's access level, a read-only connection or the connection's policies
+// refuse, so that listings only offer what would be allowed to run
+func RestrictPrivileges(session *Session, conn *SessionConnection, dbName string, privs *models.ObjectPrivileges) {
+	if privs == nil {
+		return
+	}
+	allowed := func(keyword, kind string, category sqlstmt.Category) bool {
+		stmt := sqlstmt.Statement{Keyword: keyword, Kind: kind, Category: category, Schemas: []string{dbName}}
+		return AuthorizeStatements(session, conn, []sqlstmt.Statement{stmt}, "") == nil
+	}
+	privs.Select = privs.Select && allowed("SELECT", "SELECT", sqlstmt.Read)
+	privs.Insert = privs.Insert && allowed("INSERT", "INSERT", sqlstmt.Write)
+	privs.Update = privs.Update && allowed("UPDATE", "UPDATE", sqlstmt.Write)
+	privs.Delete = privs.Delete && allowed("DELETE", "DELETE", sqlstmt.Write)
+	privs.Alter = privs.Alter && allowed("ALTER", "ALTER TABLE", sqlstmt.DDL)
+	privs.Drop = privs.Drop && allowed("DROP", "DROP TABLE", sqlstmt.DDL)
+}
+
+// schemaAllowed reports whether a policy's schema patterns allow a database
+func schemaAllowed(p config.PolicyConfig, schema string) bool {
+	if len(p.Schemas) == 0 {
+		return true
+	}
+	for _, pattern := range p.Schemas {
+		if ok, _ := path.Match(pattern, schema); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// connectionPolicies returns the policies that apply to a connection, matched by
+// its profile or its host
+func connectionPolicies(conn *SessionConnection) []config.PolicyConfig {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+
+	var profileID string
+	if conn.Profile != "" {
+		profileID, _ = findProfileID(conn.Profile)
+	}
+
+	var matched []config.PolicyConfig
+	for _, p := range policies {
+		if policyMatches(p, profileID, conn.Config) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// policyMatches reports whether a policy names the profile or the server of a
+// connection
+func policyMatches(p config.PolicyConfig, profileID string, cfg database.Config) bool {
+	for _, ref := range p.Profiles {
+		if ref == "*" {
+			return true
+		}
+		if id, ok := findProfileID(ref); ok && profileID != "" && id == profileID {
+			return true
+		}
+	}
+	return database.MatchesServer(p.Hosts, cfg)
+}
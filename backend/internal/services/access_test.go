@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"testing"
+)
+
+func TestAuthorizeStatementsPolicies(t *testing.T) {
+	InitIdentity(config.IdentityConfig{MySQLLoginAccess: config.AccessAdmin})
+	InitPolicies([]config.PolicyConfig{{Name: "production", Hosts: []string{"db.internal"}, Schemas: []string{"app"}}})
+	t.Cleanup(func() {
+		InitIdentity(config.Default().Identity)
+		InitPolicies(nil)
+	})
+
+	session := &Session{ID: "s1"}
+	production := &SessionConnection{ID: PrimaryConnectionID, Name: "production", Config: database.Config{Host: "db.internal"}}
+	staging := &SessionConnection{ID: PrimaryConnectionID, Name: "staging", Config: database.Config{Host: "db.staging"}}
+
+	tests := []struct {
+		query   string
+		conn    *SessionConnection
+		wantErr bool
+	}{
+		{"SELECT * FROM app.t", production, false},
+		{"SELECT * FROM other.t", production, true},
+		{"PREPARE s FROM 'SELECT * FROM other.t'", production, true},
+		{"EXECUTE s", production, true},
+		{"PREPARE s FROM 'SELECT 1'", staging, false},
+		{"EXECUTE s", staging, false},
+	}
+
+	for _, tt := range tests {
+		err := AuthorizeStatements(session, tt.conn, sqlstmt.Parse(tt.query), "app")
+		if tt.wantErr != errors.Is(err, ErrForbidden) {
+			t.Errorf("AuthorizeStatements(%q) on %s: error = %v, want forbidden %v", tt.query, tt.conn.Name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRestrictPrivileges(t *testing.T) {
+	InitIdentity(config.IdentityConfig{MySQLLoginAccess: config.AccessEditor})
+	InitPolicies([]config.PolicyConfig{{Name: "production", Hosts: []string{"db.internal"}, DenyStatements: []string{"DROP TABLE"}, Schemas: []string{"app"}}})
+	t.Cleanup(func() {
+		InitIdentity(config.Default().Identity)
+		InitPolicies(nil)
+	})
+
+	session := &Session{ID: "s1"}
+	production := &SessionConnection{ID: PrimaryConnectionID, Name: "production", Config: database.Config{Host: "db.internal"}}
+	replica := &SessionConnection{ID: PrimaryConnectionID, Name: "replica", Config: database.Config{Host: "db.replica", ReadOnly: true}}
+	all := models.ObjectPrivileges{Select: true, Insert: true, Update: true, Delete: true, Alter: true, Drop: true}
+
+	tests := []struct {
+		name   string
+		conn   *SessionConnection
+		dbName string
+		want   models.ObjectPrivileges
+	}{
+		{"denied statements", production, "app", models.ObjectPrivileges{Select: true, Insert: true, Update: true, Delete: true, Alter: true}},
+		{"other schemas", production, "other", models.ObjectPrivileges{}},
+		{"read-only connections", replica, "app", models.ObjectPrivileges{Select: true}},
+	}
+
+	for _, tt := range tests {
+		privs := all
+		RestrictPrivileges(session, tt.conn, tt.dbName, &privs)
+		if privs != tt.want {
+			t.Errorf("%s: privileges = %+v, want %+v", tt.name, privs, tt.want)
+		}
+	}
+
+	// Viewers only keep reading
+	InitIdentity(config.IdentityConfig{MySQLLoginAccess: config.AccessViewer})
+	staging := &SessionConnection{ID: PrimaryConnectionID, Name: "staging", Config: database.Config{Host: "db.staging"}}
+	privs := all
+	RestrictPrivileges(session, staging, "app", &privs)
+	if want := (models.ObjectPrivileges{Select: true}); privs != want {
+		t.Errorf("viewer: privileges = %+v, want %+v", privs, want)
+	}
+}
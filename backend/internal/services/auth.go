@@ -2,9 +2,10 @@ package services
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
 	"os"
 	"time"
 
@@ -12,7 +13,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("default-secret-change-in-production")
+// defaultJWTSecret signs tokens when JWT_SECRET is not set. It is public, so
+// anyone can forge tokens signed with it.
+const defaultJWTSecret = "default-secret-change-in-production"
+
+var jwtSecret = []byte(defaultJWTSecret)
 
 // Claims represents JWT claims
 type Claims struct {
@@ -21,6 +26,17 @@ type Claims struct {
 	DBUser     string `json:"db_user"`
 	DBPassword string `json:"db_password"`
 	DBDatabase string `json:"db_database"`
+	DBSocket   string `json:"db_socket,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+	// Settings reports that the TLS, SSH and driver settings of the login are
+	// held by the server under the token's ID; tokens never carry their secrets
+	Settings   bool     `json:"settings,omitempty"`
+	ReadOnly   bool     `json:"read_only,omitempty"`
+	AppUser    string   `json:"app_user,omitempty"`
+	AppRoles   []string `json:"app_roles,omitempty"`
+	AuthMethod string   `json:"auth_method,omitempty"`
+	AppIssuer  string   `json:"app_issuer,omitempty"`
+	AppSubject string   `json:"app_subject,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -31,20 +47,75 @@ func InitAuth() {
 	}
 }
 
-// GenerateToken generates a JWT token with MySQL credentials
-func GenerateToken(host string, port int, user, password, database string) (string, error) {
+// JWTSecretSet reports whether tokens are signed with a secret of the server's
+// own rather than the public default
+func JWTSecretSet() bool {
+	return string(jwtSecret) != defaultJWTSecret
+}
+
+// CheckJWTSecret refuses the default JWT secret once connection profiles or
+// application accounts exist, since forged tokens could use their credentials
+func CheckJWTSecret() error {
+	if JWTSecretSet() {
+		return nil
+	}
+	if len(ListProfiles()) > 0 {
+		return errors.New("JWT_SECRET must be set when connection profiles are stored")
+	}
+
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	if appAccountsConfigured() {
+		return errors.New("JWT_SECRET must be set when application accounts are configured")
+	}
+	return nil
+}
+
+// GenerateToken generates a JWT token with MySQL credentials and a new session ID.
+// When the login used a profile's stored password, the password is left out and
+// resolved from the profile on the server instead of being handed to the client.
+// Tokens of application users carry their identity.
+func GenerateToken(config database.Config, profile string, storedPassword bool, user *AppUser) (string, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	password := config.Password
+	if storedPassword {
+		password = ""
+	}
+
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		DBHost:     host,
-		DBPort:     port,
-		DBUser:     user,
+		DBHost:     config.Host,
+		DBPort:     config.Port,
+		DBUser:     config.User,
 		DBPassword: password,
-		DBDatabase: database,
+		DBDatabase: config.Database,
+		DBSocket:   config.Socket,
+		Profile:    profile,
+		ReadOnly:   config.ReadOnly,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if user != nil {
+		claims.AppUser = user.Username
+		claims.AppRoles = user.Roles
+		claims.AuthMethod = user.Method
+		claims.AppIssuer = user.Issuer
+		claims.AppSubject = user.Subject
+	}
+	// Profile logins take their TLS, SSH and driver settings from the profile;
+	// those of other logins, which hold keys and passwords, stay on the server
+	if profile == "" && (config.TLS.Mode != "" || config.SSH != nil || config.Options != (models.ConnectionOptions{})) {
+		storeLoginSettings(sessionID, config, expirationTime)
+		claims.Settings = true
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtSecret)
@@ -68,17 +139,118 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// ResolveLogin builds the connection settings of a login request, filling them from
+// a saved profile when one is selected. It reports whether the profile's stored
+// password is used.
+func ResolveLogin(req models.LoginRequest) (database.Config, bool, error) {
+	storedPassword := false
+	if req.Profile != "" {
+		profile, err := ResolveProfile(req.Profile)
+		if err != nil {
+			return database.Config{}, false, err
+		}
+		req.Host = profile.Host
+		req.Port = profile.Port
+		req.Database = profile.Database
+		req.Socket = profile.Socket
+		req.TLS = profile.TLS
+		req.SSH = profile.SSH
+		req.Options = profile.Options
+		req.ReadOnly = req.ReadOnly || profile.ReadOnly
+		if profile.Username != "" {
+			req.Username = profile.Username
+		}
+		if req.Password == "" {
+			if !profile.HasPassword {
+				return database.Config{}, false, fmt.Errorf("%w: password required", ErrInvalidInput)
+			}
+			req.Password = profile.Password
+			storedPassword = true
+		}
+	}
+
+	// Set defaults
+	if req.Host == "" {
+		req.Host = "localhost"
+	}
+	if req.Port == 0 {
+		req.Port = 3306
+	}
+	if req.Database == "" {
+		req.Database = "mysql"
+	}
+
+	config := database.Config{
+		Host:     req.Host,
+		Port:     req.Port,
+		User:     req.Username,
+		Password: req.Password,
+		Database: req.Database,
+		Socket:   req.Socket,
+		ReadOnly: req.ReadOnly,
+	}
+	if req.TLS != nil {
+		if err := database.ValidateTLSOptions(*req.TLS); err != nil {
+			return database.Config{}, false, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		config.TLS = *req.TLS
+	}
+	if req.SSH != nil {
+		sshOptions := *req.SSH
+		if sshOptions.Port == 0 {
+			sshOptions.Port = 22
+		}
+		if err := database.ValidateSSHOptions(sshOptions); err != nil {
+			return database.Config{}, false, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		config.SSH = &sshOptions
+	}
+	if req.Socket != "" && req.SSH != nil {
+		return database.Config{}, false, fmt.Errorf("%w: a unix socket cannot be used through an SSH tunnel", ErrInvalidInput)
+	}
+	if req.Options != nil {
+		if err := database.ValidateConnectionOptions(*req.Options); err != nil {
+			return database.Config{}, false, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		config.Options = *req.Options
+	}
+
+	// Connections to a pinned server ignore the requested address
+	if host, port, ok := database.PinnedServer(); ok {
+		config.Host = host
+		config.Port = port
+		config.Socket = ""
+		config.SSH = nil
+	}
+	if err := checkConnectionHosts(config); err != nil {
+		if !errors.Is(err, database.ErrHostNotAllowed) {
+			err = fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return database.Config{}, false, err
+	}
+
+	return config, storedPassword, nil
+}
+
+// checkConnectionHosts applies the host policy to the servers a connection uses.
+// Unix sockets must be listed when an allowlist is configured.
+func checkConnectionHosts(config database.Config) error {
+	switch {
+	case config.Socket != "":
+		return database.CheckSocket(config.Socket)
+	case config.SSH != nil:
+		if err := database.CheckSSHHost(config.SSH.Host); err != nil {
+			return err
+		}
+		return database.CheckRemoteHost(config.Host, config.Port)
+	default:
+		return database.CheckHost(config.Host, config.Port)
+	}
+}
+
 // ValidateMySQLConnection validates MySQL credentials by attempting to connect
-func ValidateMySQLConnection(host string, port int, user, password, database string) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4&timeout=5s",
-		user,
-		password,
-		host,
-		port,
-		database,
-	)
-
-	db, err := sql.Open("mysql", dsn)
+func ValidateMySQLConnection(config database.Config) error {
+	db, err := database.ConnectToDatabase(config.Database, config)
 	if err != nil {
 		return err
 	}
@@ -94,4 +266,3 @@ func ValidateMySQLConnection(host string, port int, user, password, database str
 
 	return nil
 }
-
@@ -0,0 +1,648 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxInsertBytes bounds the size of one INSERT statement of a snapshot, well below
+// the default max_allowed_packet
+const maxInsertBytes = 1 << 20
+
+// errSnapshotTooLarge stops the dump of a table beyond backups.max_table_bytes
+var errSnapshotTooLarge = errors.New("table exceeds backups.max_table_bytes")
+
+// backupIDPattern matches snapshot IDs, which are also their file names
+var backupIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}Z-[0-9a-f]{8}$`)
+
+var (
+	backupMu     sync.Mutex
+	backupConfig config.BackupConfig
+)
+
+// InitBackups creates the snapshot directory. No snapshots are taken when backups
+// are disabled.
+func InitBackups(cfg config.BackupConfig) error {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	backupConfig = cfg
+	if !cfg.Enabled {
+		return nil
+	}
+	return os.MkdirAll(cfg.Dir, 0700)
+}
+
+// BackupsEnabled reports whether tables are snapshotted before their data is
+// dropped
+func BackupsEnabled() bool {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	return backupConfig.Enabled
+}
+
+// SnapshotBeforeStatements snapshots the existing tables whose data statements
+// drop, such as DROP TABLE or ALTER TABLE ... DROP COLUMN. Unqualified tables are
+// in dbName. The snapshots record the user name and the owner key of the session.
+func SnapshotBeforeStatements(cfg database.Config, dbName string, statements []sqlstmt.Statement, user, owner string) ([]models.BackupSnapshot, error) {
+	if !BackupsEnabled() {
+		return nil, nil
+	}
+
+	var snapshots []models.BackupSnapshot
+	seen := make(map[sqlstmt.Table]bool)
+	for _, stmt := range statements {
+		if !stmt.DropsData() {
+			continue
+		}
+		for i, table := range stmt.Tables {
+			// ALTER TABLE ... RENAME TO names the new table last
+			if stmt.Kind == "ALTER TABLE" && i > 0 {
+				break
+			}
+			table.Schema = tableSchema(table, dbName)
+			if seen[table] {
+				continue
+			}
+			seen[table] = true
+
+			snapshot, err := snapshotTable(cfg, table.Schema, table.Name, stmt, user, owner)
+			if err != nil {
+				return nil, fmt.Errorf("snapshot of %s.%s failed: %w", table.Schema, table.Name, err)
+			}
+			if snapshot != nil {
+				snapshots = append(snapshots, *snapshot)
+			}
+		}
+	}
+
+	if len(snapshots) > 0 {
+		pruneBackups()
+	}
+	return snapshots, nil
+}
+
+// ListBackups returns the snapshots, newest first. Empty server, dbName, table or
+// owner match any.
+func ListBackups(server, dbName, table, owner string) ([]models.BackupSnapshot, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	all, err := readBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]models.BackupSnapshot, 0, len(all))
+	for _, s := range all {
+		if (server == "" || s.Server == server) && (dbName == "" || s.Database == dbName) && (table == "" || s.Table == table) && (owner == "" || s.Owner == owner) {
+			snapshots = append(snapshots, s)
+		}
+	}
+	return snapshots, nil
+}
+
+// RestoreBackup recreates the table of a snapshot taken on the connection's
+// server in dbName, as table or under its original name when table is empty. The
+// table must not exist. Snapshots can be restored by the owner that took them;
+// administrators may restore others' when their MySQL account can read the
+// original table. It also returns the CREATE TABLE statement it ran, if it got
+// that far.
+func RestoreBackup(cfg database.Config, id, dbName, table, owner string, admin bool) (*models.BackupSnapshot, string, error) {
+	snapshot, err := getBackup(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if snapshot.Server != BackupServer(cfg) || snapshot.Database != dbName || (snapshot.Owner != owner && !admin) {
+		return nil, "", fmt.Errorf("%w: snapshot %s", ErrNotFound, id)
+	}
+	if table == "" {
+		table = snapshot.Table
+	}
+
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	defer dbConn.Close()
+
+	if snapshot.Owner != owner {
+		privileges, err := loadEffectivePrivileges(dbConn)
+		if err != nil {
+			return nil, "", err
+		}
+		if !privileges.forTable(snapshot.Database, snapshot.Table).has("SELECT") {
+			return nil, "", fmt.Errorf("%w: SELECT on %s.%s is required to restore another user's snapshot", ErrForbidden, snapshot.Database, snapshot.Table)
+		}
+	}
+
+	var exists int
+	err = dbConn.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, dbName, table).Scan(&exists)
+	if err != nil {
+		return nil, "", err
+	}
+	if exists > 0 {
+		return nil, "", fmt.Errorf("%w: table %s already exists", ErrInvalidInput, table)
+	}
+
+	f, err := os.Open(backupPath(snapshot.ID, ".sql.gz"))
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, "", err
+	}
+	defer gz.Close()
+
+	// The session settings of the dump apply to its statements, so they all run
+	// on one connection
+	ctx := context.Background()
+	conn, err := dbConn.Conn(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+
+	created, createStatement := false, ""
+	err = replayDump(gz, func(statement string) error {
+		statement = renameDumpTable(statement, snapshot.Table, table)
+		isCreate := strings.HasPrefix(statement, "CREATE TABLE ")
+		if isCreate {
+			createStatement = statement
+		}
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+		if isCreate {
+			created = true
+		}
+		return nil
+	})
+	if err != nil {
+		// Leave no partially restored table behind
+		if created {
+			conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+quoteIdentifier(table))
+		}
+		return nil, createStatement, err
+	}
+	return snapshot, createStatement, nil
+}
+
+// DeleteBackup removes a snapshot
+func DeleteBackup(id string) error {
+	if _, err := getBackup(id); err != nil {
+		return err
+	}
+
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	return removeBackup(id)
+}
+
+// BackupServer identifies the MySQL server of a connection in snapshots
+func BackupServer(cfg database.Config) string {
+	if cfg.Socket != "" {
+		return cfg.Socket
+	}
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+}
+
+// snapshotTable dumps a table into a gzipped SQL file with a JSON metadata file
+// next to it. Tables that do not exist, views and tables larger than
+// backups.max_table_bytes are skipped.
+func snapshotTable(cfg database.Config, dbName, table string, stmt sqlstmt.Statement, user, owner string) (*models.BackupSnapshot, error) {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	backupMu.Lock()
+	dir, maxBytes := backupConfig.Dir, backupConfig.MaxTableBytes
+	backupMu.Unlock()
+
+	var tableType string
+	var dataLength int64
+	err = dbConn.QueryRow(`
+		SELECT TABLE_TYPE, COALESCE(DATA_LENGTH, 0) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, dbName, table).Scan(&tableType, &dataLength)
+	if errors.Is(err, sql.ErrNoRows) || tableType == "VIEW" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && dataLength > maxBytes {
+		log.Printf("Not snapshotting %s.%s before %s: %d bytes exceed backups.max_table_bytes", dbName, table, stmt.Kind, dataLength)
+		return nil, nil
+	}
+
+	id, err := newBackupID()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &models.BackupSnapshot{
+		ID:        id,
+		Server:    BackupServer(cfg),
+		Database:  dbName,
+		Table:     table,
+		User:      user,
+		Owner:     owner,
+		Reason:    stmt.Kind,
+		Statement: stmt.Text,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	dataPath := backupPath(id, ".sql.gz")
+	tmp, err := os.CreateTemp(dir, id+"-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// DATA_LENGTH is an estimate, so the dump itself is limited too
+	gz := gzip.NewWriter(tmp)
+	var w io.Writer = gz
+	if maxBytes > 0 {
+		w = &limitedWriter{w: gz, remaining: maxBytes}
+	}
+	snapshot.Rows, err = dumpTable(dbConn, w, snapshot)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, errSnapshotTooLarge) {
+		log.Printf("Not snapshotting %s.%s before %s: the dump exceeds backups.max_table_bytes", dbName, table, stmt.Kind)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmp.Name(), dataPath); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dataPath); err == nil {
+		snapshot.SizeBytes = info.Size()
+	}
+
+	// The metadata is written last; a snapshot without it is not listed
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(backupPath(id, ".json"), data, 0600); err != nil {
+		os.Remove(dataPath)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// limitedWriter fails with errSnapshotTooLarge once more than remaining bytes
+// are written
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, errSnapshotTooLarge
+	}
+	l.remaining -= int64(len(p))
+	return l.w.Write(p)
+}
+
+// dumpTable writes the structure and rows of a table as SQL statements and
+// returns the number of rows. Each statement ends with ";" at the end of a line;
+// SHOW CREATE TABLE escapes line breaks in comments and INSERT values are quoted
+// on one line.
+func dumpTable(dbConn *sql.DB, w io.Writer, snapshot *models.BackupSnapshot) (int64, error) {
+	var name, createSQL string
+	if err := dbConn.QueryRow("SHOW CREATE TABLE "+quoteIdentifier(snapshot.Table)).Scan(&name, &createSQL); err != nil {
+		return 0, err
+	}
+
+	// Generated columns are computed again when the table is restored
+	columns, err := queryNames(dbConn, `
+		SELECT COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		  AND EXTRA NOT LIKE '%VIRTUAL GENERATED%' AND EXTRA NOT LIKE '%STORED GENERATED%'
+		ORDER BY ORDINAL_POSITION`, snapshot.Database, snapshot.Table)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- Snapshot of %s.%s on %s\n", quoteIdentifier(snapshot.Database), quoteIdentifier(snapshot.Table), snapshot.Server)
+	fmt.Fprintf(bw, "-- Taken %s before %s\n\n", snapshot.CreatedAt.Format(time.RFC3339), snapshot.Reason)
+	bw.WriteString("SET NAMES utf8mb4;\n")
+	bw.WriteString("SET FOREIGN_KEY_CHECKS=0;\n")
+	bw.WriteString("SET SQL_MODE='NO_AUTO_VALUE_ON_ZERO';\n\n")
+	bw.WriteString(createSQL + ";\n\n")
+
+	if len(columns) == 0 {
+		bw.WriteString("SET FOREIGN_KEY_CHECKS=1;\n")
+		return 0, bw.Flush()
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	columnList := strings.Join(quoted, ", ")
+	insertPrefix := "INSERT INTO " + quoteIdentifier(snapshot.Table) + " (" + columnList + ") VALUES "
+
+	rows, err := dbConn.Query("SELECT " + columnList + " FROM " + quoteIdentifier(snapshot.Table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	typeNames := make([]string, len(types))
+	for i, t := range types {
+		typeNames[i] = t.DatabaseTypeName()
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var count int64
+	var insert strings.Builder
+	flush := func() error {
+		if insert.Len() == 0 {
+			return nil
+		}
+		_, err := bw.WriteString(insert.String() + ";\n")
+		insert.Reset()
+		return err
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+
+		encoded := make([]string, len(values))
+		for i, v := range values {
+			encoded[i] = sqlLiteral(v, typeNames[i])
+		}
+		row := "(" + strings.Join(encoded, ",") + ")"
+
+		if insert.Len() > 0 && insert.Len()+len(row) > maxInsertBytes {
+			// Stops at the first failed write, e.g. beyond max_table_bytes
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+		if insert.Len() == 0 {
+			insert.WriteString(insertPrefix)
+		} else {
+			insert.WriteByte(',')
+		}
+		insert.WriteString(row)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+
+	bw.WriteString("\nSET FOREIGN_KEY_CHECKS=1;\n")
+	return count, bw.Flush()
+}
+
+// sqlLiteral encodes a scanned value of a column of the given database type as a
+// MySQL literal
+func sqlLiteral(value interface{}, typeName string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		if typeName == "DATE" {
+			if v.IsZero() {
+				return "'0000-00-00'"
+			}
+			return "'" + v.Format("2006-01-02") + "'"
+		}
+		if v.IsZero() {
+			return "'0000-00-00 00:00:00'"
+		}
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		switch {
+		case isBinaryType(typeName):
+			if len(v) == 0 {
+				return "''"
+			}
+			return "0x" + hex.EncodeToString(v)
+		case isNumericType(typeName):
+			return string(v)
+		default:
+			return quoteString(string(v))
+		}
+	case int64, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		return quoteString(fmt.Sprint(v))
+	}
+}
+
+// isNumericType reports whether values of a column type are written unquoted
+func isNumericType(typeName string) bool {
+	typeName = strings.TrimPrefix(typeName, "UNSIGNED ")
+	switch typeName {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR":
+		return true
+	}
+	return false
+}
+
+// isBinaryType reports whether values of a column type are written as hex
+func isBinaryType(typeName string) bool {
+	switch typeName {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return true
+	}
+	return false
+}
+
+// replayDump calls exec with each statement of a dump written by dumpTable,
+// without the terminating ";"
+func replayDump(r io.Reader, exec func(statement string) error) error {
+	br := bufio.NewReader(r)
+	var statement strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case statement.Len() == 0 && (strings.TrimSpace(trimmed) == "" || strings.HasPrefix(trimmed, "--")):
+		case strings.HasSuffix(trimmed, ";"):
+			statement.WriteString(strings.TrimSuffix(trimmed, ";"))
+			if execErr := exec(statement.String()); execErr != nil {
+				return execErr
+			}
+			statement.Reset()
+		default:
+			statement.WriteString(line)
+		}
+
+		if err == io.EOF {
+			if strings.TrimSpace(statement.String()) != "" {
+				return errors.New("snapshot ends in the middle of a statement")
+			}
+			return nil
+		}
+	}
+}
+
+// renameDumpTable points the CREATE TABLE and INSERT statements of a dump at
+// another table name
+func renameDumpTable(statement, from, to string) string {
+	if from == to {
+		return statement
+	}
+	for _, prefix := range []string{"CREATE TABLE ", "INSERT INTO "} {
+		if strings.HasPrefix(statement, prefix+quoteIdentifier(from)+" ") {
+			return prefix + quoteIdentifier(to) + strings.TrimPrefix(statement, prefix+quoteIdentifier(from))
+		}
+	}
+	return statement
+}
+
+// getBackup reads the metadata of a snapshot
+func getBackup(id string) (*models.BackupSnapshot, error) {
+	if !backupIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: snapshot %s", ErrNotFound, id)
+	}
+
+	data, err := os.ReadFile(backupPath(id, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: snapshot %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.BackupSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// readBackups reads the metadata of all snapshots, newest first. The caller holds
+// backupMu.
+func readBackups() ([]models.BackupSnapshot, error) {
+	if backupConfig.Dir == "" {
+		return []models.BackupSnapshot{}, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(backupConfig.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]models.BackupSnapshot, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var snapshot models.BackupSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil || !backupIDPattern.MatchString(snapshot.ID) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// pruneBackups removes the snapshots beyond the configured age and count
+func pruneBackups() {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	snapshots, err := readBackups()
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-backupConfig.MaxAge)
+	for i, s := range snapshots {
+		if (backupConfig.MaxCount > 0 && i >= backupConfig.MaxCount) || (backupConfig.MaxAge > 0 && s.CreatedAt.Before(cutoff)) {
+			removeBackup(s.ID)
+		}
+	}
+}
+
+// removeBackup deletes the files of a snapshot. The caller holds backupMu.
+func removeBackup(id string) error {
+	// Without its metadata the snapshot is no longer listed
+	if err := os.Remove(backupPath(id, ".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(backupPath(id, ".sql.gz")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// backupPath returns the path of a snapshot file. The directory is only set by
+// InitBackups at startup.
+func backupPath(id, ext string) string {
+	return filepath.Join(backupConfig.Dir, id+ext)
+}
+
+// newBackupID returns a snapshot ID, ordered by time
+func newBackupID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typeName string
+		want     string
+	}{
+		{nil, "VARCHAR", "NULL"},
+		{[]byte("plain"), "VARCHAR", "'plain'"},
+		{[]byte("it's"), "TEXT", "'it''s'"},
+		{[]byte("a\\b"), "TEXT", `'a\\b'`},
+		{[]byte("line\nbreak;\r\x00\x1a"), "TEXT", `'line\nbreak;\r\0\Z'`},
+		{[]byte("42"), "INT", "42"},
+		{[]byte("-1.50"), "DECIMAL", "-1.50"},
+		{[]byte("7"), "UNSIGNED BIGINT", "7"},
+		{[]byte{0x00, 0xff}, "BLOB", "0x00ff"},
+		{[]byte{}, "VARBINARY", "''"},
+		{int64(-3), "INT", "-3"},
+		{1.5, "DOUBLE", "1.5"},
+		{true, "TINYINT", "true"},
+		{time.Date(2024, 2, 29, 13, 4, 5, 123000000, time.UTC), "DATETIME", "'2024-02-29 13:04:05.123'"},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), "DATE", "'2024-02-29'"},
+		{time.Time{}, "DATE", "'0000-00-00'"},
+		{time.Time{}, "TIMESTAMP", "'0000-00-00 00:00:00'"},
+		{"fallback'", "", "'fallback'''"},
+	}
+	for _, tt := range tests {
+		if got := sqlLiteral(tt.value, tt.typeName); got != tt.want {
+			t.Errorf("sqlLiteral(%#v, %s) = %s, want %s", tt.value, tt.typeName, got, tt.want)
+		}
+	}
+}
+
+func TestReplayDump(t *testing.T) {
+	dump := "-- Snapshot of `shop`.`orders`\n" +
+		"\n" +
+		"SET NAMES utf8mb4;\n" +
+		"CREATE TABLE `orders` (\n" +
+		"  `id` int NOT NULL\n" +
+		");\n" +
+		"\n" +
+		"INSERT INTO `orders` (`id`, `note`) VALUES (1,'a;'),(2,'b\\n;');\n"
+
+	var got []string
+	err := replayDump(strings.NewReader(dump), func(statement string) error {
+		got = append(got, statement)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SET NAMES utf8mb4",
+		"CREATE TABLE `orders` (\n  `id` int NOT NULL\n)",
+		"INSERT INTO `orders` (`id`, `note`) VALUES (1,'a;'),(2,'b\\n;')",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayDump statements = %q, want %q", got, want)
+	}
+
+	// A truncated dump is refused rather than running a partial statement
+	err = replayDump(strings.NewReader("CREATE TABLE `t` (\n  `id` int\n"), func(string) error { return nil })
+	if err == nil {
+		t.Error("replayDump of a truncated dump succeeded")
+	}
+}
+
+func TestRenameDumpTable(t *testing.T) {
+	tests := []struct {
+		statement string
+		from, to  string
+		want      string
+	}{
+		{"CREATE TABLE `orders` (\n  `id` int\n)", "orders", "orders_restored", "CREATE TABLE `orders_restored` (\n  `id` int\n)"},
+		{"INSERT INTO `orders` (`id`) VALUES (1)", "orders", "o`x", "INSERT INTO `o``x` (`id`) VALUES (1)"},
+		{"INSERT INTO `orders_old` (`id`) VALUES (1)", "orders", "x", "INSERT INTO `orders_old` (`id`) VALUES (1)"},
+		{"SET NAMES utf8mb4", "orders", "x", "SET NAMES utf8mb4"},
+		{"CREATE TABLE `orders` (`id` int)", "orders", "orders", "CREATE TABLE `orders` (`id` int)"},
+	}
+	for _, tt := range tests {
+		if got := renameDumpTable(tt.statement, tt.from, tt.to); got != tt.want {
+			t.Errorf("renameDumpTable(%q, %s, %s) = %q, want %q", tt.statement, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestBackupOwners(t *testing.T) {
+	if err := InitBackups(config.BackupConfig{Enabled: true, Dir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitBackups(config.BackupConfig{}) })
+
+	cfg := database.Config{Host: "db.internal", Port: 3306}
+	for i, owner := range []string{"app:alice", "app:bob"} {
+		snapshot := models.BackupSnapshot{
+			ID:        fmt.Sprintf("20240101T00000%dZ-0000000%d", i, i),
+			Server:    BackupServer(cfg),
+			Database:  "shop",
+			Table:     "orders",
+			Owner:     owner,
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		}
+		data, _ := json.Marshal(snapshot)
+		if err := os.WriteFile(backupPath(snapshot.ID, ".json"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mine, err := ListBackups(BackupServer(cfg), "shop", "", "app:alice")
+	if err != nil || len(mine) != 1 || mine[0].Owner != "app:alice" {
+		t.Fatalf("ListBackups(alice) = %v, %v; want alice's snapshot", mine, err)
+	}
+	if all, _ := ListBackups(BackupServer(cfg), "shop", "", ""); len(all) != 2 {
+		t.Errorf("ListBackups() returned %d snapshots, want 2", len(all))
+	}
+
+	// Another user's snapshot is not found before any connection is made
+	if _, _, err := RestoreBackup(cfg, "20240101T000001Z-00000001", "shop", "", "app:alice", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RestoreBackup() of bob's snapshot: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var b strings.Builder
+	w := &limitedWriter{w: &b, remaining: 8}
+	if _, err := w.Write([]byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("6789")); !errors.Is(err, errSnapshotTooLarge) {
+		t.Errorf("Write beyond the limit: error = %v, want errSnapshotTooLarge", err)
+	}
+	if b.String() != "12345" {
+		t.Errorf("written %q, want %q", b.String(), "12345")
+	}
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pendingConfirmation is an issued confirmation token, bound to the operation it
+// confirms
+type pendingConfirmation struct {
+	sessionID    string
+	connectionID string
+	operation    [sha256.Size]byte
+	expiresAt    time.Time
+}
+
+var (
+	confirmMu            sync.Mutex
+	confirmConfig        = config.Default().Confirm
+	pendingConfirmations = make(map[string]*pendingConfirmation)
+)
+
+// InitConfirmations sets the lifetime of confirmation tokens and the size from
+// which ALTER TABLE needs confirmation
+func InitConfirmations(cfg config.ConfirmConfig) {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+
+	confirmConfig = cfg
+}
+
+// RequestConfirmation issues a single-use token confirming an operation, such as
+// a query text, on a session connection
+func RequestConfirmation(sessionID, connectionID, operation string) (string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b)
+
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+
+	now := time.Now()
+	for key, p := range pendingConfirmations {
+		if now.After(p.expiresAt) {
+			delete(pendingConfirmations, key)
+		}
+	}
+
+	expiresAt := now.Add(confirmConfig.TTL)
+	pendingConfirmations[token] = &pendingConfirmation{
+		sessionID:    sessionID,
+		connectionID: connectionID,
+		operation:    sha256.Sum256([]byte(operation)),
+		expiresAt:    expiresAt,
+	}
+	return token, expiresAt, nil
+}
+
+// ConfirmOperation consumes a token issued for exactly this operation
+func ConfirmOperation(token, sessionID, connectionID, operation string) error {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+
+	p, ok := pendingConfirmations[token]
+	if !ok || time.Now().After(p.expiresAt) {
+		delete(pendingConfirmations, token)
+		return fmt.Errorf("%w: confirmation token is invalid or expired", ErrInvalidInput)
+	}
+	sum := sha256.Sum256([]byte(operation))
+	if p.sessionID != sessionID || p.connectionID != connectionID || subtle.ConstantTimeCompare(p.operation[:], sum[:]) != 1 {
+		return fmt.Errorf("%w: confirmation token was issued for a different operation", ErrInvalidInput)
+	}
+	delete(pendingConfirmations, token)
+	return nil
+}
+
+// AssessImpact returns the impact of the statements that need confirmation, or
+// nil when none does: DROP, TRUNCATE, DELETE and UPDATE without WHERE, and ALTER
+// TABLE on large tables. dbName is the database unqualified tables belong to.
+func AssessImpact(cfg database.Config, dbName string, statements []sqlstmt.Statement) ([]models.ImpactSummary, error) {
+	var candidates []sqlstmt.Statement
+	for _, stmt := range statements {
+		if destructiveReason(stmt) != "" || stmt.Kind == "ALTER TABLE" {
+			candidates = append(candidates, stmt)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	confirmMu.Lock()
+	largeTableRows := confirmConfig.LargeTableRows
+	confirmMu.Unlock()
+
+	var result []models.ImpactSummary
+	for _, stmt := range candidates {
+		summary := models.ImpactSummary{
+			Statement: stmt.Text,
+			Kind:      stmt.Kind,
+			Reason:    destructiveReason(stmt),
+			Objects:   []models.ObjectImpact{},
+		}
+
+		switch {
+		case stmt.Kind == "DROP DATABASE" || stmt.Kind == "DROP SCHEMA":
+			for _, schema := range stmt.Schemas {
+				impact, err := databaseImpact(dbConn, schema)
+				if err != nil {
+					return nil, err
+				}
+				summary.Objects = append(summary.Objects, *impact)
+			}
+		case stmt.Keyword == "DROP" || stmt.Keyword == "TRUNCATE" || stmt.Keyword == "ALTER":
+			for _, table := range stmt.Tables {
+				impact, err := tableImpact(dbConn, tableSchema(table, dbName), table.Name)
+				if err != nil {
+					return nil, err
+				}
+				summary.Objects = append(summary.Objects, *impact)
+				// ALTER TABLE ... RENAME TO names the new table last
+				if stmt.Keyword == "ALTER" {
+					break
+				}
+			}
+		case len(stmt.Tables) > 0:
+			// The target of DELETE and UPDATE comes first; later tables are read
+			impact, err := tableImpact(dbConn, tableSchema(stmt.Tables[0], dbName), stmt.Tables[0].Name)
+			if err != nil {
+				return nil, err
+			}
+			summary.Objects = append(summary.Objects, *impact)
+		}
+
+		if stmt.Kind == "ALTER TABLE" {
+			if len(summary.Objects) == 0 || summary.Objects[0].EstimatedRows < largeTableRows {
+				continue
+			}
+			summary.Reason = fmt.Sprintf("alters a table with about %d rows", summary.Objects[0].EstimatedRows)
+		}
+		result = append(result, summary)
+	}
+	return result, nil
+}
+
+// destructiveReason returns why a statement always needs confirmation, or ""
+func destructiveReason(stmt sqlstmt.Statement) string {
+	switch stmt.Keyword {
+	case "DROP":
+		object := strings.ToLower(strings.TrimPrefix(stmt.Kind, "DROP "))
+		if object == "drop" {
+			object = "objects"
+		}
+		return "drops " + object
+	case "TRUNCATE":
+		return "removes all rows"
+	case "DELETE":
+		if !stmt.HasWhere {
+			return "deletes all rows"
+		}
+	case "UPDATE":
+		if !stmt.HasWhere {
+			return "updates all rows"
+		}
+	}
+	return ""
+}
+
+// tableSchema returns the database of a table, defaulting to dbName
+func tableSchema(table sqlstmt.Table, dbName string) string {
+	if table.Schema != "" {
+		return table.Schema
+	}
+	return dbName
+}
+
+// tableImpact looks up the size of a table or view and what depends on it
+func tableImpact(dbConn *sql.DB, schema, name string) (*models.ObjectImpact, error) {
+	impact := &models.ObjectImpact{
+		Type:        "table",
+		Database:    schema,
+		Name:        name,
+		ForeignKeys: []string{},
+		Views:       []string{},
+	}
+
+	var tableType string
+	err := dbConn.QueryRow(`
+		SELECT TABLE_TYPE, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, schema, name).Scan(&tableType, &impact.EstimatedRows, &impact.SizeBytes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return impact, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	impact.Exists = true
+	if tableType == "VIEW" {
+		impact.Type = "view"
+	}
+
+	impact.ForeignKeys, err = queryNames(dbConn, `
+		SELECT DISTINCT CONCAT(TABLE_SCHEMA, '.', TABLE_NAME, ' (', CONSTRAINT_NAME, ')')
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE REFERENCED_TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME = ?
+		  AND NOT (TABLE_SCHEMA = ? AND TABLE_NAME = ?)
+		ORDER BY 1`, schema, name, schema, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// View definitions refer to tables fully qualified and quoted
+	impact.Views, err = queryNames(dbConn, `
+		SELECT CONCAT(TABLE_SCHEMA, '.', TABLE_NAME)
+		FROM information_schema.VIEWS
+		WHERE VIEW_DEFINITION LIKE ?
+		  AND NOT (TABLE_SCHEMA = ? AND TABLE_NAME = ?)
+		ORDER BY 1`, "%"+escapeLike(quoteIdentifier(schema)+"."+quoteIdentifier(name))+"%", schema, name)
+	if err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// databaseImpact looks up the size of a database and what depends on it from
+// other databases
+func databaseImpact(dbConn *sql.DB, schema string) (*models.ObjectImpact, error) {
+	impact := &models.ObjectImpact{
+		Type:        "database",
+		Database:    schema,
+		ForeignKeys: []string{},
+		Views:       []string{},
+	}
+
+	var exists int
+	if err := dbConn.QueryRow("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", schema).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return impact, nil
+	}
+	impact.Exists = true
+
+	err := dbConn.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(TABLE_ROWS), 0), COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ?`, schema).Scan(&impact.Tables, &impact.EstimatedRows, &impact.SizeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	impact.ForeignKeys, err = queryNames(dbConn, `
+		SELECT DISTINCT CONCAT(TABLE_SCHEMA, '.', TABLE_NAME, ' (', CONSTRAINT_NAME, ')')
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE REFERENCED_TABLE_SCHEMA = ? AND TABLE_SCHEMA <> ?
+		ORDER BY 1`, schema, schema)
+	if err != nil {
+		return nil, err
+	}
+	impact.Views, err = queryNames(dbConn, `
+		SELECT CONCAT(TABLE_SCHEMA, '.', TABLE_NAME)
+		FROM information_schema.VIEWS
+		WHERE VIEW_DEFINITION LIKE ? AND TABLE_SCHEMA <> ?
+		ORDER BY 1`, "%"+escapeLike(quoteIdentifier(schema)+".")+"%", schema)
+	if err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// queryNames returns the single string column of a query
+func queryNames(dbConn *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := dbConn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleCheckInterval is how often idle consoles are looked for
+const consoleCheckInterval = time.Minute
+
+// openConsole is a console session holding a dedicated connection of a session
+// connection, so that the MySQL session state lasts across queries
+type openConsole struct {
+	sessionID string
+	readOnly  bool
+	conn      *pinnedConn
+
+	// mu serializes the queries of the console and its closing
+	mu     sync.Mutex
+	closed bool
+	// info is guarded by consolesMu
+	info models.Console
+}
+
+var (
+	consolesMu     sync.Mutex
+	consoleConfig  = config.Default().Consoles
+	consoles       = make(map[string]*openConsole)
+	consoleJanitor sync.Once
+)
+
+// InitConsoles sets the idle timeout and limit of console sessions and starts
+// closing idle ones
+func InitConsoles(cfg config.ConsoleConfig) {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	consoleConfig = cfg
+	consoleJanitor.Do(func() { go closeIdleConsoles() })
+}
+
+// OpenConsole opens a console session on a dedicated connection of a session
+// connection
+func OpenConsole(sessionID string, conn *SessionConnection, req models.OpenConsoleRequest) (*models.Console, error) {
+	consolesMu.Lock()
+	_, err := checkConsoleLimits(sessionID, conn.ID)
+	consolesMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newConsoleID()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := openPinnedConn(req.Database, conn.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &openConsole{
+		sessionID: sessionID,
+		readOnly:  conn.Config.ReadOnly,
+		conn:      pinned,
+		info: models.Console{
+			ID:         id,
+			Name:       strings.TrimSpace(req.Name),
+			Connection: conn.ID,
+			Database:   req.Database,
+			OpenedAt:   now,
+			LastUsedAt: now,
+		},
+	}
+
+	// Checked again under the lock, concurrent opens may have reached the limits
+	// while this connection was opened
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	count, err := checkConsoleLimits(sessionID, conn.ID)
+	if err != nil {
+		pinned.close()
+		return nil, err
+	}
+	if c.info.Name == "" {
+		c.info.Name = fmt.Sprintf("Console %d", count+1)
+	}
+	consoles[id] = c
+	return c.view(), nil
+}
+
+// checkConsoleLimits refuses another console on a session connection once it,
+// or the server as a whole, holds the most allowed, and returns the number of
+// consoles of the connection. The caller holds consolesMu.
+func checkConsoleLimits(sessionID, connectionID string) (int, error) {
+	if len(consoles) >= consoleConfig.MaxTotal {
+		return 0, fmt.Errorf("%w: at most %d consoles may be open at once", ErrInvalidInput, consoleConfig.MaxTotal)
+	}
+	count, max := 0, consoleConfig.MaxPerConnection
+	for _, c := range consoles {
+		if c.sessionID == sessionID && c.info.Connection == connectionID {
+			count++
+		}
+	}
+	if count >= max {
+		return count, fmt.Errorf("%w: a connection may hold at most %d consoles", ErrInvalidInput, max)
+	}
+	return count, nil
+}
+
+// GetConsole returns a console session of a session connection
+func GetConsole(sessionID, connectionID, id string) (*models.Console, error) {
+	c, err := findConsole(sessionID, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	return c.view(), nil
+}
+
+// ListConsoles returns the console sessions of a session connection, oldest
+// first
+func ListConsoles(sessionID, connectionID string) []models.Console {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	result := make([]models.Console, 0)
+	for _, c := range consoles {
+		if c.sessionID == sessionID && c.info.Connection == connectionID {
+			result = append(result, *c.view())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OpenedAt.Before(result[j].OpenedAt)
+	})
+	return result
+}
+
+// ExecuteInConsole runs a query in a console session and returns the result
+// with the console's state after it. dbName is the default database the query
+// was authorized for; a console that has since moved to another one refuses it.
+func ExecuteInConsole(sessionID, connectionID, id, dbName, query string, args []interface{}) (*models.QueryResponse, *models.Console, error) {
+	c, err := findConsole(sessionID, connectionID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	statements, err := parseQuery(query, args, c.readOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, nil, fmt.Errorf("%w: console %s", ErrNotFound, id)
+	}
+	if current := c.database(); current != dbName {
+		return nil, nil, fmt.Errorf("%w: the console's database changed to %s, run the query again", ErrInvalidInput, current)
+	}
+
+	c.touch(false, dbName)
+	result, err := runStatements(c.conn.conn, statements[0], query, args)
+	if err != nil {
+		result = &models.QueryResponse{
+			Error: err.Error(),
+		}
+		if connectionLost(err) {
+			c.close()
+			result.Error += " (the console session was closed)"
+			consolesMu.Lock()
+			defer consolesMu.Unlock()
+			return result, c.view(), nil
+		}
+	}
+
+	current, known := consoleDatabase(statements, dbName, err == nil)
+	if !known {
+		// Only a failed query with a USE it may not have reached gets here, so the
+		// diagnostics of successful ones are left alone
+		var name sql.NullString
+		if err := c.conn.conn.QueryRowContext(context.Background(), "SELECT DATABASE()").Scan(&name); err == nil {
+			current = name.String
+		}
+	}
+	c.touch(true, current)
+
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	return result, c.view(), nil
+}
+
+// consoleDatabase returns the default database of a console after statements
+// ran in dbName: that of the last USE. Stored procedures restore the default
+// database when they return, so only USE changes it. known is false when the
+// query failed or named its database in a way that cannot be resolved after a
+// USE, and the database must be asked for.
+func consoleDatabase(statements []sqlstmt.Statement, dbName string, succeeded bool) (string, bool) {
+	current := dbName
+	for _, stmt := range statements {
+		if stmt.Kind != "USE" {
+			continue
+		}
+		if !succeeded || len(stmt.Schemas) != 1 {
+			return dbName, false
+		}
+		current = stmt.Schemas[0]
+	}
+	return current, true
+}
+
+// CloseConsole closes a console session of a session connection, discarding its
+// MySQL session, waiting for a running query to complete
+func CloseConsole(sessionID, connectionID, id string) error {
+	c, err := findConsole(sessionID, connectionID, id)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("%w: console %s", ErrNotFound, id)
+	}
+	c.close()
+	return nil
+}
+
+// CloseConnectionConsoles closes the console sessions of a session connection,
+// e.g. when it is closed
+func CloseConnectionConsoles(sessionID, connectionID string) {
+	for _, c := range ListConsoles(sessionID, connectionID) {
+		CloseConsole(sessionID, connectionID, c.ID)
+	}
+}
+
+// findConsole returns a console session of a session connection
+func findConsole(sessionID, connectionID, id string) (*openConsole, error) {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	c, ok := consoles[id]
+	if !ok || c.sessionID != sessionID || c.info.Connection != connectionID {
+		return nil, fmt.Errorf("%w: console %s", ErrNotFound, id)
+	}
+	return c, nil
+}
+
+// closeIdleConsoles periodically closes consoles that have not been used for the
+// idle timeout
+func closeIdleConsoles() {
+	ticker := time.NewTicker(consoleCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var idle []*openConsole
+		consolesMu.Lock()
+		timeout := consoleConfig.IdleTimeout
+		for _, c := range consoles {
+			if time.Since(c.info.LastUsedAt) > timeout {
+				idle = append(idle, c)
+			}
+		}
+		consolesMu.Unlock()
+
+		for _, c := range idle {
+			// A console running a query is not idle
+			if !c.mu.TryLock() {
+				continue
+			}
+			if !c.closed {
+				c.close()
+				log.Printf("Closed console %s after %s idle", c.info.ID, timeout)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// database returns the console's current default database. The caller holds
+// c.mu.
+func (c *openConsole) database() string {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	return c.info.Database
+}
+
+// touch records the use of the console and its current database; statement
+// counts one query. The caller holds c.mu.
+func (c *openConsole) touch(statement bool, dbName string) {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	c.info.LastUsedAt = time.Now()
+	c.info.Database = dbName
+	if statement {
+		c.info.Statements++
+	}
+}
+
+// close closes the console's connection. The caller holds c.mu.
+func (c *openConsole) close() {
+	c.conn.close()
+	c.closed = true
+
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	delete(consoles, c.info.ID)
+}
+
+// view copies the console's information. The caller holds consolesMu.
+func (c *openConsole) view() *models.Console {
+	info := c.info
+	info.ExpiresAt = info.LastUsedAt.Add(consoleConfig.IdleTimeout)
+	return &info
+}
+
+// newConsoleID returns a random console identifier
+func newConsoleID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"testing"
+	"time"
+)
+
+func TestConsoleDatabase(t *testing.T) {
+	tests := []struct {
+		query     string
+		succeeded bool
+		want      string
+		wantKnown bool
+	}{
+		{"SELECT * FROM orders", true, "shop", true},
+		{"USE sales", true, "sales", true},
+		{"USE `my db`; SELECT 1", true, "my db", true},
+		{"USE a; USE b", true, "b", true},
+		{"CALL sales.refund(1)", true, "shop", true},
+		{"SHOW WARNINGS", true, "shop", true},
+		// A failed batch may have stopped before its USE
+		{"USE sales; SELECT * FROM missing", false, "shop", false},
+		{"SELECT * FROM missing", false, "shop", true},
+	}
+
+	for _, tt := range tests {
+		got, known := consoleDatabase(sqlstmt.Parse(tt.query), "shop", tt.succeeded)
+		if got != tt.want || known != tt.wantKnown {
+			t.Errorf("consoleDatabase(%q, %v) = %q, %v; want %q, %v", tt.query, tt.succeeded, got, known, tt.want, tt.wantKnown)
+		}
+	}
+}
+
+func TestCheckConsoleLimits(t *testing.T) {
+	consolesMu.Lock()
+	defer consolesMu.Unlock()
+
+	savedConfig, savedConsoles := consoleConfig, consoles
+	consoleConfig = config.ConsoleConfig{IdleTimeout: time.Minute, MaxPerConnection: 2, MaxTotal: 3}
+	consoles = map[string]*openConsole{
+		"a": {sessionID: "s1", info: models.Console{ID: "a", Connection: "primary"}},
+	}
+	defer func() {
+		consoleConfig, consoles = savedConfig, savedConsoles
+	}()
+
+	if count, err := checkConsoleLimits("s1", "primary"); err != nil || count != 1 {
+		t.Errorf("checkConsoleLimits() = %d, %v; want 1, nil", count, err)
+	}
+
+	consoles["b"] = &openConsole{sessionID: "s1", info: models.Console{ID: "b", Connection: "primary"}}
+	if _, err := checkConsoleLimits("s1", "primary"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("full connection: error = %v, want ErrInvalidInput", err)
+	}
+
+	consoles["c"] = &openConsole{sessionID: "s2", info: models.Console{ID: "c", Connection: "primary"}}
+	if _, err := checkConsoleLimits("s3", "primary"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("full server: error = %v, want ErrInvalidInput", err)
+	}
+}
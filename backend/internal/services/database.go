@@ -8,8 +8,8 @@ import (
 )
 
 // GetDatabases returns a list of all databases
-func GetDatabases(host string, port int, user, password, defaultDB string) ([]models.Database, error) {
-	dbConn, err := database.ConnectToDatabase(defaultDB, host, port, user, password)
+func GetDatabases(cfg database.Config) ([]models.Database, error) {
+	dbConn, err := database.ConnectToDatabase(cfg.Database, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -34,12 +34,19 @@ func GetDatabases(host string, port int, user, password, defaultDB string) ([]mo
 		databases = append(databases, models.Database{Name: name})
 	}
 
+	// Annotate what the account may do; listing still works if grants are unreadable
+	if privs, err := loadEffectivePrivileges(dbConn); err == nil {
+		for i := range databases {
+			databases[i].Privileges = privs.forDatabase(databases[i].Name).objectPrivileges()
+		}
+	}
+
 	return databases, nil
 }
 
 // GetTables returns a list of tables in a database
-func GetTables(dbName string, host string, port int, user, password string) ([]models.Table, error) {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+func GetTables(dbName string, cfg database.Config) ([]models.Table, error) {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +74,19 @@ func GetTables(dbName string, host string, port int, user, password string) ([]m
 		tables = append(tables, table)
 	}
 
+	// Annotate what the account may do; listing still works if grants are unreadable
+	if privs, err := loadEffectivePrivileges(dbConn); err == nil {
+		for i := range tables {
+			tables[i].Privileges = privs.forTable(dbName, tables[i].Name).objectPrivileges()
+		}
+	}
+
 	return tables, nil
 }
 
 // GetTableStructure returns the structure of a table
-func GetTableStructure(dbName, tableName string, host string, port int, user, password string) (*models.TableStructure, error) {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+func GetTableStructure(dbName, tableName string, cfg database.Config) (*models.TableStructure, error) {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -153,8 +167,8 @@ func GetTableStructure(dbName, tableName string, host string, port int, user, pa
 }
 
 // GetTableData returns paginated table data
-func GetTableData(dbName, tableName string, page, pageSize int, sortBy, sortDir string, host string, port int, user, password string) (*models.TableDataResponse, error) {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+func GetTableData(dbName, tableName string, page, pageSize int, sortBy, sortDir string, cfg database.Config) (*models.TableDataResponse, error) {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
 	if err != nil {
 		return nil, err
 	}
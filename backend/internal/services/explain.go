@@ -0,0 +1,428 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Plan warning types reported by ExplainQuery
+const (
+	WarningFullTableScan = "full_table_scan"
+	WarningFullIndexScan = "full_index_scan"
+	WarningFilesort      = "filesort"
+	WarningTemporary     = "temporary_table"
+)
+
+// ExplainQuery runs EXPLAIN FORMAT=JSON (or EXPLAIN ANALYZE when requested and
+// supported by the server) and returns the parsed plan tree
+func ExplainQuery(dbName, query string, analyze bool, cfg database.Config) (*models.ExplainResponse, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	upperQuery := strings.ToUpper(query)
+
+	if !isExplainable(upperQuery) {
+		return nil, errors.New("only SELECT, TABLE, WITH, INSERT, REPLACE, UPDATE and DELETE statements can be explained")
+	}
+	// EXPLAIN ANALYZE executes the statement, so never allow it for writes
+	if analyze && sqlstmt.Classify(query).Category != sqlstmt.Read {
+		return nil, errors.New("EXPLAIN ANALYZE is only allowed for read-only statements")
+	}
+
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	var version string
+	if err := dbConn.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return nil, err
+	}
+
+	if analyze {
+		if !supportsExplainAnalyze(version) {
+			return nil, fmt.Errorf("EXPLAIN ANALYZE requires MySQL 8.0.18 or later (server is %s)", version)
+		}
+		return explainAnalyze(dbConn, query, version)
+	}
+
+	var raw string
+	if err := dbConn.QueryRow("EXPLAIN FORMAT=JSON " + query).Scan(&raw); err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse EXPLAIN output: %w", err)
+	}
+
+	block, ok := doc["query_block"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("unexpected EXPLAIN output: missing query_block")
+	}
+
+	plan := parseJSONPlanNode("query_block", block)
+	return &models.ExplainResponse{
+		Format:        "json",
+		ServerVersion: version,
+		Plan:          plan,
+		Warnings:      collectPlanWarnings(plan),
+		Raw:           raw,
+	}, nil
+}
+
+// isExplainable reports whether the statement can be passed to EXPLAIN
+func isExplainable(upperQuery string) bool {
+	for _, prefix := range []string{"SELECT", "TABLE", "WITH", "INSERT", "REPLACE", "UPDATE", "DELETE", "("} {
+		if strings.HasPrefix(upperQuery, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsExplainAnalyze reports whether the server version supports EXPLAIN ANALYZE
+func supportsExplainAnalyze(version string) bool {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return false
+	}
+	major, minor, patch := parseServerVersion(version)
+	if major != 8 {
+		return major > 8
+	}
+	if minor != 0 {
+		return minor > 0
+	}
+	return patch >= 18
+}
+
+// parseServerVersion extracts the numeric major, minor and patch parts of a VERSION() string
+func parseServerVersion(version string) (int, int, int) {
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.SplitN(version, ".", 3)
+	nums := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		nums[i], _ = strconv.Atoi(parts[i])
+	}
+	return nums[0], nums[1], nums[2]
+}
+
+// planChildKeys lists the JSON keys holding nested plan nodes, in display order
+var planChildKeys = []string{
+	"union_result",
+	"windowing",
+	"buffer_result",
+	"ordering_operation",
+	"grouping_operation",
+	"duplicates_removal",
+	"nested_loop",
+	"table",
+	"query_specifications",
+	"materialized_from_subquery",
+	"attached_subqueries",
+	"optimized_away_subqueries",
+	"select_list_subqueries",
+	"having_subqueries",
+	"order_by_subqueries",
+	"group_by_subqueries",
+	"update_value_subqueries",
+}
+
+// parseJSONPlanNode converts one object of EXPLAIN FORMAT=JSON output into a plan node
+func parseJSONPlanNode(operation string, obj map[string]interface{}) *models.PlanNode {
+	node := &models.PlanNode{Operation: operation}
+
+	if id, ok := toFloat(obj["select_id"]); ok {
+		node.SelectID = int(id)
+	}
+	node.Table, _ = obj["table_name"].(string)
+	node.AccessType, _ = obj["access_type"].(string)
+	node.PossibleKeys = toStrings(obj["possible_keys"])
+	node.Key, _ = obj["key"].(string)
+	node.UsedKeyParts = toStrings(obj["used_key_parts"])
+	node.KeyLength, _ = obj["key_length"].(string)
+	node.Ref = toStrings(obj["ref"])
+	node.Condition, _ = obj["attached_condition"].(string)
+	node.Message, _ = obj["message"].(string)
+	node.UsingIndex, _ = obj["using_index"].(bool)
+	node.UsingFilesort, _ = obj["using_filesort"].(bool)
+	node.UsingTemporary, _ = obj["using_temporary_table"].(bool)
+	node.Dependent, _ = obj["dependent"].(bool)
+
+	if rows, ok := toFloat(obj["rows_examined_per_scan"]); ok {
+		node.Rows = &rows
+	}
+	if rows, ok := toFloat(obj["rows_produced_per_join"]); ok {
+		node.RowsProduced = &rows
+	}
+	if filtered, ok := toFloat(obj["filtered"]); ok {
+		node.Filtered = &filtered
+	}
+
+	if costInfo, ok := obj["cost_info"].(map[string]interface{}); ok {
+		cost := &models.PlanCost{}
+		cost.Query, _ = toFloat(costInfo["query_cost"])
+		cost.Read, _ = toFloat(costInfo["read_cost"])
+		cost.Eval, _ = toFloat(costInfo["eval_cost"])
+		cost.Prefix, _ = toFloat(costInfo["prefix_cost"])
+		node.Cost = cost
+	}
+
+	for _, key := range planChildKeys {
+		value, ok := obj[key]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "materialized_from_subquery" {
+				child := parseJSONPlanNode(key, v)
+				if qb, ok := v["query_block"].(map[string]interface{}); ok {
+					child.Children = append(child.Children, parseJSONPlanNode("query_block", qb))
+				}
+				node.Children = append(node.Children, child)
+				continue
+			}
+			node.Children = append(node.Children, parseJSONPlanNode(key, v))
+		case []interface{}:
+			group := node
+			if key == "nested_loop" {
+				group = &models.PlanNode{Operation: key}
+				node.Children = append(node.Children, group)
+			}
+			for _, item := range v {
+				entry, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if table, ok := entry["table"].(map[string]interface{}); ok {
+					group.Children = append(group.Children, parseJSONPlanNode("table", table))
+				} else if qb, ok := entry["query_block"].(map[string]interface{}); ok {
+					child := parseJSONPlanNode("query_block", qb)
+					child.Dependent, _ = entry["dependent"].(bool)
+					group.Children = append(group.Children, child)
+				}
+			}
+		}
+	}
+
+	return node
+}
+
+// explainNumber matches a number in EXPLAIN ANALYZE output, such as 0.35 or 1e+06
+const explainNumber = `[0-9]+(?:\.[0-9]+)?(?:e[+-]?[0-9]+)?`
+
+// explainAnalyzeLine matches a node line of EXPLAIN ANALYZE tree output
+var explainAnalyzeLine = regexp.MustCompile(`^(\s*)-> (.*?)(?:\s+\(cost=(` + explainNumber + `)(?:\.\.(` + explainNumber + `))? rows=(` + explainNumber + `)\))?(?:\s+\(actual time=(` + explainNumber + `)\.\.(` + explainNumber + `) rows=(` + explainNumber + `) loops=(\d+)\))?(\s+\(never executed\))?\s*$`)
+
+// explainAnalyzeNode matches the start of a node line
+var explainAnalyzeNode = regexp.MustCompile(`^\s*-> `)
+
+// explainAnalyzeTable extracts the table name from an EXPLAIN ANALYZE operation
+var explainAnalyzeTable = regexp.MustCompile(`(?i)\b(?:scan|lookup|search) on (\S+?)(?:\s+using\s+(\S+?))?(?:\s|\(|$)`)
+
+// explainAnalyze runs EXPLAIN ANALYZE and parses its tree output
+func explainAnalyze(dbConn *sql.DB, query, version string) (*models.ExplainResponse, error) {
+	var raw string
+	if err := dbConn.QueryRow("EXPLAIN ANALYZE " + query).Scan(&raw); err != nil {
+		return nil, err
+	}
+
+	plan, err := parseAnalyzeTree(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExplainResponse{
+		Format:        "tree",
+		Analyze:       true,
+		ServerVersion: version,
+		Plan:          plan,
+		Warnings:      collectPlanWarnings(plan),
+		Raw:           raw,
+	}, nil
+}
+
+// parseAnalyzeTree converts EXPLAIN ANALYZE tree output into a plan node tree
+func parseAnalyzeTree(raw string) (*models.PlanNode, error) {
+	type frame struct {
+		indent int
+		node   *models.PlanNode
+	}
+
+	root := &models.PlanNode{Operation: "query"}
+	stack := []frame{{indent: -1, node: root}}
+
+	// A description may span lines, e.g. with a newline in a string literal; the
+	// costs follow on its last line
+	var lines []string
+	for _, line := range strings.Split(raw, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+		case explainAnalyzeNode.MatchString(line) || len(lines) == 0:
+			lines = append(lines, line)
+		default:
+			lines[len(lines)-1] += " " + strings.TrimSpace(line)
+		}
+	}
+
+	for _, line := range lines {
+		m := explainAnalyzeLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		node := &models.PlanNode{Operation: m[2]}
+		if m[3] != "" {
+			cost := &models.PlanCost{}
+			cost.Total, _ = strconv.ParseFloat(m[3], 64)
+			if m[4] != "" {
+				cost.Total, _ = strconv.ParseFloat(m[4], 64)
+			}
+			node.Cost = cost
+			if rows, err := strconv.ParseFloat(m[5], 64); err == nil {
+				node.Rows = &rows
+			}
+		}
+		if m[6] != "" {
+			actual := &models.PlanActual{Executed: true}
+			actual.FirstRowMs, _ = strconv.ParseFloat(m[6], 64)
+			actual.AllRowsMs, _ = strconv.ParseFloat(m[7], 64)
+			actual.Rows, _ = strconv.ParseFloat(m[8], 64)
+			actual.Loops, _ = strconv.ParseInt(m[9], 10, 64)
+			node.Actual = actual
+		} else if m[10] != "" {
+			node.Actual = &models.PlanActual{}
+		}
+
+		if t := explainAnalyzeTable.FindStringSubmatch(node.Operation); t != nil {
+			node.Table = t[1]
+			node.Key = t[2]
+		}
+		classifyAnalyzeNode(node)
+
+		indent := len(m[1])
+		for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, frame{indent: indent, node: node})
+	}
+
+	if len(root.Children) == 0 {
+		return nil, errors.New("unexpected EXPLAIN ANALYZE output")
+	}
+	if len(root.Children) == 1 {
+		return root.Children[0], nil
+	}
+	return root, nil
+}
+
+// classifyAnalyzeNode derives access type and flags from an EXPLAIN ANALYZE operation
+func classifyAnalyzeNode(node *models.PlanNode) {
+	op := strings.ToLower(node.Operation)
+	switch {
+	case strings.HasPrefix(op, "table scan on"):
+		node.AccessType = "ALL"
+	case strings.HasPrefix(op, "index scan on"), strings.HasPrefix(op, "covering index scan on"):
+		node.AccessType = "index"
+	case strings.HasPrefix(op, "index range scan on"), strings.HasPrefix(op, "covering index range scan on"):
+		node.AccessType = "range"
+	case strings.HasPrefix(op, "single-row index lookup on"):
+		node.AccessType = "eq_ref"
+	case strings.Contains(op, "index lookup on"):
+		node.AccessType = "ref"
+	}
+	if strings.HasPrefix(op, "sort") {
+		node.UsingFilesort = true
+	}
+	if strings.Contains(op, "temporary") || strings.HasPrefix(op, "materialize") {
+		node.UsingTemporary = true
+	}
+	if strings.HasPrefix(op, "covering") {
+		node.UsingIndex = true
+	}
+}
+
+// collectPlanWarnings walks the plan tree and flags full scans, filesorts and temporary tables
+func collectPlanWarnings(plan *models.PlanNode) []models.PlanWarning {
+	warnings := []models.PlanWarning{}
+
+	var walk func(node *models.PlanNode)
+	walk = func(node *models.PlanNode) {
+		if node == nil {
+			return
+		}
+		switch node.AccessType {
+		case "ALL":
+			warnings = append(warnings, models.PlanWarning{
+				Type:    WarningFullTableScan,
+				Table:   node.Table,
+				Message: fmt.Sprintf("Full table scan on %s", node.Table),
+			})
+		case "index":
+			warnings = append(warnings, models.PlanWarning{
+				Type:    WarningFullIndexScan,
+				Table:   node.Table,
+				Message: fmt.Sprintf("Full index scan on %s", node.Table),
+			})
+		}
+		if node.UsingFilesort {
+			warnings = append(warnings, models.PlanWarning{
+				Type:    WarningFilesort,
+				Table:   node.Table,
+				Message: "Using filesort",
+			})
+		}
+		if node.UsingTemporary {
+			warnings = append(warnings, models.PlanWarning{
+				Type:    WarningTemporary,
+				Table:   node.Table,
+				Message: "Using temporary table",
+			})
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(plan)
+
+	return warnings
+}
+
+// toFloat converts a JSON number or numeric string into a float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toStrings converts a JSON array of strings into a string slice
+func toStrings(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
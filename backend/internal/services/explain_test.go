@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"mysql-admin-tool/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// planLines renders a plan tree one node per line, indented by depth, with the
+// fields read from EXPLAIN ANALYZE
+func planLines(node *models.PlanNode, depth int) []string {
+	line := strings.Repeat("  ", depth) + node.Operation
+	if node.Table != "" {
+		line += fmt.Sprintf(" table=%s", node.Table)
+	}
+	if node.Key != "" {
+		line += fmt.Sprintf(" key=%s", node.Key)
+	}
+	if node.AccessType != "" {
+		line += fmt.Sprintf(" access=%s", node.AccessType)
+	}
+	if node.Cost != nil {
+		line += fmt.Sprintf(" cost=%g rows=%g", node.Cost.Total, *node.Rows)
+	}
+	if a := node.Actual; a != nil {
+		if a.Executed {
+			line += fmt.Sprintf(" actual=%g..%g rows=%g loops=%d", a.FirstRowMs, a.AllRowsMs, a.Rows, a.Loops)
+		} else {
+			line += " never executed"
+		}
+	}
+	if node.UsingFilesort {
+		line += " filesort"
+	}
+	if node.UsingTemporary {
+		line += " temporary"
+	}
+	if node.UsingIndex {
+		line += " covering"
+	}
+
+	lines := []string{line}
+	for _, child := range node.Children {
+		lines = append(lines, planLines(child, depth+1)...)
+	}
+	return lines
+}
+
+func TestParseAnalyzeTree(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "hash join",
+			raw: `-> Inner hash join (t2.c2 = t1.c1)  (cost=4.70 rows=6) (actual time=0.032..0.035 rows=6 loops=1)
+    -> Table scan on t2  (cost=0.06 rows=6) (actual time=0.003..0.005 rows=6 loops=1)
+    -> Hash
+        -> Table scan on t1  (cost=0.85 rows=6) (actual time=0.018..0.022 rows=6 loops=1)
+`,
+			want: []string{
+				"Inner hash join (t2.c2 = t1.c1) cost=4.7 rows=6 actual=0.032..0.035 rows=6 loops=1",
+				"  Table scan on t2 table=t2 access=ALL cost=0.06 rows=6 actual=0.003..0.005 rows=6 loops=1",
+				"  Hash",
+				"    Table scan on t1 table=t1 access=ALL cost=0.85 rows=6 actual=0.018..0.022 rows=6 loops=1",
+			},
+		},
+		{
+			name: "nested loop with index lookups",
+			raw: `-> Nested loop inner join  (cost=4.95 rows=9) (actual time=0.153..0.200 rows=9 loops=1)
+    -> Filter: (t1.c1 is not null)  (cost=1.15 rows=9) (actual time=0.101..0.119 rows=9 loops=1)
+        -> Table scan on t1  (cost=1.15 rows=9) (actual time=0.097..0.113 rows=9 loops=1)
+    -> Single-row index lookup on t2 using PRIMARY (c1=t1.c1)  (cost=0.31 rows=1) (actual time=0.008..0.008 rows=1 loops=9)
+`,
+			want: []string{
+				"Nested loop inner join cost=4.95 rows=9 actual=0.153..0.2 rows=9 loops=1",
+				"  Filter: (t1.c1 is not null) cost=1.15 rows=9 actual=0.101..0.119 rows=9 loops=1",
+				"    Table scan on t1 table=t1 access=ALL cost=1.15 rows=9 actual=0.097..0.113 rows=9 loops=1",
+				"  Single-row index lookup on t2 using PRIMARY (c1=t1.c1) table=t2 key=PRIMARY access=eq_ref cost=0.31 rows=1 actual=0.008..0.008 rows=1 loops=9",
+			},
+		},
+		{
+			name: "sort, aggregate and covering range scan",
+			raw: `-> Sort: cnt DESC  (actual time=0.412..0.415 rows=12 loops=1)
+    -> Table scan on <temporary>  (actual time=0.380..0.383 rows=12 loops=1)
+        -> Aggregate using temporary table  (actual time=0.378..0.378 rows=12 loops=1)
+            -> Covering index range scan on orders using idx_created over ('2024-01-01' <= created_at)  (cost=41.26 rows=203) (actual time=0.025..0.190 rows=203 loops=1)
+`,
+			want: []string{
+				"Sort: cnt DESC actual=0.412..0.415 rows=12 loops=1 filesort",
+				"  Table scan on <temporary> table=<temporary> access=ALL actual=0.38..0.383 rows=12 loops=1 temporary",
+				"    Aggregate using temporary table actual=0.378..0.378 rows=12 loops=1 temporary",
+				"      Covering index range scan on orders using idx_created over ('2024-01-01' <= created_at) table=orders key=idx_created access=range cost=41.26 rows=203 actual=0.025..0.19 rows=203 loops=1 covering",
+			},
+		},
+		{
+			name: "cost ranges and branches that never ran",
+			raw: `-> Nested loop inner join  (cost=0.70..0.70 rows=0) (actual time=0.014..0.014 rows=0 loops=1)
+    -> Filter: (c.active = 1)  (cost=0.35..0.35 rows=1) (actual time=0.012..0.012 rows=0 loops=1)
+        -> Table scan on c  (cost=0.35..0.35 rows=1) (actual time=0.010..0.010 rows=1 loops=1)
+    -> Index lookup on o using fk_customer (customer_id=c.id)  (cost=0.35..0.35 rows=1) (never executed)
+`,
+			want: []string{
+				"Nested loop inner join cost=0.7 rows=0 actual=0.014..0.014 rows=0 loops=1",
+				"  Filter: (c.active = 1) cost=0.35 rows=1 actual=0.012..0.012 rows=0 loops=1",
+				"    Table scan on c table=c access=ALL cost=0.35 rows=1 actual=0.01..0.01 rows=1 loops=1",
+				"  Index lookup on o using fk_customer (customer_id=c.id) table=o key=fk_customer access=ref cost=0.35 rows=1 never executed",
+			},
+		},
+		{
+			name: "descriptions continued on the next line",
+			raw: `-> Filter: (t.note = 'first
+second')  (cost=1.25 rows=1) (actual time=0.020..0.020 rows=0 loops=1)
+    -> Table scan on t  (cost=1.25 rows=10) (actual time=0.015..0.018 rows=10 loops=1)
+`,
+			want: []string{
+				"Filter: (t.note = 'first second') cost=1.25 rows=1 actual=0.02..0.02 rows=0 loops=1",
+				"  Table scan on t table=t access=ALL cost=1.25 rows=10 actual=0.015..0.018 rows=10 loops=1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := parseAnalyzeTree(tt.raw)
+			if err != nil {
+				t.Fatalf("parseAnalyzeTree: %v", err)
+			}
+			if got := planLines(plan, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseAnalyzeTreeInvalid(t *testing.T) {
+	for _, raw := range []string{"", "EXPLAIN output"} {
+		if _, err := parseAnalyzeTree(raw); err == nil {
+			t.Errorf("parseAnalyzeTree(%q) error = nil, want an error", raw)
+		}
+	}
+}
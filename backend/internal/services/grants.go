@@ -0,0 +1,168 @@
+package services
+
+import (
+	"mysql-admin-tool/internal/models"
+	"strings"
+)
+
+// ParseGrant parses a single statement returned by SHOW GRANTS
+func ParseGrant(statement string) (models.Grant, bool) {
+	grant := models.Grant{Statement: statement}
+
+	s := strings.TrimSpace(statement)
+	if len(s) < 6 || !strings.EqualFold(s[:6], "GRANT ") {
+		return grant, false
+	}
+	s = strings.TrimSpace(s[6:])
+
+	onIdx := indexTopLevel(s, " ON ")
+	toIdx := indexTopLevel(s, " TO ")
+	if toIdx < 0 {
+		return grant, false
+	}
+	grant.WithGrantOption = strings.Contains(strings.ToUpper(s[toIdx:]), "WITH GRANT OPTION") ||
+		strings.Contains(strings.ToUpper(s[toIdx:]), "WITH ADMIN OPTION")
+
+	// Role grants have no ON clause: GRANT `role`@`%` TO `user`@`%`
+	if onIdx < 0 || onIdx > toIdx {
+		grant.Level = models.GrantLevelRole
+		for _, role := range splitTopLevel(s[:toIdx], ',') {
+			grant.Roles = append(grant.Roles, unquoteAccount(role))
+		}
+		return grant, true
+	}
+
+	for _, item := range splitTopLevel(s[:onIdx], ',') {
+		priv := models.GrantPrivilege{Name: strings.ToUpper(strings.TrimSpace(item))}
+		if open := strings.Index(item, "("); open >= 0 {
+			priv.Name = strings.ToUpper(strings.TrimSpace(item[:open]))
+			cols := strings.TrimSuffix(strings.TrimSpace(item[open+1:]), ")")
+			for _, col := range splitTopLevel(cols, ',') {
+				priv.Columns = append(priv.Columns, unquoteIdentifier(col))
+			}
+		}
+		grant.Privileges = append(grant.Privileges, priv)
+	}
+
+	object := strings.TrimSpace(s[onIdx+4 : toIdx])
+	upperObject := strings.ToUpper(object)
+	for _, routineType := range []string{"PROCEDURE ", "FUNCTION "} {
+		if strings.HasPrefix(upperObject, routineType) {
+			grant.RoutineType = strings.TrimSpace(routineType)
+			object = strings.TrimSpace(object[len(routineType):])
+		}
+	}
+
+	if len(grant.Privileges) == 1 && grant.Privileges[0].Name == "PROXY" {
+		grant.Level = models.GrantLevelProxy
+		grant.Roles = []string{unquoteAccount(object)}
+		return grant, true
+	}
+
+	parts := splitTopLevel(object, '.')
+	if len(parts) != 2 {
+		return grant, false
+	}
+	grant.Database = unquoteIdentifier(parts[0])
+	grant.Table = unquoteIdentifier(parts[1])
+
+	switch {
+	case grant.RoutineType != "":
+		grant.Level = models.GrantLevelRoutine
+	case parts[0] == "*" && parts[1] == "*":
+		grant.Level = models.GrantLevelGlobal
+		grant.Database, grant.Table = "", ""
+	case parts[1] == "*":
+		grant.Level = models.GrantLevelDatabase
+		grant.Table = ""
+	default:
+		grant.Level = models.GrantLevelTable
+		allColumns := true
+		for _, priv := range grant.Privileges {
+			if len(priv.Columns) == 0 {
+				allColumns = false
+			}
+		}
+		if allColumns {
+			grant.Level = models.GrantLevelColumn
+		}
+	}
+
+	return grant, true
+}
+
+// indexTopLevel returns the index of sep in s ignoring quoted and parenthesized
+// sections, matching case-insensitively
+func indexTopLevel(s, sep string) int {
+	upper := strings.ToUpper(s)
+	sep = strings.ToUpper(sep)
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(upper[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s on sep ignoring quoted and parenthesized sections
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// unquoteIdentifier removes backtick or quote delimiters from an identifier
+func unquoteIdentifier(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		switch q := s[0]; q {
+		case '`', '\'', '"':
+			if s[len(s)-1] == q {
+				return strings.ReplaceAll(s[1:len(s)-1], string([]byte{q, q}), string(q))
+			}
+		}
+	}
+	return s
+}
+
+// unquoteAccount normalizes an account name such as `user`@`host` to user@host
+func unquoteAccount(s string) string {
+	parts := splitTopLevel(strings.TrimSpace(s), '@')
+	if len(parts) != 2 {
+		return unquoteIdentifier(s)
+	}
+	return unquoteIdentifier(parts[0]) + "@" + unquoteIdentifier(parts[1])
+}
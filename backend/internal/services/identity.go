@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authentication methods of a session
+const (
+	AuthMethodMySQL = "mysql"
+	AuthMethodLocal = "local"
+	AuthMethodOIDC  = "oidc"
+)
+
+var (
+	// ErrInvalidCredentials is returned when an application login fails
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrForbidden is returned when the application user may not perform an action
+	ErrForbidden = errors.New("forbidden")
+)
+
+// AppUser is an authenticated application account. Issuer and Subject identify
+// OpenID Connect users, whose usernames come from a claim they may control.
+type AppUser struct {
+	Username string
+	Method   string
+	Roles    []string
+	Issuer   string
+	Subject  string
+}
+
+var (
+	identityMu   sync.RWMutex
+	identity     = config.Default().Identity
+	localUsers   = make(map[string]config.LocalUserConfig)
+	roleProfiles = make(map[string][]string)
+	roleAccess   = make(map[string]AccessLevel)
+
+	// dummyHash is compared against for unknown users so that they take as long
+	// as wrong passwords
+	dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+)
+
+// InitIdentity sets the application users, roles and OpenID Connect provider
+func InitIdentity(cfg config.IdentityConfig) {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+
+	identity = cfg
+	localUsers = make(map[string]config.LocalUserConfig, len(cfg.Users))
+	for _, user := range cfg.Users {
+		localUsers[user.Username] = user
+	}
+	roleProfiles = make(map[string][]string, len(cfg.Roles))
+	roleAccess = make(map[string]AccessLevel, len(cfg.Roles))
+	for _, role := range cfg.Roles {
+		roleProfiles[role.Name] = role.Profiles
+		roleAccess[role.Name] = parseAccessLevel(role.Access)
+	}
+
+	initOIDC(cfg.OIDC)
+}
+
+// MySQLLoginAllowed reports whether users may log in with MySQL credentials
+func MySQLLoginAllowed() bool {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	return identity.AllowMySQLLogin
+}
+
+// StoredPasswordLoginAllowed reports whether MySQL logins may use the password
+// stored in a profile. This must be enabled with allow_stored_password_login, and
+// once application accounts are configured, stored passwords are only used for
+// the profiles granted to their roles.
+func StoredPasswordLoginAllowed() bool {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	return identity.AllowStoredPasswordLogin && !appAccountsConfigured()
+}
+
+// appAccountsConfigured reports whether local users, roles or OpenID Connect
+// are configured. The caller holds identityMu.
+func appAccountsConfigured() bool {
+	return len(localUsers) > 0 || len(identity.Roles) > 0 || identity.OIDC.Enabled
+}
+
+// GetAuthMethods returns the login methods offered by the server
+func GetAuthMethods() models.AuthMethods {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	return models.AuthMethods{
+		MySQL: identity.AllowMySQLLogin,
+		Local: len(localUsers) > 0,
+		OIDC:  identity.OIDC.Enabled,
+	}
+}
+
+// HashPassword returns the bcrypt hash of a password for the config file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// AuthenticateLocalUser checks the password of a local application user
+func AuthenticateLocalUser(username, password string) (*AppUser, error) {
+	identityMu.RLock()
+	user, ok := localUsers[username]
+	identityMu.RUnlock()
+
+	hash := dummyHash
+	if ok {
+		hash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &AppUser{
+		Username: user.Username,
+		Method:   AuthMethodLocal,
+		Roles:    user.Roles,
+	}, nil
+}
+
+// AllowedProfiles returns the IDs of the profiles the user's roles may connect with
+func (u *AppUser) AllowedProfiles() []string {
+	identityMu.RLock()
+	var refs []string
+	for _, role := range u.Roles {
+		refs = append(refs, roleProfiles[role]...)
+	}
+	identityMu.RUnlock()
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, ref := range refs {
+		if id, ok := findProfileID(ref); ok && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CanUseProfile reports whether the user's roles grant a profile, by ID or name
+func (u *AppUser) CanUseProfile(ref string) (string, bool) {
+	id, ok := findProfileID(ref)
+	if !ok {
+		return "", false
+	}
+	for _, allowed := range u.AllowedProfiles() {
+		if allowed == id {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// ResolveAppLogin returns the connection settings of a profile granted to the
+// user, using the profile's stored credentials. Without a profile the first
+// granted one is used.
+func ResolveAppLogin(user *AppUser, profile string) (database.Config, string, error) {
+	var id string
+	if profile == "" {
+		allowed := user.AllowedProfiles()
+		if len(allowed) == 0 {
+			return database.Config{}, "", fmt.Errorf("%w: no connection profiles are granted to %s", ErrForbidden, user.Username)
+		}
+		id = allowed[0]
+	} else {
+		var ok bool
+		if id, ok = user.CanUseProfile(profile); !ok {
+			return database.Config{}, "", fmt.Errorf("%w: profile %s is not granted to %s", ErrForbidden, profile, user.Username)
+		}
+	}
+
+	config, storedPassword, err := ResolveLogin(models.LoginRequest{Profile: id})
+	if err != nil {
+		return database.Config{}, "", err
+	}
+	if !storedPassword {
+		return database.Config{}, "", fmt.Errorf("%w: profile %s stores no password", ErrInvalidInput, id)
+	}
+	return config, id, nil
+}
+
+// CurrentUser returns the identity of a session
+func CurrentUser(session *Session) models.CurrentUser {
+	primary, _ := session.Connection(PrimaryConnectionID)
+
+	if session.User == nil {
+		result := models.CurrentUser{
+			Method:   AuthMethodMySQL,
+			Roles:    []string{},
+			Access:   session.Access().String(),
+			Profiles: []models.ProfileSummary{},
+		}
+		if primary != nil {
+			result.Username = primary.Config.User
+		}
+		return result
+	}
+
+	allowed := make(map[string]bool)
+	for _, id := range session.User.AllowedProfiles() {
+		allowed[id] = true
+	}
+	profiles := []models.ProfileSummary{}
+	for _, p := range ListProfileSummaries() {
+		if allowed[p.ID] {
+			profiles = append(profiles, p)
+		}
+	}
+
+	roles := session.User.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return models.CurrentUser{
+		Username: session.User.Username,
+		Method:   session.User.Method,
+		Roles:    roles,
+		Access:   session.Access().String(),
+		Profiles: profiles,
+	}
+}
+
+// appUserFromClaims restores the application user of a token
+func appUserFromClaims(claims *Claims) *AppUser {
+	if claims.AppUser == "" {
+		return nil
+	}
+	return &AppUser{
+		Username: claims.AppUser,
+		Method:   claims.AuthMethod,
+		Roles:    claims.AppRoles,
+		Issuer:   claims.AppIssuer,
+		Subject:  claims.AppSubject,
+	}
+}
+
+// dedupeRoles returns the role names in order without duplicates
+func dedupeRoles(roles []string) []string {
+	seen := make(map[string]bool, len(roles))
+	result := []string{}
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role != "" && !seen[role] {
+			seen[role] = true
+			result = append(result, role)
+		}
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooManyAttempts is returned when a login is refused because of earlier failures
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LoginBlockedError reports how long a client has to wait before the next login
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap makes the error match ErrTooManyAttempts
+func (e *LoginBlockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// loginAttempts tracks the failed logins of one key
+type loginAttempts struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	locked       bool
+}
+
+var (
+	loginLimitMu sync.Mutex
+	loginLimit   = config.Default().RateLimit.Login
+	loginState   = make(map[string]*loginAttempts)
+)
+
+// InitLoginLimiter sets the brute-force protection limits for logins
+func InitLoginLimiter(cfg config.LoginLimitConfig) {
+	loginLimitMu.Lock()
+	defer loginLimitMu.Unlock()
+
+	loginLimit = cfg
+}
+
+// LoginAccount returns the account key of a MySQL login, e.g. "root@db:3306" or
+// "root@unix:/run/mysqld/mysqld.sock". MySQL user names are case-sensitive, so
+// only the host is lowercased.
+func LoginAccount(cfg database.Config) string {
+	if cfg.Socket != "" {
+		return fmt.Sprintf("%s@unix:%s", cfg.User, filepath.Clean(cfg.Socket))
+	}
+	return fmt.Sprintf("%s@%s:%d", cfg.User, strings.ToLower(cfg.Host), cfg.Port)
+}
+
+// loginKeys returns the keys a login attempt is counted under
+func loginKeys(ip, account string) []string {
+	return []string{"ip:" + ip, "account:" + account}
+}
+
+// ReserveLoginAttempt returns a LoginBlockedError when the client IP or the
+// account has to wait before another login attempt. Otherwise it counts the
+// attempt as failed before the credentials are checked, so that parallel guesses
+// cannot all pass while the first ones are still being validated; a successful
+// login undoes it with RecordLoginSuccess.
+func ReserveLoginAttempt(ip, account string) error {
+	loginLimitMu.Lock()
+	defer loginLimitMu.Unlock()
+
+	now := time.Now()
+	pruneLoginState(now)
+
+	var wait time.Duration
+	for _, key := range loginKeys(ip, account) {
+		if a, ok := loginState[key]; ok && a.blockedUntil.After(now) {
+			if d := a.blockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+
+	recordLoginFailure(loginKeys(ip, account), now)
+	return nil
+}
+
+// recordLoginFailure counts a failed login and blocks further attempts with an
+// exponentially growing delay, locking the key after too many failures. The
+// caller must hold loginLimitMu.
+func recordLoginFailure(keys []string, now time.Time) {
+	for _, key := range keys {
+		a, ok := loginState[key]
+		if !ok {
+			a = &loginAttempts{}
+			loginState[key] = a
+		}
+		a.failures++
+		a.lastFailure = now
+
+		switch {
+		case loginLimit.LockoutAfter > 0 && a.failures >= loginLimit.LockoutAfter:
+			a.locked = true
+			a.blockedUntil = now.Add(loginLimit.LockoutDuration)
+		case a.failures > loginLimit.FreeAttempts:
+			a.blockedUntil = now.Add(backoffDelay(a.failures - loginLimit.FreeAttempts))
+		}
+	}
+}
+
+// RecordLoginSuccess forgets the failures of the client IP and the account
+func RecordLoginSuccess(ip, account string) {
+	loginLimitMu.Lock()
+	defer loginLimitMu.Unlock()
+
+	for _, key := range loginKeys(ip, account) {
+		delete(loginState, key)
+	}
+}
+
+// backoffDelay returns the delay after the nth failure beyond the free attempts
+func backoffDelay(n int) time.Duration {
+	delay := loginLimit.BaseDelay
+	for i := 1; i < n && delay < loginLimit.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > loginLimit.MaxDelay {
+		delay = loginLimit.MaxDelay
+	}
+	return delay
+}
+
+// pruneLoginState drops keys whose failures have expired. The caller must hold
+// loginLimitMu.
+func pruneLoginState(now time.Time) {
+	for key, a := range loginState {
+		if now.After(a.blockedUntil) && now.Sub(a.lastFailure) > loginLimit.ResetAfter {
+			delete(loginState, key)
+		}
+	}
+}
+
+// ListLoginLimits returns the current failed login state for operators
+func ListLoginLimits() []models.LoginLimit {
+	loginLimitMu.Lock()
+	defer loginLimitMu.Unlock()
+
+	now := time.Now()
+	pruneLoginState(now)
+
+	result := make([]models.LoginLimit, 0, len(loginState))
+	for key, a := range loginState {
+		limit := models.LoginLimit{
+			Key:         key,
+			Failures:    a.failures,
+			LastFailure: a.lastFailure,
+		}
+		if a.blockedUntil.After(now) {
+			blockedUntil := a.blockedUntil
+			limit.BlockedUntil = &blockedUntil
+			limit.Locked = a.locked
+		}
+		result = append(result, limit)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// ClearLoginLimit lifts the block of a key, e.g. "ip:10.0.0.5"
+func ClearLoginLimit(key string) error {
+	loginLimitMu.Lock()
+	defer loginLimitMu.Unlock()
+
+	if _, ok := loginState[key]; !ok {
+		return fmt.Errorf("%w: login limit %s", ErrNotFound, key)
+	}
+	delete(loginState, key)
+	return nil
+}
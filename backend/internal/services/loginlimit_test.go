@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/database"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withLoginLimit runs a test against fresh login limiter state
+func withLoginLimit(t *testing.T, cfg config.LoginLimitConfig) {
+	t.Helper()
+	loginLimitMu.Lock()
+	saved, savedState := loginLimit, loginState
+	loginLimit, loginState = cfg, make(map[string]*loginAttempts)
+	loginLimitMu.Unlock()
+
+	t.Cleanup(func() {
+		loginLimitMu.Lock()
+		loginLimit, loginState = saved, savedState
+		loginLimitMu.Unlock()
+	})
+}
+
+func TestReserveLoginAttempt(t *testing.T) {
+	cfg := config.LoginLimitConfig{
+		FreeAttempts:    2,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    5,
+		LockoutDuration: 24 * time.Hour,
+		ResetAfter:      time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		success  bool
+		wantErr  bool
+	}{
+		{name: "first attempt", failures: 0},
+		{name: "within the free attempts", failures: 1},
+		{name: "backs off after the free attempts", failures: 3, wantErr: true},
+		{name: "success forgets the failures", failures: 3, success: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withLoginLimit(t, cfg)
+			for i := 0; i < tt.failures; i++ {
+				// Reserved attempts count as failures until a success is recorded
+				loginLimitMu.Lock()
+				recordLoginFailure(loginKeys("10.0.0.1", "root@db:3306"), time.Now())
+				loginLimitMu.Unlock()
+			}
+			if tt.success {
+				RecordLoginSuccess("10.0.0.1", "root@db:3306")
+			}
+
+			err := ReserveLoginAttempt("10.0.0.1", "root@db:3306")
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("ReserveLoginAttempt() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrTooManyAttempts) {
+				t.Fatalf("ReserveLoginAttempt() = %v, want ErrTooManyAttempts", err)
+			}
+		})
+	}
+}
+
+func TestReserveLoginAttemptConcurrent(t *testing.T) {
+	withLoginLimit(t, config.LoginLimitConfig{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		ResetAfter:   time.Hour,
+	})
+
+	// Parallel guesses must not all pass the check before any failure is counted
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ReserveLoginAttempt("10.0.0.1", "root@db:3306") == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 4 {
+		t.Fatalf("%d parallel attempts allowed, want 4", allowed)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	withLoginLimit(t, config.LoginLimitConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{30, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.n); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLoginAccount(t *testing.T) {
+	tests := []struct {
+		cfg  database.Config
+		want string
+	}{
+		{database.Config{User: "root", Host: "DB.internal", Port: 3306}, "root@db.internal:3306"},
+		{database.Config{User: "Root", Host: "db.internal", Port: 3306}, "Root@db.internal:3306"},
+		{database.Config{User: "root", Socket: "/run/mysqld/../mysqld/mysqld.sock"}, "root@unix:/run/mysqld/mysqld.sock"},
+	}
+	for _, tt := range tests {
+		if got := LoginAccount(tt.cfg); got != tt.want {
+			t.Errorf("LoginAccount(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/config"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcLoginTimeout bounds the time between starting and finishing an OIDC login
+const oidcLoginTimeout = 10 * time.Minute
+
+// Logins are started without authentication, so the pending ones are capped per
+// client IP, dropping the client's oldest, and in total
+const (
+	maxOIDCLoginsPerClient = 10
+	maxOIDCLogins          = 10000
+)
+
+// ErrTooManyLogins is returned when too many OIDC logins are awaiting their callback
+var ErrTooManyLogins = errors.New("too many logins in progress, retry later")
+
+// oidcLogin is an OIDC authorization request awaiting its callback
+type oidcLogin struct {
+	nonce   string
+	profile string
+	client  string
+	expires time.Time
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcSettings config.OIDCConfig
+	oidcProvider *oidc.Provider
+	oidcPending  = make(map[string]oidcLogin)
+
+	// oidcHTTPClient talks to the provider for discovery, keys and token exchange
+	oidcHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// initOIDC sets the provider settings; the provider is discovered on first use
+func initOIDC(cfg config.OIDCConfig) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	oidcSettings = cfg
+	oidcProvider = nil
+	oidcPending = make(map[string]oidcLogin)
+}
+
+// oidcClient returns the discovered provider and the OAuth2 client configuration
+func oidcClient() (*oidc.Provider, *oauth2.Config, config.OIDCConfig, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	cfg := oidcSettings
+	if !cfg.Enabled {
+		return nil, nil, cfg, fmt.Errorf("%w: OpenID Connect login is not enabled", ErrNotFound)
+	}
+
+	if oidcProvider == nil {
+		// The provider keeps the context for fetching signing keys later on
+		provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), oidcHTTPClient), cfg.Issuer)
+		if err != nil {
+			return nil, nil, cfg, fmt.Errorf("OpenID Connect discovery failed: %w", err)
+		}
+		oidcProvider = provider
+	}
+
+	oauth := &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Endpoint:     oidcProvider.Endpoint(),
+		Scopes:       cfg.Scopes,
+	}
+	return oidcProvider, oauth, cfg, nil
+}
+
+// StartOIDCLogin returns the provider's authorization URL and the state that
+// identifies the login. The profile is used once the login completes.
+func StartOIDCLogin(profile, clientIP string) (string, string, error) {
+	_, oauth, _, err := oidcClient()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := newSessionID()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newSessionID()
+	if err != nil {
+		return "", "", err
+	}
+
+	oidcMu.Lock()
+	now := time.Now()
+	clientLogins, oldest := 0, ""
+	for key, pending := range oidcPending {
+		if now.After(pending.expires) {
+			delete(oidcPending, key)
+			continue
+		}
+		if pending.client == clientIP {
+			clientLogins++
+			if oldest == "" || pending.expires.Before(oidcPending[oldest].expires) {
+				oldest = key
+			}
+		}
+	}
+	if clientLogins >= maxOIDCLoginsPerClient {
+		delete(oidcPending, oldest)
+	}
+	if len(oidcPending) >= maxOIDCLogins {
+		oidcMu.Unlock()
+		return "", "", ErrTooManyLogins
+	}
+	oidcPending[state] = oidcLogin{nonce: nonce, profile: profile, client: clientIP, expires: now.Add(oidcLoginTimeout)}
+	oidcMu.Unlock()
+
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce)), state, nil
+}
+
+// FinishOIDCLogin exchanges the authorization code, verifies the ID token and maps
+// its claims to an application user. It returns the profile requested at start.
+func FinishOIDCLogin(ctx context.Context, state, code string) (*AppUser, string, error) {
+	oidcMu.Lock()
+	pending, ok := oidcPending[state]
+	delete(oidcPending, state)
+	oidcMu.Unlock()
+
+	if !ok || time.Now().After(pending.expires) {
+		return nil, "", fmt.Errorf("%w: unknown or expired login state", ErrInvalidInput)
+	}
+
+	provider, oauth, cfg, err := oidcClient()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx = oidc.ClientContext(ctx, oidcHTTPClient)
+	token, err := oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: no ID token in the token response", ErrInvalidCredentials)
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if idToken.Nonce != pending.nonce {
+		return nil, "", fmt.Errorf("%w: ID token nonce mismatch", ErrInvalidCredentials)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", err
+	}
+
+	user := &AppUser{
+		Username: idToken.Subject,
+		Method:   AuthMethodOIDC,
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+	}
+	if name, ok := claims[cfg.UsernameClaim].(string); ok && name != "" {
+		user.Username = name
+	}
+
+	roles := append([]string{}, cfg.DefaultRoles...)
+	for _, value := range claimValues(claims[cfg.RolesClaim]) {
+		if role, ok := cfg.RoleMapping[value]; ok {
+			roles = append(roles, role)
+		}
+	}
+	user.Roles = dedupeRoles(roles)
+	if len(user.Roles) == 0 {
+		return nil, "", fmt.Errorf("%w: no roles are mapped to %s", ErrForbidden, user.Username)
+	}
+
+	return user, pending.profile, nil
+}
+
+// claimValues returns the string values of a claim holding a string or a list
+func claimValues(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mysql-admin-tool/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIssuer is an OpenID Connect provider issuing ID tokens with the claims set
+// by the test
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims jwt.MapClaims
+}
+
+// startTestIssuer serves discovery, signing keys and the token endpoint
+func startTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims)
+		issuer.mu.Unlock()
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// issue sets the claims of the next ID token
+func (i *testIssuer) issue(claims jwt.MapClaims) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.claims = claims
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := startTestIssuer(t)
+
+	savedClient := oidcHTTPClient
+	oidcHTTPClient = issuer.server.Client()
+	InitIdentity(config.IdentityConfig{
+		OIDC: config.OIDCConfig{
+			Enabled:       true,
+			Issuer:        issuer.server.URL,
+			ClientID:      "dbadmin",
+			ClientSecret:  "secret",
+			RedirectURL:   "https://dbadmin.example.com/api/auth/oidc/callback",
+			Scopes:        []string{"openid", "profile"},
+			UsernameClaim: "preferred_username",
+			RolesClaim:    "groups",
+			RoleMapping:   map[string]string{"dba": "admins", "dev": "engineers"},
+		},
+	})
+	t.Cleanup(func() {
+		oidcHTTPClient = savedClient
+		InitIdentity(config.Default().Identity)
+	})
+
+	tests := []struct {
+		name      string
+		claims    func(nonce string) jwt.MapClaims
+		state     func(state string) string
+		wantUser  string
+		wantRoles []string
+		wantErr   error
+	}{
+		{
+			name: "maps groups to roles",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "preferred_username": "alice", "groups": []string{"dev", "dba", "sales", "dev"}}
+			},
+			wantUser:  "alice",
+			wantRoles: []string{"engineers", "admins"},
+		},
+		{
+			name: "falls back to the subject",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "groups": "dba"}
+			},
+			wantUser:  "user-1",
+			wantRoles: []string{"admins"},
+		},
+		{
+			name: "refuses users without mapped roles",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "preferred_username": "bob", "groups": []string{"sales"}}
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name: "refuses a mismatched nonce",
+			claims: func(string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": "other", "groups": "dba"}
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "refuses tokens for another client",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "aud": "other", "groups": "dba"}
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "refuses an unknown state",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "groups": "dba"}
+			},
+			state:   func(string) string { return "forged" },
+			wantErr: ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL, state, err := StartOIDCLogin("Production", "192.0.2.1")
+			if err != nil {
+				t.Fatalf("StartOIDCLogin: %v", err)
+			}
+			u, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Query().Get("state"); got != state {
+				t.Fatalf("authorization URL state = %q, want %q", got, state)
+			}
+
+			now := time.Now()
+			claims := jwt.MapClaims{
+				"iss": issuer.server.URL,
+				"sub": "user-1",
+				"aud": "dbadmin",
+				"iat": now.Unix(),
+				"exp": now.Add(time.Hour).Unix(),
+			}
+			for k, v := range tt.claims(u.Query().Get("nonce")) {
+				claims[k] = v
+			}
+			issuer.issue(claims)
+			if tt.state != nil {
+				state = tt.state(state)
+			}
+
+			user, profile, err := FinishOIDCLogin(context.Background(), state, "code")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FinishOIDCLogin() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FinishOIDCLogin: %v", err)
+			}
+			if user.Username != tt.wantUser || user.Method != AuthMethodOIDC || !reflect.DeepEqual(user.Roles, tt.wantRoles) {
+				t.Fatalf("user = %+v, want %s with roles %v", user, tt.wantUser, tt.wantRoles)
+			}
+			// Users are told apart by subject, not by the username claim
+			if owner := (&Session{User: user}).Owner(); owner != "oidc:"+issuer.server.URL+"#user-1" {
+				t.Fatalf("owner = %q, want the issuer and subject", owner)
+			}
+			if profile != "Production" {
+				t.Fatalf("profile = %q, want Production", profile)
+			}
+
+			// A state completes one login only
+			if _, _, err := FinishOIDCLogin(context.Background(), state, "code"); !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("second FinishOIDCLogin() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestOIDCPendingLimits(t *testing.T) {
+	issuer := startTestIssuer(t)
+
+	savedClient := oidcHTTPClient
+	oidcHTTPClient = issuer.server.Client()
+	InitIdentity(config.IdentityConfig{
+		OIDC: config.OIDCConfig{
+			Enabled:     true,
+			Issuer:      issuer.server.URL,
+			ClientID:    "dbadmin",
+			RedirectURL: "https://dbadmin.example.com/api/auth/oidc/callback",
+			Scopes:      []string{"openid"},
+		},
+	})
+	t.Cleanup(func() {
+		oidcHTTPClient = savedClient
+		InitIdentity(config.Default().Identity)
+	})
+
+	var states []string
+	for i := 0; i < maxOIDCLoginsPerClient+1; i++ {
+		_, state, err := StartOIDCLogin("", "192.0.2.1")
+		if err != nil {
+			t.Fatalf("StartOIDCLogin: %v", err)
+		}
+		states = append(states, state)
+		// Logins of one client are ordered by expiry
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := FinishOIDCLogin(context.Background(), states[0], "code"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("oldest login of the client: error = %v, want ErrInvalidInput", err)
+	}
+
+	oidcMu.Lock()
+	for i := len(oidcPending); i < maxOIDCLogins; i++ {
+		oidcPending[fmt.Sprint("state-", i)] = oidcLogin{client: fmt.Sprint("198.51.100.", i), expires: time.Now().Add(time.Minute)}
+	}
+	oidcMu.Unlock()
+	if _, _, err := StartOIDCLogin("", "203.0.113.1"); !errors.Is(err, ErrTooManyLogins) {
+		t.Errorf("full server: error = %v, want ErrTooManyLogins", err)
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type hints of query parameters
+const (
+	ParamString   = "string"
+	ParamInt      = "int"
+	ParamDecimal  = "decimal"
+	ParamFloat    = "float"
+	ParamBool     = "bool"
+	ParamDate     = "date"
+	ParamDateTime = "datetime"
+	ParamTime     = "time"
+	ParamBinary   = "binary"
+)
+
+var (
+	// decimalPattern matches exact numeric literals
+	decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+	// timePattern matches TIME values, which may exceed 24 hours or be negative
+	timePattern = regexp.MustCompile(`^-?[0-9]{1,3}:[0-5][0-9](:[0-5][0-9](\.[0-9]{1,6})?)?$`)
+)
+
+// dateTimeLayouts are the accepted formats of datetime parameters
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999",
+	"2006-01-02T15:04:05.999999",
+	"2006-01-02",
+}
+
+// BindParams prepares a query and its parameters for binding: named parameters
+// replace the :name placeholders with ?, positional ones must match the number of
+// ? placeholders. It returns the query to run and the converted values.
+func BindParams(query string, params []models.QueryParam) (string, []interface{}, error) {
+	named := len(params) > 0 && params[0].Name != ""
+	for _, p := range params {
+		if (p.Name != "") != named {
+			return "", nil, fmt.Errorf("%w: parameters must all be named or all be positional", ErrInvalidInput)
+		}
+	}
+
+	bound, names := sqlstmt.BindNamed(query)
+	positional := sqlstmt.CountPositional(query)
+	if len(names) > 0 && positional > 0 {
+		return "", nil, fmt.Errorf("%w: the query mixes ? and :name placeholders", ErrInvalidInput)
+	}
+
+	if !named {
+		if len(names) > 0 {
+			return "", nil, fmt.Errorf("%w: the query has :name placeholders, parameters need names", ErrInvalidInput)
+		}
+		if positional != len(params) {
+			return "", nil, fmt.Errorf("%w: the query has %d placeholders but %d parameters were given", ErrInvalidInput, positional, len(params))
+		}
+		args := make([]interface{}, len(params))
+		for i, p := range params {
+			value, err := convertParam(p)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: parameter %d: %v", ErrInvalidInput, i+1, err)
+			}
+			args[i] = value
+		}
+		return query, args, nil
+	}
+
+	values := make(map[string]models.QueryParam, len(params))
+	for _, p := range params {
+		if _, ok := values[p.Name]; ok {
+			return "", nil, fmt.Errorf("%w: parameter %s is given twice", ErrInvalidInput, p.Name)
+		}
+		values[p.Name] = p
+	}
+	args, err := bindNamedValues(names, values)
+	if err != nil {
+		return "", nil, err
+	}
+	return bound, args, nil
+}
+
+// bindNamedValues returns the converted value of each placeholder name in order.
+// Every name needs a value and every value a placeholder.
+func bindNamedValues(names []string, values map[string]models.QueryParam) ([]interface{}, error) {
+	var missing []string
+	for _, name := range names {
+		if _, ok := values[name]; !ok && !containsName(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing values for %s", ErrInvalidInput, strings.Join(missing, ", "))
+	}
+	for name := range values {
+		if !containsName(names, name) {
+			return nil, fmt.Errorf("%w: the query has no parameter %s", ErrInvalidInput, name)
+		}
+	}
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		value, err := convertParam(values[name])
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %s: %v", ErrInvalidInput, name, err)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// convertParam converts a parameter value to the value bound for its type hint.
+// Without a hint strings, booleans and null are bound as they are, whole numbers
+// as integers and other numbers as floats. null is NULL for every type.
+func convertParam(p models.QueryParam) (interface{}, error) {
+	if p.Value == nil {
+		return nil, nil
+	}
+
+	switch p.Type {
+	case "":
+		switch v := p.Value.(type) {
+		case string, bool:
+			return v, nil
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+			if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+				return u, nil
+			}
+			return v.Float64()
+		case float64:
+			return v, nil
+		}
+	case ParamString:
+		switch v := p.Value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case ParamInt:
+		text, ok := numberText(p.Value)
+		if !ok {
+			break
+		}
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return u, nil
+		}
+		return nil, fmt.Errorf("%q is not an integer", text)
+	case ParamDecimal:
+		// Decimals are bound as text so that MySQL converts them exactly
+		text, ok := numberText(p.Value)
+		if !ok {
+			break
+		}
+		if !decimalPattern.MatchString(text) {
+			return nil, fmt.Errorf("%q is not a decimal number", text)
+		}
+		return text, nil
+	case ParamFloat:
+		text, ok := numberText(p.Value)
+		if !ok {
+			break
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return f, nil
+	case ParamBool:
+		switch v := p.Value.(type) {
+		case bool:
+			return v, nil
+		case string, json.Number:
+			b, err := strconv.ParseBool(fmt.Sprint(v))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", v)
+			}
+			return b, nil
+		}
+	case ParamDate:
+		// Dates are bound as text; a time.Time would be shifted to the connection's
+		// time zone
+		if s, ok := p.Value.(string); ok {
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return nil, fmt.Errorf("%q is not a date (YYYY-MM-DD)", s)
+			}
+			return s, nil
+		}
+	case ParamDateTime:
+		if s, ok := p.Value.(string); ok {
+			for _, layout := range dateTimeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not a datetime (RFC 3339 or YYYY-MM-DD HH:MM:SS)", s)
+		}
+	case ParamTime:
+		if s, ok := p.Value.(string); ok {
+			if !timePattern.MatchString(s) {
+				return nil, fmt.Errorf("%q is not a time (HH:MM:SS)", s)
+			}
+			return s, nil
+		}
+	case ParamBinary:
+		if s, ok := p.Value.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("binary values must be base64 encoded")
+			}
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", p.Type)
+	}
+
+	if p.Type == "" {
+		return nil, fmt.Errorf("values must be strings, numbers, booleans or null")
+	}
+	return nil, fmt.Errorf("invalid %s value %v", p.Type, p.Value)
+}
+
+// numberText returns the text of a number or string value
+func numberText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String(), true
+	case string:
+		return strings.TrimSpace(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
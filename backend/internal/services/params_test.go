@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"mysql-admin-tool/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBindParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		params  []models.QueryParam
+		bound   string
+		args    []interface{}
+		wantErr bool
+	}{
+		{
+			name:   "positional",
+			query:  "SELECT * FROM t WHERE a = ? AND b = ?",
+			params: []models.QueryParam{{Value: "x"}, {Value: json.Number("2")}},
+			bound:  "SELECT * FROM t WHERE a = ? AND b = ?",
+			args:   []interface{}{"x", int64(2)},
+		},
+		{
+			name:   "named values are repeated for each placeholder",
+			query:  "SELECT * FROM t WHERE a = :a OR b = :a AND c = :c",
+			params: []models.QueryParam{{Name: "c", Value: nil}, {Name: "a", Value: true}},
+			bound:  "SELECT * FROM t WHERE a = ? OR b = ? AND c = ?",
+			args:   []interface{}{true, true, nil},
+		},
+		{
+			name:   "placeholders in executable comments are bound",
+			query:  "SELECT /*!80000 :a, */ /*M! :a, */ :b",
+			params: []models.QueryParam{{Name: "a", Value: "x"}, {Name: "b", Value: json.Number("1")}},
+			bound:  "SELECT /*!80000 ?, */ /*M! ?, */ ?",
+			args:   []interface{}{"x", "x", int64(1)},
+		},
+		{
+			name:  "no parameters",
+			query: "SELECT ':a', '?'",
+			bound: "SELECT ':a', '?'",
+			args:  []interface{}{},
+		},
+		{
+			name:    "too few positional parameters",
+			query:   "SELECT ?, ?",
+			params:  []models.QueryParam{{Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "mixed placeholders",
+			query:   "SELECT ?, :a",
+			params:  []models.QueryParam{{Name: "a", Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "mixed parameters",
+			query:   "SELECT :a, :b",
+			params:  []models.QueryParam{{Name: "a", Value: "x"}, {Value: "y"}},
+			wantErr: true,
+		},
+		{
+			name:    "positional parameters for named placeholders",
+			query:   "SELECT :a",
+			params:  []models.QueryParam{{Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			query:   "SELECT :a, :b",
+			params:  []models.QueryParam{{Name: "a", Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown parameter",
+			query:   "SELECT :a",
+			params:  []models.QueryParam{{Name: "a", Value: "x"}, {Name: "b", Value: "y"}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate parameter",
+			query:   "SELECT :a",
+			params:  []models.QueryParam{{Name: "a", Value: "x"}, {Name: "a", Value: "y"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound, args, err := BindParams(tt.query, tt.params)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("BindParams() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BindParams: %v", err)
+			}
+			if bound != tt.bound || !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("BindParams() = %q, %#v; want %q, %#v", bound, args, tt.bound, tt.args)
+			}
+		})
+	}
+}
+
+func TestConvertParam(t *testing.T) {
+	tests := []struct {
+		param   models.QueryParam
+		want    interface{}
+		wantErr bool
+	}{
+		{param: models.QueryParam{Value: nil}, want: nil},
+		{param: models.QueryParam{Type: ParamInt, Value: nil}, want: nil},
+		{param: models.QueryParam{Value: "text"}, want: "text"},
+		{param: models.QueryParam{Value: json.Number("12")}, want: int64(12)},
+		{param: models.QueryParam{Value: json.Number("18446744073709551615")}, want: uint64(18446744073709551615)},
+		{param: models.QueryParam{Value: json.Number("1.5")}, want: 1.5},
+		{param: models.QueryParam{Value: []interface{}{1}}, wantErr: true},
+		{param: models.QueryParam{Type: ParamString, Value: json.Number("007")}, want: "007"},
+		{param: models.QueryParam{Type: ParamInt, Value: " 42 "}, want: int64(42)},
+		{param: models.QueryParam{Type: ParamInt, Value: "4.2"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamDecimal, Value: json.Number("0.1")}, want: "0.1"},
+		{param: models.QueryParam{Type: ParamDecimal, Value: "1e3"}, want: "1e3"},
+		{param: models.QueryParam{Type: ParamDecimal, Value: "1; DROP TABLE t"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamFloat, Value: "2.5"}, want: 2.5},
+		{param: models.QueryParam{Type: ParamBool, Value: "true"}, want: true},
+		{param: models.QueryParam{Type: ParamBool, Value: "yes"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamDate, Value: "2024-02-29"}, want: "2024-02-29"},
+		{param: models.QueryParam{Type: ParamDate, Value: "2023-02-29"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamDateTime, Value: "2024-02-29 13:04:05"}, want: time.Date(2024, 2, 29, 13, 4, 5, 0, time.UTC)},
+		{param: models.QueryParam{Type: ParamDateTime, Value: "yesterday"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamTime, Value: "-838:59:59"}, want: "-838:59:59"},
+		{param: models.QueryParam{Type: ParamTime, Value: "12:60"}, wantErr: true},
+		{param: models.QueryParam{Type: ParamBinary, Value: "AP8="}, want: []byte{0x00, 0xff}},
+		{param: models.QueryParam{Type: ParamBinary, Value: "not base64"}, wantErr: true},
+		{param: models.QueryParam{Type: "uuid", Value: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := convertParam(tt.param)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("convertParam(%+v) = %#v, want an error", tt.param, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertParam(%+v): %v", tt.param, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("convertParam(%+v) = %#v, want %#v", tt.param, got, tt.want)
+		}
+	}
+}
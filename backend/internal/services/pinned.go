@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"mysql-admin-tool/internal/database"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// pinnedConn is a dedicated MySQL connection held across requests, for open
+// transactions and console sessions
+type pinnedConn struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+// openPinnedConn opens a dedicated connection to dbName
+func openPinnedConn(dbName string, cfg database.Config) (*pinnedConn, error) {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dbConn.Conn(context.Background())
+	if err != nil {
+		dbConn.Close()
+		return nil, err
+	}
+	return &pinnedConn{db: dbConn, conn: conn}, nil
+}
+
+// close closes the connection; the server discards its session state and rolls
+// back an open transaction
+func (p *pinnedConn) close() {
+	p.conn.Close()
+	p.db.Close()
+}
+
+// connectionLost reports whether a statement error left the connection unusable
+func connectionLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"database/sql"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// privilegeSet is a set of upper-case privilege names
+type privilegeSet map[string]bool
+
+// has reports whether the set contains the privilege, directly or through ALL
+func (s privilegeSet) has(privilege string) bool {
+	return s[privilege] || s["ALL"] || s["ALL PRIVILEGES"]
+}
+
+// add adds the privileges of a grant to the set
+func (s privilegeSet) add(privileges []models.GrantPrivilege) {
+	for _, p := range privileges {
+		// Column-level privileges do not apply to the table as a whole
+		if len(p.Columns) == 0 {
+			s[p.Name] = true
+		}
+	}
+}
+
+// union returns a new set containing the privileges of both sets
+func (s privilegeSet) union(other privilegeSet) privilegeSet {
+	result := make(privilegeSet, len(s)+len(other))
+	for p := range s {
+		result[p] = true
+	}
+	for p := range other {
+		result[p] = true
+	}
+	return result
+}
+
+// objectPrivileges converts the set into the API representation
+func (s privilegeSet) objectPrivileges() *models.ObjectPrivileges {
+	return &models.ObjectPrivileges{
+		Select: s.has("SELECT"),
+		Insert: s.has("INSERT"),
+		Update: s.has("UPDATE"),
+		Delete: s.has("DELETE"),
+		Alter:  s.has("ALTER"),
+		Drop:   s.has("DROP"),
+	}
+}
+
+// databaseGrant holds the privileges granted on a database name pattern
+type databaseGrant struct {
+	pattern *regexp.Regexp
+	privs   privilegeSet
+}
+
+// effectivePrivileges holds the privileges of an account resolved per level
+type effectivePrivileges struct {
+	account   string
+	roles     []string
+	raw       []string
+	grants    []models.Grant
+	global    privilegeSet
+	databases []databaseGrant
+	tables    map[string]privilegeSet
+}
+
+// forDatabase returns the privileges that apply to every table of a database
+func (p *effectivePrivileges) forDatabase(dbName string) privilegeSet {
+	result := p.global.union(nil)
+	for _, grant := range p.databases {
+		if grant.pattern.MatchString(dbName) {
+			result = result.union(grant.privs)
+		}
+	}
+	return result
+}
+
+// forTable returns the privileges that apply to a table
+func (p *effectivePrivileges) forTable(dbName, tableName string) privilegeSet {
+	return p.forDatabase(dbName).union(p.tables[dbName+"."+tableName])
+}
+
+// loadEffectivePrivileges reads and resolves the grants of the connected account,
+// including the privileges of its granted roles
+func loadEffectivePrivileges(dbConn *sql.DB) (*effectivePrivileges, error) {
+	p := &effectivePrivileges{
+		global: make(privilegeSet),
+		tables: make(map[string]privilegeSet),
+	}
+
+	if err := dbConn.QueryRow("SELECT CURRENT_USER()").Scan(&p.account); err != nil {
+		return nil, err
+	}
+
+	statements, err := showGrants(dbConn, "SHOW GRANTS")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range statements {
+		if grant, ok := ParseGrant(statement); ok && grant.Level == models.GrantLevelRole {
+			p.roles = append(p.roles, grant.Roles...)
+		}
+	}
+
+	// Expand the privileges granted through roles (MySQL 8.0)
+	if len(p.roles) > 0 {
+		quoted := make([]string, 0, len(p.roles))
+		for _, role := range p.roles {
+			name, host, _ := strings.Cut(role, "@")
+			quoted = append(quoted, accountName(name, host))
+		}
+		if expanded, err := showGrants(dbConn, "SHOW GRANTS FOR CURRENT_USER() USING "+strings.Join(quoted, ", ")); err == nil {
+			statements = expanded
+		}
+	}
+	p.raw = statements
+
+	for _, statement := range statements {
+		grant, ok := ParseGrant(statement)
+		if !ok {
+			continue
+		}
+		p.grants = append(p.grants, grant)
+
+		switch grant.Level {
+		case models.GrantLevelGlobal:
+			p.global.add(grant.Privileges)
+		case models.GrantLevelDatabase:
+			privs := make(privilegeSet)
+			privs.add(grant.Privileges)
+			p.databases = append(p.databases, databaseGrant{
+				pattern: databasePattern(grant.Database),
+				privs:   privs,
+			})
+		case models.GrantLevelTable:
+			key := grant.Database + "." + grant.Table
+			if p.tables[key] == nil {
+				p.tables[key] = make(privilegeSet)
+			}
+			p.tables[key].add(grant.Privileges)
+		}
+	}
+
+	return p, nil
+}
+
+// databasePattern converts a database grant name, which may contain the LIKE
+// wildcards % and _ (escaped with a backslash), into a regular expression
+func databasePattern(name string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; c {
+		case '\\':
+			if i+1 < len(name) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(name[i])))
+			}
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// GetCurrentPrivileges returns the effective privileges of the logged-in account
+func GetCurrentPrivileges(cfg database.Config) (*models.CurrentPrivileges, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	p, err := loadEffectivePrivileges(dbConn)
+	if err != nil {
+		return nil, err
+	}
+
+	global := []string{}
+	for name := range p.global {
+		global = append(global, name)
+	}
+	sort.Strings(global)
+
+	result := &models.CurrentPrivileges{
+		Account: p.account,
+		Global:  global,
+		Roles:   p.roles,
+		Grants:  p.grants,
+		Raw:     p.raw,
+	}
+	if result.Roles == nil {
+		result.Roles = []string{}
+	}
+	if result.Grants == nil {
+		result.Grants = []models.Grant{}
+	}
+	return result, nil
+}
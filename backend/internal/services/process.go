@@ -0,0 +1,95 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+)
+
+// ListProcesses returns the server process list visible to the user. Accounts without
+// the PROCESS privilege only see their own threads.
+func ListProcesses(filter models.ProcessFilter, cfg database.Config) ([]models.Process, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	query := "SELECT ID, USER, HOST, DB, COMMAND, TIME, STATE, INFO FROM information_schema.PROCESSLIST WHERE ID <> CONNECTION_ID()"
+	var args []interface{}
+	if filter.User != "" {
+		query += " AND USER = ?"
+		args = append(args, filter.User)
+	}
+	if filter.DB != "" {
+		query += " AND DB = ?"
+		args = append(args, filter.DB)
+	}
+	if filter.Command != "" {
+		query += " AND COMMAND = ?"
+		args = append(args, filter.Command)
+	}
+	if filter.MinTime > 0 {
+		query += " AND TIME >= ?"
+		args = append(args, filter.MinTime)
+	}
+	query += " ORDER BY TIME DESC, ID"
+
+	rows, err := dbConn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	processes := []models.Process{}
+	for rows.Next() {
+		var p models.Process
+		var db, state, info sql.NullString
+		if err := rows.Scan(&p.ID, &p.User, &p.Host, &db, &p.Command, &p.Time, &state, &info); err != nil {
+			continue
+		}
+		if db.Valid {
+			p.DB = &db.String
+		}
+		if state.Valid {
+			p.State = &state.String
+		}
+		if info.Valid {
+			p.Info = &info.String
+		}
+		processes = append(processes, p)
+	}
+
+	return processes, rows.Err()
+}
+
+// KillProcess terminates a connection, or only its running statement when queryOnly
+// is set. MySQL enforces the privilege check: killing threads of other accounts
+// requires CONNECTION_ADMIN or SUPER.
+func KillProcess(id int64, queryOnly bool, cfg database.Config) error {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	var count int
+	if err := dbConn.QueryRow("SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE ID = ?", id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: process %d", ErrNotFound, id)
+	}
+
+	_, err = dbConn.Exec(KillStatement(id, queryOnly))
+	return err
+}
+
+// KillStatement returns the statement KillProcess runs
+func KillStatement(id int64, queryOnly bool) string {
+	if queryOnly {
+		return fmt.Sprintf("KILL QUERY %d", id)
+	}
+	return fmt.Sprintf("KILL CONNECTION %d", id)
+}
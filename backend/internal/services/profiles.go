@@ -0,0 +1,436 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	profilesMu   sync.RWMutex
+	profilesPath string
+	profilesKey  [32]byte
+	// profilesKeySet is false when neither PROFILES_KEY nor JWT_SECRET is set;
+	// profiles are then not stored, since the public default secret would
+	// protect nothing
+	profilesKeySet bool
+	profiles       = make(map[string]*models.ConnectionProfile)
+)
+
+// InitProfiles loads the encrypted profile store. The encryption key is derived from
+// key, falling back to the JWT secret when empty. Without either profiles cannot
+// be stored. A store encrypted with the default JWT secret is re-encrypted with
+// the key.
+func InitProfiles(path, key string) error {
+	if key == "" && JWTSecretSet() {
+		log.Printf("PROFILES_KEY not set, encrypting connection profiles with the JWT secret")
+		key = string(jwtSecret)
+	}
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	profilesPath = path
+	profilesKeySet = key != ""
+	profilesKey = sha256.Sum256([]byte(key))
+	profiles = make(map[string]*models.ConnectionProfile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !profilesKeySet {
+		return fmt.Errorf("PROFILES_KEY or JWT_SECRET must be set to decrypt profile store %s", path)
+	}
+
+	rekey := false
+	plaintext, err := decryptProfiles(data, profilesKey)
+	if err != nil {
+		var legacyErr error
+		plaintext, legacyErr = decryptProfiles(data, sha256.Sum256([]byte(defaultJWTSecret)))
+		if legacyErr != nil {
+			return fmt.Errorf("failed to decrypt profile store %s: %w", path, err)
+		}
+		rekey = true
+	}
+
+	var stored []*models.ConnectionProfile
+	if err := json.Unmarshal(plaintext, &stored); err != nil {
+		return fmt.Errorf("failed to parse profile store %s: %w", path, err)
+	}
+	for _, p := range stored {
+		profiles[p.ID] = p
+	}
+	if rekey {
+		log.Printf("Re-encrypting profile store %s, which used the default JWT secret", path)
+		return saveProfiles()
+	}
+	return nil
+}
+
+// ListProfiles returns all profiles without their secrets
+func ListProfiles() []models.ConnectionProfile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	result := make([]models.ConnectionProfile, 0, len(profiles))
+	for _, p := range profiles {
+		result = append(result, redactProfile(p))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// ListProfileSummaries returns the public part of all profiles for the login screen
+func ListProfileSummaries() []models.ProfileSummary {
+	list := ListProfiles()
+	result := make([]models.ProfileSummary, len(list))
+	for i, p := range list {
+		result[i] = models.ProfileSummary{
+			ID:          p.ID,
+			Name:        p.Name,
+			Color:       p.Color,
+			Tag:         p.Tag,
+			HasPassword: p.HasPassword,
+			ReadOnly:    p.ReadOnly,
+		}
+	}
+	return result
+}
+
+// GetProfile returns a profile without its secrets
+func GetProfile(id string) (*models.ConnectionProfile, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	p, ok := profiles[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: profile %s", ErrNotFound, id)
+	}
+	redacted := redactProfile(p)
+	return &redacted, nil
+}
+
+// ResolveProfile returns a profile including its stored credentials, for use when
+// connecting. It must never be returned to API clients.
+func ResolveProfile(id string) (*models.ConnectionProfile, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	p, ok := profiles[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: profile %s", ErrNotFound, id)
+	}
+	resolved := *p
+	return &resolved, nil
+}
+
+// findProfileID returns the ID of a profile referenced by ID or name
+func findProfileID(ref string) (string, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
+	if _, ok := profiles[ref]; ok {
+		return ref, true
+	}
+	for id, p := range profiles {
+		if strings.EqualFold(p.Name, ref) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// CreateProfile stores a new profile
+func CreateProfile(req models.ProfileRequest) (*models.ConnectionProfile, error) {
+	if err := validateProfileRequest(&req); err != nil {
+		return nil, err
+	}
+
+	id, err := newProfileID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	p := &models.ConnectionProfile{ID: id, CreatedAt: now}
+	applyProfileRequest(p, req)
+	p.UpdatedAt = now
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	profiles[id] = p
+	if err := saveProfiles(); err != nil {
+		delete(profiles, id)
+		return nil, err
+	}
+	redacted := redactProfile(p)
+	return &redacted, nil
+}
+
+// UpdateProfile replaces the settings of an existing profile
+func UpdateProfile(id string, req models.ProfileRequest) (*models.ConnectionProfile, error) {
+	if err := validateProfileRequest(&req); err != nil {
+		return nil, err
+	}
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	existing, ok := profiles[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: profile %s", ErrNotFound, id)
+	}
+
+	updated := *existing
+	applyProfileRequest(&updated, req)
+	updated.UpdatedAt = time.Now().UTC()
+
+	profiles[id] = &updated
+	if err := saveProfiles(); err != nil {
+		profiles[id] = existing
+		return nil, err
+	}
+	redacted := redactProfile(&updated)
+	return &redacted, nil
+}
+
+// DeleteProfile removes a profile
+func DeleteProfile(id string) error {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+
+	existing, ok := profiles[id]
+	if !ok {
+		return fmt.Errorf("%w: profile %s", ErrNotFound, id)
+	}
+
+	delete(profiles, id)
+	if err := saveProfiles(); err != nil {
+		profiles[id] = existing
+		return err
+	}
+	return nil
+}
+
+// validateProfileRequest checks required fields and applies defaults
+func validateProfileRequest(req *models.ProfileRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("%w: profile name is required", ErrInvalidInput)
+	}
+	if req.Host == "" {
+		req.Host = "localhost"
+	}
+	if req.Port == 0 {
+		req.Port = 3306
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		return fmt.Errorf("%w: invalid port %d", ErrInvalidInput, req.Port)
+	}
+	if req.TLS != nil {
+		// The certificate can only be checked together with a new key
+		tlsOptions := *req.TLS
+		if tlsOptions.Key == "" {
+			tlsOptions.Cert = ""
+		}
+		if err := database.ValidateTLSOptions(tlsOptions); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+	if req.Options != nil {
+		if err := database.ValidateConnectionOptions(*req.Options); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+	if req.Socket != "" && req.SSH != nil {
+		return fmt.Errorf("%w: a unix socket cannot be used through an SSH tunnel", ErrInvalidInput)
+	}
+	if req.SSH != nil {
+		if req.SSH.Port == 0 {
+			req.SSH.Port = 22
+		}
+		if req.SSH.Host == "" || req.SSH.User == "" {
+			return fmt.Errorf("%w: SSH host and user are required", ErrInvalidInput)
+		}
+		if req.SSH.Port < 1 || req.SSH.Port > 65535 {
+			return fmt.Errorf("%w: invalid SSH port %d", ErrInvalidInput, req.SSH.Port)
+		}
+	}
+	return nil
+}
+
+// applyProfileRequest copies request fields onto a profile, keeping the stored
+// password when none is given
+func applyProfileRequest(p *models.ConnectionProfile, req models.ProfileRequest) {
+	p.Name = req.Name
+	p.Host = req.Host
+	p.Port = req.Port
+	p.Database = req.Database
+	p.Socket = req.Socket
+	p.Options = req.Options
+	p.ReadOnly = req.ReadOnly
+	p.Username = req.Username
+	p.Color = req.Color
+	p.Tag = req.Tag
+
+	switch {
+	case req.ClearPassword:
+		p.Password = ""
+	case req.Password != "":
+		p.Password = req.Password
+	}
+	p.HasPassword = p.Password != ""
+
+	// An empty client key keeps the stored one while the certificate is unchanged
+	if req.TLS == nil {
+		p.TLS = nil
+	} else {
+		tlsOptions := *req.TLS
+		tlsOptions.HasKey = false
+		if tlsOptions.Key == "" && p.TLS != nil && p.TLS.Cert == tlsOptions.Cert {
+			tlsOptions.Key = p.TLS.Key
+		}
+		p.TLS = &tlsOptions
+	}
+
+	// Empty SSH secrets keep the stored ones
+	if req.SSH == nil {
+		p.SSH = nil
+	} else {
+		sshOptions := *req.SSH
+		sshOptions.HasPassword = false
+		sshOptions.HasPrivateKey = false
+		if p.SSH != nil {
+			if sshOptions.Password == "" {
+				sshOptions.Password = p.SSH.Password
+			}
+			if sshOptions.PrivateKey == "" {
+				sshOptions.PrivateKey = p.SSH.PrivateKey
+				if sshOptions.Passphrase == "" {
+					sshOptions.Passphrase = p.SSH.Passphrase
+				}
+			}
+		}
+		p.SSH = &sshOptions
+	}
+}
+
+// redactProfile returns a copy of the profile without its secrets
+func redactProfile(p *models.ConnectionProfile) models.ConnectionProfile {
+	redacted := *p
+	redacted.Password = ""
+	redacted.HasPassword = p.Password != ""
+	if p.TLS != nil {
+		tlsOptions := *p.TLS
+		tlsOptions.HasKey = tlsOptions.Key != ""
+		tlsOptions.Key = ""
+		redacted.TLS = &tlsOptions
+	}
+	if p.SSH != nil {
+		sshOptions := *p.SSH
+		sshOptions.HasPassword = sshOptions.Password != ""
+		sshOptions.HasPrivateKey = sshOptions.PrivateKey != ""
+		sshOptions.Password = ""
+		sshOptions.PrivateKey = ""
+		sshOptions.Passphrase = ""
+		redacted.SSH = &sshOptions
+	}
+	return redacted
+}
+
+// newProfileID returns a random profile identifier
+func newProfileID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// saveProfiles encrypts and atomically writes the profile store. The caller must
+// hold profilesMu.
+func saveProfiles() error {
+	if !profilesKeySet {
+		return fmt.Errorf("%w: set PROFILES_KEY or JWT_SECRET to store connection profiles", ErrForbidden)
+	}
+	stored := make([]*models.ConnectionProfile, 0, len(profiles))
+	for _, p := range profiles {
+		stored = append(stored, p)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+
+	plaintext, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	data, err := encryptProfiles(plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(profilesPath), 0o700); err != nil {
+		return err
+	}
+	tmp := profilesPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, profilesPath)
+}
+
+// encryptProfiles seals the store with AES-256-GCM, prefixing the random nonce
+func encryptProfiles(plaintext []byte) ([]byte, error) {
+	gcm, err := profilesCipher(profilesKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decryptProfiles opens a store sealed by encryptProfiles with key
+func decryptProfiles(data []byte, key [32]byte) ([]byte, error) {
+	gcm, err := profilesCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("profile store is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// profilesCipher returns the AEAD of the profile store for a key
+func profilesCipher(key [32]byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
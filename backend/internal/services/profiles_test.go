@@ -0,0 +1,48 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"mysql-admin-tool/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitProfilesKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.enc")
+
+	// Without PROFILES_KEY or JWT_SECRET nothing is stored
+	if err := InitProfiles(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateProfile(models.ProfileRequest{Name: "Production", Host: "db.internal"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("CreateProfile() without a key: error = %v, want ErrForbidden", err)
+	}
+
+	// A store sealed with the default JWT secret is re-encrypted with the key
+	profilesKey = sha256.Sum256([]byte(defaultJWTSecret))
+	plaintext, _ := json.Marshal([]*models.ConnectionProfile{{ID: "p1", Name: "Production", Host: "db.internal"}})
+	sealed, err := encryptProfiles(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitProfiles(path, ""); err == nil {
+		t.Fatal("InitProfiles() without a key loaded the store")
+	}
+	if err := InitProfiles(path, "new key"); err != nil {
+		t.Fatalf("InitProfiles(): %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if _, err := decryptProfiles(data, sha256.Sum256([]byte("new key"))); err != nil {
+		t.Fatalf("store not re-encrypted: %v", err)
+	}
+	if err := InitProfiles(path, "new key"); err != nil || len(ListProfiles()) != 1 {
+		t.Fatalf("reload: %d profiles, error %v", len(ListProfiles()), err)
+	}
+}
@@ -1,37 +1,77 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"mysql-admin-tool/internal/database"
 	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
 	"strings"
 )
 
-// ExecuteQuery executes a SQL query and returns results
-func ExecuteQuery(dbName, query string, host string, port int, user, password string) (*models.QueryResponse, error) {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+// queryRunner runs statements: a connection pool, a dedicated connection or a
+// transaction
+type queryRunner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ExecuteQuery executes a SQL query and returns results. args are bound to the ?
+// placeholders of a single statement. Read-only connections only run statements
+// that read.
+func ExecuteQuery(dbName, query string, args []interface{}, cfg database.Config) (*models.QueryResponse, error) {
+	query = strings.TrimSpace(query)
+	statements, err := parseQuery(query, args, cfg.ReadOnly)
 	if err != nil {
 		return nil, err
 	}
-	defer dbConn.Close()
-
-	// Trim and check query type
-	query = strings.TrimSpace(query)
-	upperQuery := strings.ToUpper(query)
 
-	// Handle SELECT queries
-	if strings.HasPrefix(upperQuery, "SELECT") || strings.HasPrefix(upperQuery, "SHOW") || strings.HasPrefix(upperQuery, "DESCRIBE") || strings.HasPrefix(upperQuery, "DESC") || strings.HasPrefix(upperQuery, "EXPLAIN") {
-		return executeSelectQuery(dbConn, query)
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
+	if err != nil {
+		return nil, err
 	}
+	defer dbConn.Close()
 
-	// Handle INSERT, UPDATE, DELETE, CREATE, DROP, ALTER
-	result, err := dbConn.Exec(query)
+	result, err := runStatements(dbConn, statements[0], query, args)
 	if err != nil {
 		return &models.QueryResponse{
 			Error: err.Error(),
 		}, nil
 	}
+	return result, nil
+}
+
+// parseQuery splits a query into its statements and checks that they may run
+func parseQuery(query string, args []interface{}, readOnly bool) ([]sqlstmt.Statement, error) {
+	statements := sqlstmt.Parse(query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: query contains no statement", ErrInvalidInput)
+	}
+	if len(args) > 0 && len(statements) > 1 {
+		return nil, fmt.Errorf("%w: parameters can only be bound in a single statement", ErrInvalidInput)
+	}
+	if readOnly {
+		if err := checkReadOnly(statements); err != nil {
+			return nil, err
+		}
+	}
+	return statements, nil
+}
+
+// runStatements runs a query starting with the statement first. Errors of the
+// statements are returned as they are for the caller to report.
+func runStatements(runner queryRunner, first sqlstmt.Statement, query string, args []interface{}) (*models.QueryResponse, error) {
+	// Statements that return rows
+	if returnsRows(first) {
+		return executeSelectQuery(runner, query, args)
+	}
+
+	// Handle INSERT, UPDATE, DELETE, CREATE, DROP, ALTER
+	result, err := runner.ExecContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
 
 	affected, _ := result.RowsAffected()
 	return &models.QueryResponse{
@@ -39,21 +79,43 @@ func ExecuteQuery(dbName, query string, host string, port int, user, password st
 	}, nil
 }
 
+// checkReadOnly returns ErrForbidden unless every statement only reads
+func checkReadOnly(statements []sqlstmt.Statement) error {
+	for _, stmt := range statements {
+		if !readOnlyAllowed(stmt) {
+			return fmt.Errorf("%w: %s statements are not allowed on a read-only connection", ErrForbidden, stmt.Kind)
+		}
+	}
+	return nil
+}
+
+// readOnlyAllowed reports whether a statement may run on a read-only connection.
+// Besides reads only USE is allowed; session statements could undo the server
+// side read-only mode.
+func readOnlyAllowed(stmt sqlstmt.Statement) bool {
+	return stmt.Category == sqlstmt.Read || stmt.Kind == "USE"
+}
+
+// returnsRows reports whether a statement produces a result set
+func returnsRows(stmt sqlstmt.Statement) bool {
+	switch stmt.Keyword {
+	case "EXPLAIN", "DESCRIBE", "DESC":
+		return true
+	}
+	return stmt.Category == sqlstmt.Read
+}
+
 // executeSelectQuery executes a SELECT query and returns rows
-func executeSelectQuery(dbConn *sql.DB, query string) (*models.QueryResponse, error) {
-	rows, err := dbConn.Query(query)
+func executeSelectQuery(runner queryRunner, query string, args []interface{}) (*models.QueryResponse, error) {
+	rows, err := runner.QueryContext(context.Background(), query, args...)
 	if err != nil {
-		return &models.QueryResponse{
-			Error: err.Error(),
-		}, nil
+		return nil, err
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return &models.QueryResponse{
-			Error: err.Error(),
-		}, nil
+		return nil, err
 	}
 
 	var resultRows [][]interface{}
@@ -65,9 +127,7 @@ func executeSelectQuery(dbConn *sql.DB, query string) (*models.QueryResponse, er
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return &models.QueryResponse{
-				Error: err.Error(),
-			}, nil
+			return nil, err
 		}
 
 		// Convert []byte to string for JSON serialization
@@ -90,9 +150,14 @@ func executeSelectQuery(dbConn *sql.DB, query string) (*models.QueryResponse, er
 	}, nil
 }
 
-// CreateTable creates a new table
-func CreateTable(dbName, tableName, createSQL string, host string, port int, user, password string) error {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+// CreateTable creates a new table. createSQL must be a single CREATE TABLE
+// statement.
+func CreateTable(dbName, tableName, createSQL string, cfg database.Config) error {
+	if statements := sqlstmt.Parse(createSQL); len(statements) != 1 || statements[0].Kind != "CREATE TABLE" {
+		return fmt.Errorf("%w: createSQL must be a single CREATE TABLE statement", ErrInvalidInput)
+	}
+
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
 	if err != nil {
 		return err
 	}
@@ -103,15 +168,19 @@ func CreateTable(dbName, tableName, createSQL string, host string, port int, use
 }
 
 // DropTable drops a table
-func DropTable(dbName, tableName string, host string, port int, user, password string) error {
-	dbConn, err := database.ConnectToDatabase(dbName, host, port, user, password)
+func DropTable(dbName, tableName string, cfg database.Config) error {
+	dbConn, err := database.ConnectToDatabase(dbName, cfg)
 	if err != nil {
 		return err
 	}
 	defer dbConn.Close()
 
-	query := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", dbName, tableName)
-	_, err = dbConn.Exec(query)
+	_, err = dbConn.Exec(DropTableStatement(dbName, tableName))
 	return err
 }
 
+// DropTableStatement returns the statement DropTable runs
+func DropTableStatement(dbName, tableName string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s.%s", quoteIdentifier(dbName), quoteIdentifier(tableName))
+}
+
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"mysql-admin-tool/internal/sqlstmt"
+	"reflect"
+	"testing"
+)
+
+func TestDropTableStatement(t *testing.T) {
+	// A backtick in the table name cannot reach another database
+	statements := sqlstmt.Parse(DropTableStatement("shop", "t`, `secret`.`users"))
+	if len(statements) != 1 {
+		t.Fatalf("DropTableStatement parsed into %d statements, want 1", len(statements))
+	}
+	want := []sqlstmt.Table{{Schema: "shop", Name: "t`, `secret`.`users"}}
+	if got := statements[0]; !reflect.DeepEqual(got.Schemas, []string{"shop"}) || !reflect.DeepEqual(got.Tables, want) {
+		t.Errorf("DropTableStatement schemas %q tables %v, want [shop] %v", got.Schemas, got.Tables, want)
+	}
+}
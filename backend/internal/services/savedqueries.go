@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	savedQueriesMu   sync.RWMutex
+	savedQueriesPath string
+	savedQueries     = make(map[string]*models.SavedQuery)
+)
+
+// InitSavedQueries loads the saved query store
+func InitSavedQueries(path string) error {
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+
+	savedQueriesPath = path
+	savedQueries = make(map[string]*models.SavedQuery)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored []*models.SavedQuery
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse saved query store %s: %w", path, err)
+	}
+	for _, q := range stored {
+		savedQueries[q.ID] = q
+	}
+	return nil
+}
+
+// ListSavedQueries returns the saved queries a session owns or that are shared
+// with it, sorted by name. Non-empty tag and text narrow the list to queries with
+// the tag and to names, descriptions or SQL containing text.
+func ListSavedQueries(session *Session, tag, text string) []models.SavedQuery {
+	savedQueriesMu.RLock()
+	defer savedQueriesMu.RUnlock()
+
+	text = strings.ToLower(text)
+	result := make([]models.SavedQuery, 0)
+	for _, q := range savedQueries {
+		if !savedQueryVisible(session, q) {
+			continue
+		}
+		if tag != "" && !containsFold(q.Tags, tag) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(q.Name+"\n"+q.Description+"\n"+q.Query), text) {
+			continue
+		}
+		result = append(result, viewSavedQuery(session, q))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// GetSavedQuery returns a saved query the session owns or that is shared with it
+func GetSavedQuery(session *Session, id string) (*models.SavedQuery, error) {
+	savedQueriesMu.RLock()
+	defer savedQueriesMu.RUnlock()
+
+	q, ok := savedQueries[id]
+	if !ok || !savedQueryVisible(session, q) {
+		return nil, fmt.Errorf("%w: saved query %s", ErrNotFound, id)
+	}
+	view := viewSavedQuery(session, q)
+	return &view, nil
+}
+
+// CreateSavedQuery saves a new query owned by the session's user
+func CreateSavedQuery(session *Session, req models.SavedQueryRequest) (*models.SavedQuery, error) {
+	if err := validateSavedQueryRequest(&req); err != nil {
+		return nil, err
+	}
+
+	id, err := newProfileID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	q := &models.SavedQuery{ID: id, Owner: session.Owner(), CreatedAt: now}
+	applySavedQueryRequest(q, req)
+	q.UpdatedAt = now
+
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+
+	savedQueries[id] = q
+	if err := saveSavedQueries(); err != nil {
+		delete(savedQueries, id)
+		return nil, err
+	}
+	view := viewSavedQuery(session, q)
+	return &view, nil
+}
+
+// UpdateSavedQuery replaces a saved query. Only its owner may change it.
+func UpdateSavedQuery(session *Session, id string, req models.SavedQueryRequest) (*models.SavedQuery, error) {
+	if err := validateSavedQueryRequest(&req); err != nil {
+		return nil, err
+	}
+
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+
+	q, err := ownedSavedQuery(session, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *q
+	applySavedQueryRequest(q, req)
+	q.UpdatedAt = time.Now().UTC()
+	if err := saveSavedQueries(); err != nil {
+		*q = previous
+		return nil, err
+	}
+	view := viewSavedQuery(session, q)
+	return &view, nil
+}
+
+// DeleteSavedQuery deletes a saved query. Only its owner may delete it.
+func DeleteSavedQuery(session *Session, id string) error {
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+
+	q, err := ownedSavedQuery(session, id)
+	if err != nil {
+		return err
+	}
+
+	delete(savedQueries, id)
+	if err := saveSavedQueries(); err != nil {
+		savedQueries[id] = q
+		return err
+	}
+	return nil
+}
+
+// BindSavedQuery replaces the placeholders of a saved query with ? and returns the
+// values to bind in their order. Every placeholder needs a value.
+func BindSavedQuery(q *models.SavedQuery, values map[string]models.QueryParam) (string, []interface{}, error) {
+	query, names := sqlstmt.BindNamed(q.Query)
+	args, err := bindNamedValues(names, values)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// savedQueryVisible reports whether a session owns a saved query or it is shared
+// with the session's user or one of their roles
+func savedQueryVisible(session *Session, q *models.SavedQuery) bool {
+	owner := session.Owner()
+	if q.Owner == owner || q.SharedWith.Everyone {
+		return true
+	}
+	for _, user := range q.SharedWith.Users {
+		if sharedWithUser(owner, user) {
+			return true
+		}
+	}
+	if session.User != nil {
+		for _, role := range session.User.Roles {
+			if containsFold(q.SharedWith.Roles, role) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharedWithUser reports whether a user entry of a saved query's sharing names
+// the session's user: a local account by its username, an OpenID Connect user
+// as oidc:issuer#subject, or a MySQL account as user@host:port or
+// user@unix:socket, whose host is not case-sensitive
+func sharedWithUser(owner, user string) bool {
+	if strings.HasPrefix(user, "oidc:") {
+		return owner == user
+	}
+	if i := strings.LastIndex(user, "@"); i >= 0 && !strings.HasPrefix(user[i+1:], "unix:") {
+		user = user[:i] + "@" + strings.ToLower(user[i+1:])
+	}
+	return owner == "app:"+user || owner == "mysql:"+user
+}
+
+// ownedSavedQuery returns a saved query the session may change: ErrNotFound when
+// the session cannot see it, ErrForbidden when it is only shared with it. The
+// caller holds savedQueriesMu.
+func ownedSavedQuery(session *Session, id string) (*models.SavedQuery, error) {
+	q, ok := savedQueries[id]
+	if !ok || !savedQueryVisible(session, q) {
+		return nil, fmt.Errorf("%w: saved query %s", ErrNotFound, id)
+	}
+	if q.Owner != session.Owner() {
+		return nil, fmt.Errorf("%w: only the owner can change saved query %s", ErrForbidden, id)
+	}
+	return q, nil
+}
+
+// viewSavedQuery copies a saved query for a session
+func viewSavedQuery(session *Session, q *models.SavedQuery) models.SavedQuery {
+	view := *q
+	view.Owned = q.Owner == session.Owner()
+	return view
+}
+
+// validateSavedQueryRequest checks and normalizes a saved query request
+func validateSavedQueryRequest(req *models.SavedQueryRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("%w: saved query name is required", ErrInvalidInput)
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if len(sqlstmt.Parse(req.Query)) == 0 {
+		return fmt.Errorf("%w: saved query contains no statement", ErrInvalidInput)
+	}
+	if len(sqlstmt.Parameters(req.Query)) > 0 && len(sqlstmt.Parse(req.Query)) > 1 {
+		return fmt.Errorf("%w: parameters can only be used in a single statement", ErrInvalidInput)
+	}
+	req.Tags = trimUnique(req.Tags)
+	req.SharedWith.Users = trimUnique(req.SharedWith.Users)
+	req.SharedWith.Roles = trimUnique(req.SharedWith.Roles)
+	return nil
+}
+
+// applySavedQueryRequest copies the fields of a request to a saved query
+func applySavedQueryRequest(q *models.SavedQuery, req models.SavedQueryRequest) {
+	q.Name = req.Name
+	q.Description = strings.TrimSpace(req.Description)
+	q.Tags = req.Tags
+	q.Query = req.Query
+	q.Database = strings.TrimSpace(req.Database)
+	q.Parameters = sqlstmt.Parameters(req.Query)
+	q.SharedWith = req.SharedWith
+}
+
+// saveSavedQueries writes the store. The caller holds savedQueriesMu.
+func saveSavedQueries() error {
+	stored := make([]*models.SavedQuery, 0, len(savedQueries))
+	for _, q := range savedQueries {
+		stored = append(stored, q)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(savedQueriesPath), 0o700); err != nil {
+		return err
+	}
+	tmp := savedQueriesPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, savedQueriesPath)
+}
+
+// trimUnique trims values and drops empty and duplicate ones, ignoring case
+func trimUnique(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !containsFold(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values contains v, ignoring case
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
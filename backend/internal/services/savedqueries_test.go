@@ -0,0 +1,27 @@
+package services
+
+import "testing"
+
+func TestSharedWithUser(t *testing.T) {
+	tests := []struct {
+		owner string
+		user  string
+		want  bool
+	}{
+		{"app:alice", "alice", true},
+		{"app:alice", "bob", false},
+		{"oidc:https://sso.example.com#248289761001", "oidc:https://sso.example.com#248289761001", true},
+		// The same subject from another issuer is another person
+		{"oidc:https://other.example.com#248289761001", "oidc:https://sso.example.com#248289761001", false},
+		{"oidc:https://sso.example.com#248289761001", "oidc:248289761001", false},
+		{"mysql:root@db.internal:3306", "root@DB.internal:3306", true},
+		{"mysql:Root@db.internal:3306", "root@db.internal:3306", false},
+		{"mysql:root@unix:/run/mysqld/mysqld.sock", "root@unix:/run/mysqld/mysqld.sock", true},
+		{"mysql:root@unix:/run/mysqld/mysqld.sock", "root@unix:/run/MySQLd/mysqld.sock", false},
+	}
+	for _, tt := range tests {
+		if got := sharedWithUser(tt.owner, tt.user); got != tt.want {
+			t.Errorf("sharedWithUser(%q, %q) = %v, want %v", tt.owner, tt.user, got, tt.want)
+		}
+	}
+}
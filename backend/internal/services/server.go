@@ -0,0 +1,284 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrReadOnlyVariable is returned when attempting to SET GLOBAL a non-dynamic variable
+var ErrReadOnlyVariable = errors.New("variable is read-only and cannot be changed at runtime")
+
+const (
+	// metricsHistorySize is the number of samples kept per server
+	metricsHistorySize = 120
+	// minSampleInterval is the minimum time between two recorded samples
+	minSampleInterval = time.Second
+)
+
+// metricsSample is a raw GLOBAL STATUS snapshot
+type metricsSample struct {
+	at     time.Time
+	status map[string]int64
+}
+
+// metricsHistory holds the last raw sample and the computed history of a server
+type metricsHistory struct {
+	last    *metricsSample
+	current models.ServerMetrics
+	history []models.ServerMetrics
+}
+
+var (
+	metricsMu       sync.Mutex
+	metricsByServer = make(map[string]*metricsHistory)
+)
+
+// GetServerInfo returns version, uptime and identity information about the server
+func GetServerInfo(cfg database.Config) (*models.ServerInfo, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	info := &models.ServerInfo{}
+	err = dbConn.QueryRow("SELECT VERSION(), @@version_comment, @@hostname, @@port, CURRENT_USER()").
+		Scan(&info.Version, &info.VersionComment, &info.Hostname, &info.Port, &info.CurrentUser)
+	if err != nil {
+		return nil, err
+	}
+
+	var name, uptime string
+	if err := dbConn.QueryRow("SHOW GLOBAL STATUS LIKE 'Uptime'").Scan(&name, &uptime); err != nil {
+		return nil, err
+	}
+	info.UptimeSeconds, _ = strconv.ParseInt(uptime, 10, 64)
+	info.StartedAt = time.Now().Add(-time.Duration(info.UptimeSeconds) * time.Second).UTC().Format(time.RFC3339)
+
+	return info, nil
+}
+
+// GetGlobalStatus returns SHOW GLOBAL STATUS, optionally filtered by a name substring
+func GetGlobalStatus(search string, cfg database.Config) ([]models.ServerVariable, error) {
+	return showVariables("SHOW GLOBAL STATUS", search, cfg)
+}
+
+// GetVariables returns SHOW GLOBAL VARIABLES, optionally filtered by a name substring
+func GetVariables(search string, cfg database.Config) ([]models.ServerVariable, error) {
+	return showVariables("SHOW GLOBAL VARIABLES", search, cfg)
+}
+
+// showVariables runs a SHOW ... STATUS/VARIABLES statement with an optional LIKE filter
+func showVariables(statement, search string, cfg database.Config) ([]models.ServerVariable, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	var rows *sql.Rows
+	if search != "" {
+		rows, err = dbConn.Query(statement+" LIKE ?", "%"+escapeLike(search)+"%")
+	} else {
+		rows, err = dbConn.Query(statement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variables := []models.ServerVariable{}
+	for rows.Next() {
+		var v models.ServerVariable
+		var value sql.NullString
+		if err := rows.Scan(&v.Name, &value); err != nil {
+			continue
+		}
+		v.Value = value.String
+		variables = append(variables, v)
+	}
+
+	return variables, rows.Err()
+}
+
+// numericValuePattern matches numeric variable values that must not be quoted
+var numericValuePattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// SetGlobalVariable changes a dynamic global system variable with SET GLOBAL
+func SetGlobalVariable(name, value string, cfg database.Config) (*models.ServerVariable, error) {
+	if !variableNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: invalid variable name %q", ErrInvalidInput, name)
+	}
+
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	var current models.ServerVariable
+	err = dbConn.QueryRow("SHOW GLOBAL VARIABLES LIKE ?", escapeLike(name)).Scan(&current.Name, &current.Value)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: variable %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Every new connection would inherit the quoting rules
+	if strings.EqualFold(current.Name, "sql_mode") {
+		if err := database.CheckSQLMode(value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+
+	if _, err := dbConn.Exec(SetGlobalStatement(current.Name, value)); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1238 {
+			return nil, fmt.Errorf("%w: %s", ErrReadOnlyVariable, current.Name)
+		}
+		return nil, err
+	}
+
+	var updated models.ServerVariable
+	if err := dbConn.QueryRow("SHOW GLOBAL VARIABLES LIKE ?", escapeLike(current.Name)).Scan(&updated.Name, &updated.Value); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// SetGlobalStatement returns the statement SetGlobalVariable runs for a variable
+// name checked against the server
+func SetGlobalStatement(name, value string) string {
+	var expr string
+	switch upper := strings.ToUpper(value); {
+	case numericValuePattern.MatchString(value):
+		expr = value
+	case upper == "ON" || upper == "OFF" || upper == "DEFAULT" || upper == "TRUE" || upper == "FALSE":
+		expr = upper
+	default:
+		expr = quoteString(value)
+	}
+	return fmt.Sprintf("SET GLOBAL %s = %s", name, expr)
+}
+
+// GetServerMetrics samples GLOBAL STATUS and returns metrics computed against the
+// previous sample of the same server, together with the recorded history
+func GetServerMetrics(cfg database.Config) (*models.ServerMetricsResponse, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query("SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sample := &metricsSample{at: time.Now(), status: make(map[string]int64)}
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			continue
+		}
+		if n, err := strconv.ParseInt(value.String, 10, 64); err == nil {
+			sample.status[name] = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var maxConnections int64
+	if err := dbConn.QueryRow("SELECT @@max_connections").Scan(&maxConnections); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s@%s:%d", cfg.User, cfg.Host, cfg.Port)
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	h, ok := metricsByServer[key]
+	if !ok {
+		h = &metricsHistory{}
+		metricsByServer[key] = h
+	}
+
+	if h.last == nil || sample.at.Sub(h.last.at) >= minSampleInterval {
+		h.current = computeMetrics(h.last, sample, maxConnections)
+		h.last = sample
+		h.history = append(h.history, h.current)
+		if len(h.history) > metricsHistorySize {
+			h.history = h.history[len(h.history)-metricsHistorySize:]
+		}
+	}
+
+	history := make([]models.ServerMetrics, len(h.history))
+	copy(history, h.history)
+
+	return &models.ServerMetricsResponse{
+		Current: h.current,
+		History: history,
+	}, nil
+}
+
+// computeMetrics derives rates from the difference between two samples; without a
+// usable previous sample the averages since server startup are returned
+func computeMetrics(prev, cur *metricsSample, maxConnections int64) models.ServerMetrics {
+	delta := func(name string) float64 {
+		if prev == nil {
+			return float64(cur.status[name])
+		}
+		return float64(cur.status[name] - prev.status[name])
+	}
+
+	interval := float64(cur.status["Uptime"])
+	if prev != nil && cur.status["Uptime"] >= prev.status["Uptime"] {
+		interval = cur.at.Sub(prev.at).Seconds()
+	} else {
+		// First sample or server restart: fall back to averages since startup
+		prev = nil
+	}
+	if interval <= 0 {
+		interval = 1
+	}
+
+	m := models.ServerMetrics{
+		Timestamp:           cur.at.UTC(),
+		IntervalSeconds:     interval,
+		QPS:                 delta("Questions") / interval,
+		SelectsPerSec:       delta("Com_select") / interval,
+		WritesPerSec:        (delta("Com_insert") + delta("Com_insert_select") + delta("Com_update") + delta("Com_update_multi") + delta("Com_delete") + delta("Com_delete_multi") + delta("Com_replace") + delta("Com_replace_select")) / interval,
+		SlowQueries:         cur.status["Slow_queries"],
+		BufferPoolHitRatio:  100,
+		ConnectionsInUse:    cur.status["Threads_connected"],
+		ThreadsRunning:      cur.status["Threads_running"],
+		MaxConnections:      maxConnections,
+		MaxUsedConnections:  cur.status["Max_used_connections"],
+		BytesReceivedPerSec: delta("Bytes_received") / interval,
+		BytesSentPerSec:     delta("Bytes_sent") / interval,
+	}
+
+	if requests := delta("Innodb_buffer_pool_read_requests"); requests > 0 {
+		m.BufferPoolHitRatio = (requests - delta("Innodb_buffer_pool_reads")) / requests * 100
+	}
+	if maxConnections > 0 {
+		m.ConnectionUsage = float64(m.ConnectionsInUse) / float64(maxConnections) * 100
+	}
+
+	return m
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"sync"
+	"time"
+)
+
+const (
+	// PrimaryConnectionID identifies the connection established at login
+	PrimaryConnectionID = "default"
+	// maxSessionConnections limits the number of connections a session may hold
+	maxSessionConnections = 16
+)
+
+// SessionConnection is a named MySQL connection held by a session
+type SessionConnection struct {
+	ID        string
+	Name      string
+	Profile   string
+	Color     string
+	Tag       string
+	Config    database.Config
+	CreatedAt time.Time
+}
+
+// Session holds the MySQL connections of one application login
+type Session struct {
+	ID        string
+	ExpiresAt time.Time
+	// User is the application account of the session, nil for MySQL logins
+	User *AppUser
+
+	mu          sync.RWMutex
+	connections map[string]*SessionConnection
+	order       []string
+}
+
+// loginSettings are the TLS, SSH and driver settings of a login without a
+// profile, kept until the session's token expires
+type loginSettings struct {
+	tls       models.TLSOptions
+	ssh       *models.SSHTunnelOptions
+	options   models.ConnectionOptions
+	expiresAt time.Time
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]*Session)
+	// settings holds the login settings of tokens by session ID
+	settings = make(map[string]*loginSettings)
+)
+
+// storeLoginSettings keeps the TLS, SSH and driver settings of a login for the
+// session of its token
+func storeLoginSettings(sessionID string, config database.Config, expiresAt time.Time) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	settings[sessionID] = &loginSettings{
+		tls:       config.TLS,
+		ssh:       config.SSH,
+		options:   config.Options,
+		expiresAt: expiresAt,
+	}
+}
+
+// SessionFromClaims returns the session of a validated token, recreating it with
+// the login connection when the server has restarted since the token was issued
+func SessionFromClaims(tokenString string, claims *Claims) (*Session, error) {
+	id := claims.ID
+	if id == "" {
+		// Tokens issued before sessions existed are identified by their hash
+		sum := sha256.Sum256([]byte(tokenString))
+		id = hex.EncodeToString(sum[:16])
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	now := time.Now()
+	for key, s := range sessions {
+		if now.After(s.ExpiresAt) {
+			delete(sessions, key)
+		}
+	}
+	for key, ls := range settings {
+		if now.After(ls.expiresAt) {
+			delete(settings, key)
+		}
+	}
+
+	if s, ok := sessions[id]; ok {
+		return s, nil
+	}
+
+	config := database.Config{
+		Host:     claims.DBHost,
+		Port:     claims.DBPort,
+		User:     claims.DBUser,
+		Password: claims.DBPassword,
+		Database: claims.DBDatabase,
+		Socket:   claims.DBSocket,
+		ReadOnly: claims.ReadOnly,
+	}
+	if claims.Settings {
+		ls, ok := settings[id]
+		if !ok {
+			// Lost with a server restart
+			return nil, fmt.Errorf("%w: the connection settings of this login are gone, please log in again", ErrInvalidCredentials)
+		}
+		config.TLS = ls.tls
+		config.SSH = ls.ssh
+		config.Options = ls.options
+	}
+	primary := &SessionConnection{
+		ID:        PrimaryConnectionID,
+		Profile:   claims.Profile,
+		CreatedAt: now,
+	}
+
+	// The profile decides where a profile login connects, whatever the token
+	// says. Tokens issued for a profile's stored password carry no password.
+	user := appUserFromClaims(claims)
+	if claims.Profile != "" {
+		resolved, storedPassword, err := ResolveLogin(models.LoginRequest{
+			Profile:  claims.Profile,
+			Username: claims.DBUser,
+			Password: claims.DBPassword,
+			ReadOnly: claims.ReadOnly,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			if _, ok := user.CanUseProfile(claims.Profile); !ok {
+				return nil, fmt.Errorf("%w: profile %s is not granted to %s", ErrForbidden, claims.Profile, user.Username)
+			}
+		} else if storedPassword && !StoredPasswordLoginAllowed() {
+			return nil, fmt.Errorf("%w: the stored password of profile %s is only available to application accounts", ErrForbidden, claims.Profile)
+		}
+		config = resolved
+		if profile, err := ResolveProfile(claims.Profile); err == nil {
+			primary.Name = profile.Name
+			primary.Color = profile.Color
+			primary.Tag = profile.Tag
+		}
+	}
+	if primary.Name == "" {
+		primary.Name = defaultConnectionName(config)
+	}
+	primary.Config = config
+
+	expiresAt := now.Add(24 * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	s := &Session{
+		ID:          id,
+		ExpiresAt:   expiresAt,
+		User:        user,
+		connections: map[string]*SessionConnection{PrimaryConnectionID: primary},
+		order:       []string{PrimaryConnectionID},
+	}
+	sessions[id] = s
+	return s, nil
+}
+
+// Connection returns a connection of the session by ID
+func (s *Session) Connection(id string) (*SessionConnection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conn, ok := s.connections[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: connection %s", ErrNotFound, id)
+	}
+	return conn, nil
+}
+
+// Owner identifies the person behind the session across logins: the local
+// application account, the issuer and subject of an OpenID Connect user, or the
+// MySQL account of the login connection
+func (s *Session) Owner() string {
+	if s.User != nil && s.User.Method == AuthMethodOIDC {
+		return "oidc:" + s.User.Issuer + "#" + s.User.Subject
+	}
+	if s.User != nil {
+		return "app:" + s.User.Username
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return "mysql:" + LoginAccount(s.connections[PrimaryConnectionID].Config)
+}
+
+// Connections returns the connections of the session in the order they were opened
+func (s *Session) Connections() []models.SessionConnection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.SessionConnection, 0, len(s.order))
+	for _, id := range s.order {
+		result = append(result, s.connections[id].info())
+	}
+	return result
+}
+
+// AddConnection validates and adds a new named connection to the session
+func (s *Session) AddConnection(req models.OpenConnectionRequest) (*models.SessionConnection, error) {
+	// Application users connect through the profiles granted to their roles
+	if s.User != nil {
+		id, ok := s.User.CanUseProfile(req.Profile)
+		if !ok {
+			return nil, fmt.Errorf("%w: profile %s is not granted to %s", ErrForbidden, req.Profile, s.User.Username)
+		}
+		req.LoginRequest = models.LoginRequest{Profile: id, ReadOnly: req.ReadOnly}
+	}
+
+	config, storedPassword, err := ResolveLogin(req.LoginRequest)
+	if err != nil {
+		return nil, err
+	}
+	if s.User == nil && storedPassword && !StoredPasswordLoginAllowed() {
+		return nil, fmt.Errorf("%w: the stored password of profile %s is only available to application accounts", ErrForbidden, req.Profile)
+	}
+
+	if err := s.checkConnectionCount(); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateMySQLConnection(config); err != nil {
+		return nil, err
+	}
+
+	id, err := newConnectionID()
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &SessionConnection{
+		ID:        id,
+		Name:      req.Name,
+		Profile:   req.Profile,
+		Config:    config,
+		CreatedAt: time.Now(),
+	}
+	if req.Profile != "" {
+		if profile, err := ResolveProfile(req.Profile); err == nil {
+			if conn.Name == "" {
+				conn.Name = profile.Name
+			}
+			conn.Color = profile.Color
+			conn.Tag = profile.Tag
+		}
+	}
+	if conn.Name == "" {
+		conn.Name = defaultConnectionName(config)
+	}
+
+	// Checked again under the write lock, concurrent opens may have filled the
+	// session while this one was validated
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.connections) >= maxSessionConnections {
+		return nil, fmt.Errorf("%w: a session may hold at most %d connections", ErrInvalidInput, maxSessionConnections)
+	}
+	s.connections[id] = conn
+	s.order = append(s.order, id)
+
+	info := conn.info()
+	return &info, nil
+}
+
+// RemoveConnection closes a connection of the session. The login connection cannot
+// be removed.
+func (s *Session) RemoveConnection(id string) error {
+	if id == PrimaryConnectionID {
+		return fmt.Errorf("%w: the login connection cannot be closed", ErrInvalidInput)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.connections[id]; !ok {
+		return fmt.Errorf("%w: connection %s", ErrNotFound, id)
+	}
+	delete(s.connections, id)
+	for i, existing := range s.order {
+		if existing == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// checkConnectionCount refuses new connections once the session holds the
+// maximum, before a connection is validated
+func (s *Session) checkConnectionCount() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.connections) >= maxSessionConnections {
+		return fmt.Errorf("%w: a session may hold at most %d connections", ErrInvalidInput, maxSessionConnections)
+	}
+	return nil
+}
+
+// info converts the connection into its API representation
+func (c *SessionConnection) info() models.SessionConnection {
+	return models.SessionConnection{
+		ID:        c.ID,
+		Name:      c.Name,
+		Host:      c.Config.Host,
+		Port:      c.Config.Port,
+		Socket:    c.Config.Socket,
+		Username:  c.Config.User,
+		Database:  c.Config.Database,
+		Profile:   c.Profile,
+		Color:     c.Color,
+		Tag:       c.Tag,
+		ReadOnly:  c.Config.ReadOnly,
+		Primary:   c.ID == PrimaryConnectionID,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// defaultConnectionName names a connection after its account and address
+func defaultConnectionName(config database.Config) string {
+	if config.Socket != "" {
+		return fmt.Sprintf("%s@%s", config.User, config.Socket)
+	}
+	return fmt.Sprintf("%s@%s:%d", config.User, config.Host, config.Port)
+}
+
+// newSessionID returns a random session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newConnectionID returns a random connection identifier
+func newConnectionID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// withProfile stores a profile with a password in a temporary store
+func withProfile(t *testing.T, req models.ProfileRequest) *models.ConnectionProfile {
+	t.Helper()
+	if err := InitProfiles(filepath.Join(t.TempDir(), "profiles.enc"), "test key"); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := CreateProfile(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+func TestSessionFromClaimsProfile(t *testing.T) {
+	profile := withProfile(t, models.ProfileRequest{
+		Name:     "Production",
+		Host:     "db.internal",
+		Port:     3307,
+		Username: "app",
+		Password: "stored",
+	})
+	InitIdentity(config.IdentityConfig{
+		Roles: []config.RoleConfig{{Name: "engineers", Access: "editor", Profiles: []string{"Production"}}},
+	})
+	t.Cleanup(func() { InitIdentity(config.Default().Identity) })
+
+	tests := []struct {
+		name     string
+		claims   Claims
+		wantHost string
+		wantErr  error
+	}{
+		{
+			name:     "takes the address from the profile",
+			claims:   Claims{DBHost: "attacker.example.com", DBPort: 3306, DBUser: "root", Profile: profile.ID, AppUser: "alice", AppRoles: []string{"engineers"}},
+			wantHost: "db.internal",
+		},
+		{
+			name:    "re-checks the role grants",
+			claims:  Claims{Profile: profile.ID, AppUser: "bob", AppRoles: []string{"analysts"}},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "refuses the stored password to MySQL logins",
+			claims:  Claims{DBHost: "attacker.example.com", Profile: profile.ID},
+			wantErr: ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tt.claims
+			claims.RegisteredClaims = jwt.RegisteredClaims{ID: tt.name}
+			session, err := SessionFromClaims("", &claims)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SessionFromClaims() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SessionFromClaims: %v", err)
+			}
+			conn, _ := session.Connection(PrimaryConnectionID)
+			if conn.Config.Host != tt.wantHost || conn.Config.Port != 3307 || conn.Config.User != "app" || conn.Config.Password != "stored" {
+				t.Fatalf("connection = %s@%s:%d, want app@%s:3307 with the stored password", conn.Config.User, conn.Config.Host, conn.Config.Port, tt.wantHost)
+			}
+		})
+	}
+}
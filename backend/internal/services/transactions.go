@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"mysql-admin-tool/internal/sqlstmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// transactionCheckInterval is how often idle transactions are looked for
+const transactionCheckInterval = 15 * time.Second
+
+// isolationLevels maps the isolation levels of begin requests to the driver's
+var isolationLevels = map[string]sql.IsolationLevel{
+	"READ UNCOMMITTED": sql.LevelReadUncommitted,
+	"READ COMMITTED":   sql.LevelReadCommitted,
+	"REPEATABLE READ":  sql.LevelRepeatableRead,
+	"SERIALIZABLE":     sql.LevelSerializable,
+}
+
+// openTransaction is a transaction held open across requests on a dedicated
+// connection of a session connection
+type openTransaction struct {
+	sessionID string
+	readOnly  bool
+	conn      *pinnedConn
+	tx        *sql.Tx
+
+	// mu serializes the statements of the transaction and its end
+	mu   sync.Mutex
+	done bool
+	// info is guarded by transactionsMu
+	info models.Transaction
+}
+
+var (
+	transactionsMu     sync.Mutex
+	transactionConfig  = config.Default().Transactions
+	transactions       = make(map[string]*openTransaction)
+	transactionJanitor sync.Once
+)
+
+// InitTransactions sets the idle timeout and limit of open transactions and
+// starts rolling back idle ones
+func InitTransactions(cfg config.TransactionConfig) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	transactionConfig = cfg
+	transactionJanitor.Do(func() { go rollbackIdleTransactions() })
+}
+
+// BeginTransaction begins a transaction in a database on a dedicated connection
+// of a session connection. Its statements run through ExecuteInTransaction until
+// it is committed, rolled back or idle for too long.
+func BeginTransaction(sessionID string, conn *SessionConnection, req models.BeginTransactionRequest) (*models.Transaction, error) {
+	if req.Database == "" {
+		return nil, fmt.Errorf("%w: database name is required", ErrInvalidInput)
+	}
+	isolation := strings.Join(strings.Fields(strings.ToUpper(strings.ReplaceAll(req.Isolation, "_", " "))), " ")
+	opts := &sql.TxOptions{ReadOnly: req.ReadOnly}
+	if isolation != "" {
+		level, ok := isolationLevels[isolation]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown isolation level %q", ErrInvalidInput, req.Isolation)
+		}
+		opts.Isolation = level
+	}
+
+	transactionsMu.Lock()
+	err := checkTransactionLimits(sessionID, conn.ID)
+	transactionsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newTransactionID()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := openPinnedConn(req.Database, conn.Config)
+	if err != nil {
+		return nil, err
+	}
+	// The transaction ends when the context of BeginTx is canceled, so none is
+	// used here
+	tx, err := pinned.conn.BeginTx(context.Background(), opts)
+	if err != nil {
+		pinned.close()
+		return nil, err
+	}
+
+	now := time.Now()
+	t := &openTransaction{
+		sessionID: sessionID,
+		readOnly:  conn.Config.ReadOnly,
+		conn:      pinned,
+		tx:        tx,
+		info: models.Transaction{
+			ID:         id,
+			Connection: conn.ID,
+			Database:   req.Database,
+			Isolation:  isolation,
+			ReadOnly:   req.ReadOnly,
+			StartedAt:  now,
+			LastUsedAt: now,
+		},
+	}
+
+	// Checked again under the lock, concurrent begins may have reached the limits
+	// while this connection was opened
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	if err := checkTransactionLimits(sessionID, conn.ID); err != nil {
+		tx.Rollback()
+		pinned.close()
+		return nil, err
+	}
+	transactions[id] = t
+	return t.view(), nil
+}
+
+// checkTransactionLimits refuses another transaction on a session connection
+// once it, or the server as a whole, holds the most allowed. The caller holds
+// transactionsMu.
+func checkTransactionLimits(sessionID, connectionID string) error {
+	if len(transactions) >= transactionConfig.MaxTotal {
+		return fmt.Errorf("%w: at most %d transactions may be open at once", ErrInvalidInput, transactionConfig.MaxTotal)
+	}
+	count, max := 0, transactionConfig.MaxPerConnection
+	for _, t := range transactions {
+		if t.sessionID == sessionID && t.info.Connection == connectionID {
+			count++
+		}
+	}
+	if count >= max {
+		return fmt.Errorf("%w: a connection may hold at most %d open transactions", ErrInvalidInput, max)
+	}
+	return nil
+}
+
+// ExecuteInTransaction runs a query in an open transaction of a session
+// connection. Statements that end the transaction, implicitly or explicitly, are
+// refused; use CommitTransaction and RollbackTransaction instead.
+func ExecuteInTransaction(sessionID, connectionID, id, dbName, query string, args []interface{}) (*models.QueryResponse, error) {
+	t, err := findTransaction(sessionID, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if dbName != t.info.Database {
+		return nil, fmt.Errorf("%w: transaction %s runs in database %s", ErrInvalidInput, id, t.info.Database)
+	}
+
+	query = strings.TrimSpace(query)
+	statements, err := parseQuery(query, args, t.readOnly)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransactionStatements(statements); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return nil, fmt.Errorf("%w: transaction %s", ErrNotFound, id)
+	}
+	t.touch(false)
+	result, err := runStatements(t.tx, statements[0], query, args)
+	t.touch(true)
+	if err == nil {
+		return result, nil
+	}
+
+	// MySQL rolls back the whole transaction on deadlocks, and a lost connection
+	// takes it along
+	if transactionLost(err) {
+		t.end(false)
+		return &models.QueryResponse{
+			Error: err.Error() + " (the transaction was rolled back)",
+		}, nil
+	}
+	return &models.QueryResponse{
+		Error: err.Error(),
+	}, nil
+}
+
+// CommitTransaction commits an open transaction of a session connection and
+// releases its connection
+func CommitTransaction(sessionID, connectionID, id string) (*models.Transaction, error) {
+	return finishTransaction(sessionID, connectionID, id, true)
+}
+
+// RollbackTransaction rolls back an open transaction of a session connection and
+// releases its connection
+func RollbackTransaction(sessionID, connectionID, id string) (*models.Transaction, error) {
+	return finishTransaction(sessionID, connectionID, id, false)
+}
+
+// ListTransactions returns the open transactions of a session connection, oldest
+// first
+func ListTransactions(sessionID, connectionID string) []models.Transaction {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	result := make([]models.Transaction, 0)
+	for _, t := range transactions {
+		if t.sessionID == sessionID && t.info.Connection == connectionID {
+			result = append(result, *t.view())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
+
+// RollbackConnectionTransactions rolls back the open transactions of a session
+// connection, e.g. when it is closed
+func RollbackConnectionTransactions(sessionID, connectionID string) {
+	for _, t := range ListTransactions(sessionID, connectionID) {
+		if _, err := RollbackTransaction(sessionID, connectionID, t.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Failed to roll back transaction %s: %v", t.ID, err)
+		}
+	}
+}
+
+// finishTransaction commits or rolls back an open transaction, waiting for a
+// running statement to complete
+func finishTransaction(sessionID, connectionID, id string, commit bool) (*models.Transaction, error) {
+	t, err := findTransaction(sessionID, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return nil, fmt.Errorf("%w: transaction %s", ErrNotFound, id)
+	}
+	transactionsMu.Lock()
+	info := t.view()
+	transactionsMu.Unlock()
+	return info, t.end(commit)
+}
+
+// findTransaction returns an open transaction of a session connection
+func findTransaction(sessionID, connectionID, id string) (*openTransaction, error) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	t, ok := transactions[id]
+	if !ok || t.sessionID != sessionID || t.info.Connection != connectionID {
+		return nil, fmt.Errorf("%w: transaction %s", ErrNotFound, id)
+	}
+	return t, nil
+}
+
+// checkTransactionStatements refuses statements that would end an open
+// transaction or change its database. Savepoints are allowed.
+func checkTransactionStatements(statements []sqlstmt.Statement) error {
+	for _, stmt := range statements {
+		switch {
+		case stmt.Kind == "SAVEPOINT" || stmt.Kind == "RELEASE" || rollbackToSavepoint(stmt):
+		case stmt.Category == sqlstmt.Transaction:
+			return fmt.Errorf("%w: %s statements cannot run in an open transaction, commit or roll it back instead", ErrInvalidInput, stmt.Kind)
+		case stmt.Category == sqlstmt.DDL || stmt.Category == sqlstmt.Admin:
+			return fmt.Errorf("%w: %s statements would implicitly commit the transaction", ErrInvalidInput, stmt.Kind)
+		case stmt.Kind == "USE":
+			return fmt.Errorf("%w: a transaction runs in the database it began in", ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// rollbackToSavepoint reports whether a statement is ROLLBACK [WORK] TO
+// [SAVEPOINT] name
+func rollbackToSavepoint(stmt sqlstmt.Statement) bool {
+	if stmt.Kind != "ROLLBACK" {
+		return false
+	}
+	words := strings.Fields(strings.ToUpper(stmt.Text))
+	if len(words) > 1 && words[1] == "WORK" {
+		words = words[1:]
+	}
+	return len(words) > 2 && words[1] == "TO"
+}
+
+// transactionLost reports whether a statement error ended the transaction on the
+// server
+func transactionLost(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 // ER_LOCK_DEADLOCK
+	}
+	return connectionLost(err)
+}
+
+// rollbackIdleTransactions periodically rolls back transactions that have not
+// been used for the idle timeout
+func rollbackIdleTransactions() {
+	ticker := time.NewTicker(transactionCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var idle []*openTransaction
+		transactionsMu.Lock()
+		timeout := transactionConfig.IdleTimeout
+		for _, t := range transactions {
+			if time.Since(t.info.LastUsedAt) > timeout {
+				idle = append(idle, t)
+			}
+		}
+		transactionsMu.Unlock()
+
+		for _, t := range idle {
+			// A transaction running a statement is not idle
+			if !t.mu.TryLock() {
+				continue
+			}
+			if !t.done {
+				if err := t.end(false); err != nil {
+					log.Printf("Failed to roll back idle transaction %s: %v", t.info.ID, err)
+				} else {
+					log.Printf("Rolled back transaction %s after %s idle", t.info.ID, timeout)
+				}
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// touch records the use of the transaction; statements counts one statement.
+// The caller holds t.mu.
+func (t *openTransaction) touch(statement bool) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	t.info.LastUsedAt = time.Now()
+	if statement {
+		t.info.Statements++
+	}
+}
+
+// end commits or rolls back the transaction and closes its connection. The
+// caller holds t.mu.
+func (t *openTransaction) end(commit bool) error {
+	var err error
+	if commit {
+		err = t.tx.Commit()
+	} else {
+		err = t.tx.Rollback()
+		if errors.Is(err, sql.ErrTxDone) {
+			err = nil
+		}
+	}
+	t.conn.close()
+	t.done = true
+
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	delete(transactions, t.info.ID)
+	return err
+}
+
+// view copies the transaction's information. The caller holds transactionsMu.
+func (t *openTransaction) view() *models.Transaction {
+	info := t.info
+	info.ExpiresAt = info.LastUsedAt.Add(transactionConfig.IdleTimeout)
+	return &info
+}
+
+// newTransactionID returns a random transaction identifier
+func newTransactionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"mysql-admin-tool/internal/config"
+	"mysql-admin-tool/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCheckTransactionLimits(t *testing.T) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	savedConfig, savedTransactions := transactionConfig, transactions
+	transactionConfig = config.TransactionConfig{IdleTimeout: time.Minute, MaxPerConnection: 2, MaxTotal: 3}
+	transactions = map[string]*openTransaction{
+		"a": {sessionID: "s1", info: models.Transaction{ID: "a", Connection: "primary"}},
+		"b": {sessionID: "s1", info: models.Transaction{ID: "b", Connection: "primary"}},
+	}
+	defer func() {
+		transactionConfig, transactions = savedConfig, savedTransactions
+	}()
+
+	if err := checkTransactionLimits("s1", "primary"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("full connection: error = %v, want ErrInvalidInput", err)
+	}
+	if err := checkTransactionLimits("s2", "primary"); err != nil {
+		t.Errorf("other session: error = %v, want nil", err)
+	}
+
+	transactions["c"] = &openTransaction{sessionID: "s2", info: models.Transaction{ID: "c", Connection: "primary"}}
+	if err := checkTransactionLimits("s3", "primary"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("full server: error = %v, want ErrInvalidInput", err)
+	}
+}
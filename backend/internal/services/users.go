@@ -0,0 +1,330 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"mysql-admin-tool/internal/database"
+	"mysql-admin-tool/internal/models"
+	"regexp"
+	"strings"
+)
+
+// staticPrivileges lists the privilege names accepted by GrantPrivileges and RevokePrivileges
+var staticPrivileges = map[string]bool{
+	"ALL": true, "ALL PRIVILEGES": true, "ALTER": true, "ALTER ROUTINE": true, "CREATE": true,
+	"CREATE ROLE": true, "CREATE ROUTINE": true, "CREATE TABLESPACE": true,
+	"CREATE TEMPORARY TABLES": true, "CREATE USER": true, "CREATE VIEW": true, "DELETE": true,
+	"DROP": true, "DROP ROLE": true, "EVENT": true, "EXECUTE": true, "FILE": true,
+	"GRANT OPTION": true, "INDEX": true, "INSERT": true, "LOCK TABLES": true, "PROCESS": true,
+	"REFERENCES": true, "RELOAD": true, "REPLICATION CLIENT": true, "REPLICATION SLAVE": true,
+	"SELECT": true, "SHOW DATABASES": true, "SHOW VIEW": true, "SHUTDOWN": true, "SUPER": true,
+	"TRIGGER": true, "UPDATE": true, "USAGE": true,
+}
+
+// globalOnlyPrivileges lists privileges that can only be granted ON *.*
+var globalOnlyPrivileges = map[string]bool{
+	"CREATE ROLE": true, "CREATE TABLESPACE": true, "CREATE USER": true, "DROP ROLE": true,
+	"FILE": true, "PROCESS": true, "RELOAD": true, "REPLICATION CLIENT": true,
+	"REPLICATION SLAVE": true, "SHOW DATABASES": true, "SHUTDOWN": true, "SUPER": true,
+}
+
+// columnPrivileges lists privileges that can be granted at column level
+var columnPrivileges = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "REFERENCES": true,
+}
+
+// dynamicPrivilegePattern matches MySQL 8.0 dynamic privileges such as BACKUP_ADMIN
+var dynamicPrivilegePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)+$`)
+
+// accountName renders a quoted 'user'@'host' account name
+func accountName(user, host string) string {
+	return quoteString(user) + "@" + quoteString(host)
+}
+
+// ListUsers returns all accounts from mysql.user
+func ListUsers(cfg database.Config) ([]models.MySQLUser, error) {
+	dbConn, err := database.ConnectToDatabase("mysql", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	// Older servers and MariaDB lack some of the account columns
+	columns := make(map[string]bool)
+	colRows, err := dbConn.Query("SELECT LOWER(COLUMN_NAME) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = 'mysql' AND TABLE_NAME = 'user'")
+	if err != nil {
+		return nil, err
+	}
+	for colRows.Next() {
+		var name string
+		if err := colRows.Scan(&name); err == nil {
+			columns[name] = true
+		}
+	}
+	colRows.Close()
+
+	column := func(name, fallback string) string {
+		if columns[name] {
+			return name
+		}
+		return fallback
+	}
+
+	query := fmt.Sprintf("SELECT User, Host, %s, %s, %s FROM mysql.user ORDER BY User, Host",
+		column("plugin", "''"),
+		column("account_locked", "'N'"),
+		column("password_expired", "'N'"),
+	)
+	rows, err := dbConn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.MySQLUser{}
+	for rows.Next() {
+		var u models.MySQLUser
+		var plugin, locked, expired sql.NullString
+		if err := rows.Scan(&u.User, &u.Host, &plugin, &locked, &expired); err != nil {
+			continue
+		}
+		u.Plugin = plugin.String
+		u.AccountLocked = locked.String == "Y"
+		u.PasswordExpired = expired.String == "Y"
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// CreateUser creates a MySQL account
+func CreateUser(req models.CreateUserRequest, cfg database.Config) error {
+	statement, err := CreateUserStatement(req)
+	if err != nil {
+		return err
+	}
+	return execAccountStatement(statement, cfg)
+}
+
+// CreateUserStatement returns the statement CreateUser runs
+func CreateUserStatement(req models.CreateUserRequest) (string, error) {
+	if req.User == "" {
+		return "", fmt.Errorf("%w: user name is required", ErrInvalidInput)
+	}
+	if req.Host == "" {
+		req.Host = "%"
+	}
+
+	statement := "CREATE USER " + accountName(req.User, req.Host)
+	if req.Password != "" {
+		statement += " IDENTIFIED BY " + quoteString(req.Password)
+	}
+	return statement, nil
+}
+
+// DropUser drops a MySQL account
+func DropUser(targetUser, targetHost string, cfg database.Config) error {
+	return execAccountStatement(DropUserStatement(targetUser, targetHost), cfg)
+}
+
+// DropUserStatement returns the statement DropUser runs
+func DropUserStatement(targetUser, targetHost string) string {
+	return "DROP USER " + accountName(targetUser, targetHost)
+}
+
+// RenameUser renames a MySQL account
+func RenameUser(targetUser, targetHost string, req models.RenameUserRequest, cfg database.Config) error {
+	statement, err := RenameUserStatement(targetUser, targetHost, req)
+	if err != nil {
+		return err
+	}
+	return execAccountStatement(statement, cfg)
+}
+
+// RenameUserStatement returns the statement RenameUser runs
+func RenameUserStatement(targetUser, targetHost string, req models.RenameUserRequest) (string, error) {
+	if req.User == "" {
+		return "", fmt.Errorf("%w: new user name is required", ErrInvalidInput)
+	}
+	if req.Host == "" {
+		req.Host = targetHost
+	}
+	return fmt.Sprintf("RENAME USER %s TO %s", accountName(targetUser, targetHost), accountName(req.User, req.Host)), nil
+}
+
+// ChangePassword sets a new password for a MySQL account
+func ChangePassword(targetUser, targetHost, newPassword string, cfg database.Config) error {
+	return execAccountStatement(ChangePasswordStatement(targetUser, targetHost, newPassword), cfg)
+}
+
+// ChangePasswordStatement returns the statement ChangePassword runs
+func ChangePasswordStatement(targetUser, targetHost, newPassword string) string {
+	return fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s", accountName(targetUser, targetHost), quoteString(newPassword))
+}
+
+// SetAccountLocked locks or unlocks a MySQL account
+func SetAccountLocked(targetUser, targetHost string, locked bool, cfg database.Config) error {
+	return execAccountStatement(AccountLockStatement(targetUser, targetHost, locked), cfg)
+}
+
+// AccountLockStatement returns the statement SetAccountLocked runs
+func AccountLockStatement(targetUser, targetHost string, locked bool) string {
+	action := "UNLOCK"
+	if locked {
+		action = "LOCK"
+	}
+	return fmt.Sprintf("ALTER USER %s ACCOUNT %s", accountName(targetUser, targetHost), action)
+}
+
+// GetUserGrants returns the SHOW GRANTS output for an account, raw and parsed
+func GetUserGrants(targetUser, targetHost string, cfg database.Config) (*models.UserGrants, error) {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	account := accountName(targetUser, targetHost)
+	statements, err := showGrants(dbConn, "SHOW GRANTS FOR "+account)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUserGrants(targetUser+"@"+targetHost, statements), nil
+}
+
+// GrantPrivileges grants privileges to an account
+func GrantPrivileges(targetUser, targetHost string, req models.PrivilegeRequest, cfg database.Config) error {
+	statement, err := PrivilegeStatement(targetUser, targetHost, req, true)
+	if err != nil {
+		return err
+	}
+	return execAccountStatement(statement, cfg)
+}
+
+// RevokePrivileges revokes privileges from an account
+func RevokePrivileges(targetUser, targetHost string, req models.PrivilegeRequest, cfg database.Config) error {
+	statement, err := PrivilegeStatement(targetUser, targetHost, req, false)
+	if err != nil {
+		return err
+	}
+	return execAccountStatement(statement, cfg)
+}
+
+// PrivilegeStatement returns the statement GrantPrivileges, or RevokePrivileges
+// when grant is false, runs
+func PrivilegeStatement(targetUser, targetHost string, req models.PrivilegeRequest, grant bool) (string, error) {
+	privileges, target, err := buildPrivilegeClause(req)
+	if err != nil {
+		return "", err
+	}
+	if !grant {
+		return fmt.Sprintf("REVOKE %s ON %s FROM %s", privileges, target, accountName(targetUser, targetHost)), nil
+	}
+	statement := fmt.Sprintf("GRANT %s ON %s TO %s", privileges, target, accountName(targetUser, targetHost))
+	if req.WithGrantOption {
+		statement += " WITH GRANT OPTION"
+	}
+	return statement, nil
+}
+
+// buildPrivilegeClause validates a privilege request and renders its privilege list
+// and ON target
+func buildPrivilegeClause(req models.PrivilegeRequest) (string, string, error) {
+	if len(req.Privileges) == 0 {
+		return "", "", fmt.Errorf("%w: at least one privilege is required", ErrInvalidInput)
+	}
+	if req.Table != "" && req.Database == "" {
+		return "", "", fmt.Errorf("%w: database is required for table privileges", ErrInvalidInput)
+	}
+	if len(req.Columns) > 0 && req.Table == "" {
+		return "", "", fmt.Errorf("%w: table is required for column privileges", ErrInvalidInput)
+	}
+
+	global := req.Database == ""
+	var columnList string
+	if len(req.Columns) > 0 {
+		quoted := make([]string, len(req.Columns))
+		for i, col := range req.Columns {
+			quoted[i] = quoteIdentifier(col)
+		}
+		columnList = " (" + strings.Join(quoted, ", ") + ")"
+	}
+
+	privileges := make([]string, 0, len(req.Privileges))
+	for _, p := range req.Privileges {
+		name := strings.Join(strings.Fields(strings.ToUpper(p)), " ")
+		switch {
+		case staticPrivileges[name]:
+		case global && dynamicPrivilegePattern.MatchString(name):
+		default:
+			return "", "", fmt.Errorf("%w: unknown privilege %q", ErrInvalidInput, p)
+		}
+		if !global && globalOnlyPrivileges[name] {
+			return "", "", fmt.Errorf("%w: %s can only be granted globally", ErrInvalidInput, name)
+		}
+		if columnList != "" {
+			if !columnPrivileges[name] {
+				return "", "", fmt.Errorf("%w: %s cannot be granted on columns", ErrInvalidInput, name)
+			}
+			name += columnList
+		}
+		privileges = append(privileges, name)
+	}
+
+	target := "*.*"
+	switch {
+	case req.Table != "":
+		target = quoteIdentifier(req.Database) + "." + quoteIdentifier(req.Table)
+	case req.Database != "":
+		target = quoteIdentifier(req.Database) + ".*"
+	}
+
+	return strings.Join(privileges, ", "), target, nil
+}
+
+// showGrants runs a SHOW GRANTS statement and returns its lines
+func showGrants(dbConn *sql.DB, statement string) ([]string, error) {
+	rows, err := dbConn.Query(statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		statements = append(statements, line)
+	}
+	return statements, rows.Err()
+}
+
+// newUserGrants parses SHOW GRANTS lines into a UserGrants response
+func newUserGrants(account string, statements []string) *models.UserGrants {
+	result := &models.UserGrants{
+		Account: account,
+		Grants:  []models.Grant{},
+		Raw:     statements,
+	}
+	for _, statement := range statements {
+		if grant, ok := ParseGrant(statement); ok {
+			result.Grants = append(result.Grants, grant)
+		}
+	}
+	return result
+}
+
+// execAccountStatement executes an account management statement
+func execAccountStatement(statement string, cfg database.Config) error {
+	dbConn, err := database.ConnectToDatabase("", cfg)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	_, err = dbConn.Exec(statement)
+	return err
+}
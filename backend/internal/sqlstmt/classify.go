@@ -0,0 +1,638 @@
+// Package sqlstmt classifies MySQL statements by what they do, for access checks
+// made before a statement reaches the server.
+package sqlstmt
+
+import "strings"
+
+// Category groups statements by their effect
+type Category string
+
+// Statement categories, from least to most privileged
+const (
+	// Read statements only read data or metadata
+	Read Category = "read"
+	// Session statements change session state such as the default database
+	Session Category = "session"
+	// Transaction statements control transactions and table locks
+	Transaction Category = "transaction"
+	// Write statements modify rows
+	Write Category = "write"
+	// DDL statements create, change or remove schema objects
+	DDL Category = "ddl"
+	// Admin statements manage accounts, privileges and the server
+	Admin Category = "admin"
+	// Other statements cannot be classified, such as dynamic SQL
+	Other Category = "other"
+)
+
+// Statement is a classified SQL statement
+type Statement struct {
+	// Text is the statement without its terminating semicolon
+	Text string
+	// Keyword is the leading keyword, e.g. DROP
+	Keyword string
+	// Kind is the keyword with the object type where it matters, e.g. DROP TABLE
+	Kind     string
+	Category Category
+	// Schemas lists the databases the statement refers to explicitly
+	Schemas []string
+	// UsesDefault reports whether the statement refers to tables of the default
+	// database
+	UsesDefault bool
+	// Tables lists the tables and views the statement names; Schema is empty for
+	// tables of the default database
+	Tables []Table
+	// HasWhere reports whether the statement has a WHERE clause outside subqueries
+	HasWhere bool
+}
+
+// Table is a table or view named by a statement
+type Table struct {
+	Schema string
+	Name   string
+}
+
+// objectTypes are the schema object keywords following CREATE, ALTER and DROP
+var objectTypes = map[string]bool{
+	"DATABASE": true, "SCHEMA": true, "TABLE": true, "VIEW": true, "INDEX": true,
+	"USER": true, "ROLE": true, "PROCEDURE": true, "FUNCTION": true, "TRIGGER": true,
+	"EVENT": true, "TABLESPACE": true, "SERVER": true, "LOGFILE": true, "RESOURCE": true,
+	"INSTANCE": true, "UNDO": true,
+}
+
+// adminObjects are object types whose management requires administrative access
+var adminObjects = map[string]bool{
+	"USER": true, "ROLE": true, "SERVER": true, "TABLESPACE": true, "LOGFILE": true,
+	"RESOURCE": true, "INSTANCE": true, "UNDO": true,
+}
+
+// keywordCategories maps leading keywords to their category
+var keywordCategories = map[string]Category{
+	"SELECT": Read, "SHOW": Read, "DESCRIBE": Read, "DESC": Read, "EXPLAIN": Read,
+	"HELP": Read, "TABLE": Read, "VALUES": Read, "DO": Read, "ANALYZE": Read,
+	"CHECK": Read, "CHECKSUM": Read,
+	"USE":   Session,
+	"BEGIN": Transaction, "COMMIT": Transaction, "ROLLBACK": Transaction,
+	"SAVEPOINT": Transaction, "RELEASE": Transaction, "XA": Transaction,
+	"LOCK": Transaction, "UNLOCK": Transaction,
+	"INSERT": Write, "UPDATE": Write, "DELETE": Write, "REPLACE": Write,
+	"LOAD": Write, "CALL": Write, "HANDLER": Write, "IMPORT": Write,
+	"CREATE": DDL, "ALTER": DDL, "DROP": DDL, "RENAME": DDL, "TRUNCATE": DDL,
+	"OPTIMIZE": DDL, "REPAIR": DDL,
+	"GRANT": Admin, "REVOKE": Admin, "KILL": Admin, "SHUTDOWN": Admin,
+	"RESTART": Admin, "FLUSH": Admin, "RESET": Admin, "PURGE": Admin,
+	"INSTALL": Admin, "UNINSTALL": Admin, "CHANGE": Admin, "STOP": Admin,
+	"CLONE": Admin, "BINLOG": Admin, "CACHE": Admin,
+}
+
+// Parse splits a query into statements and classifies each of them. Empty
+// statements are skipped.
+func Parse(query string) []Statement {
+	var statements []Statement
+	defaultUsed := ""
+	for _, tokens := range splitStatements(tokenize(query)) {
+		stmt := classify(tokens)
+		// A USE earlier in the batch makes that database the default
+		if defaultUsed != "" && stmt.UsesDefault {
+			stmt.Schemas = appendSchema(stmt.Schemas, defaultUsed)
+			stmt.UsesDefault = false
+			for i := range stmt.Tables {
+				if stmt.Tables[i].Schema == "" {
+					stmt.Tables[i].Schema = defaultUsed
+				}
+			}
+		}
+		if stmt.Keyword == "USE" && len(stmt.Schemas) > 0 {
+			defaultUsed = stmt.Schemas[0]
+		}
+		statements = append(statements, stmt)
+	}
+	return statements
+}
+
+// Classify classifies a single statement, such as a representative statement
+// for an API operation
+func Classify(query string) Statement {
+	statements := Parse(query)
+	if len(statements) == 0 {
+		return Statement{Category: Other}
+	}
+	return statements[0]
+}
+
+// classify classifies the tokens of one statement
+func classify(tokens []token) Statement {
+	stmt := Statement{Text: joinTokens(tokens), Category: Other}
+	if len(tokens) == 0 || tokens[0].kind != wordToken {
+		return stmt
+	}
+
+	stmt.Keyword = tokens[0].upper()
+	stmt.Kind = stmt.Keyword
+	if category, ok := keywordCategories[stmt.Keyword]; ok {
+		stmt.Category = category
+	}
+
+	switch stmt.Keyword {
+	case "CREATE", "ALTER", "DROP", "RENAME":
+		if object := objectType(tokens[1:]); object != "" {
+			stmt.Kind += " " + object
+			if adminObjects[object] {
+				stmt.Category = Admin
+			}
+		}
+	case "WITH":
+		stmt.Category = Read
+		if topLevelWordIn(tokens, "UPDATE", "DELETE", "INSERT", "REPLACE") {
+			stmt.Category = Write
+		}
+	case "EXPLAIN", "DESCRIBE", "DESC":
+		// EXPLAIN ANALYZE executes the explained statement
+		if len(tokens) > 2 && tokens[1].upper() == "ANALYZE" {
+			stmt.Kind = stmt.Keyword + " ANALYZE"
+			stmt.Category = classify(tokens[2:]).Category
+		}
+	case "SET":
+		stmt.Kind, stmt.Category = classifySet(tokens[1:])
+	case "SHOW":
+		if len(tokens) > 1 && tokens[1].upper() == "CREATE" {
+			stmt.Kind, stmt.Category = classifyShowCreate(tokens[2:])
+		}
+	case "START":
+		stmt.Category = Admin
+		if len(tokens) > 1 && tokens[1].upper() == "TRANSACTION" {
+			stmt.Kind, stmt.Category = "START TRANSACTION", Transaction
+		}
+	case "LOCK":
+		if len(tokens) > 1 && tokens[1].upper() == "INSTANCE" {
+			stmt.Kind, stmt.Category = "LOCK INSTANCE", Admin
+		}
+	}
+
+	// SELECT, WITH ... SELECT, TABLE and the like write files on the server with
+	// INTO OUTFILE and INTO DUMPFILE
+	if intoFile(tokens) {
+		stmt.Category = Admin
+	}
+
+	stmt.Schemas, stmt.Tables, stmt.UsesDefault = references(tokens)
+	stmt.HasWhere = topLevelWordIn(tokens, "WHERE")
+	return stmt
+}
+
+// intoFile reports whether a statement writes its result to a file on the server
+func intoFile(tokens []token) bool {
+	return containsSequence(tokens, "INTO", "OUTFILE") || containsSequence(tokens, "INTO", "DUMPFILE")
+}
+
+// objectType returns the object keyword of a CREATE, ALTER or DROP statement,
+// skipping modifiers such as OR REPLACE, TEMPORARY or DEFINER = ...
+func objectType(tokens []token) string {
+	for i, t := range tokens {
+		if i > 12 {
+			break
+		}
+		if t.kind == wordToken && objectTypes[t.upper()] {
+			return t.upper()
+		}
+	}
+	return ""
+}
+
+// quotingVariables are session variables that change how the server reads quotes,
+// backslashes and multibyte characters. Statements are classified and literals
+// quoted assuming the rules set up at connect time, so changing them is reserved
+// to administrators.
+var quotingVariables = map[string]bool{
+	"SQL_MODE": true, "CHARACTER_SET_CLIENT": true, "CHARACTER_SET_CONNECTION": true,
+	"CHARACTER_SET_RESULTS": true, "COLLATION_CONNECTION": true,
+}
+
+// classifySet classifies a SET statement by its scope and the variables it sets.
+// Each assignment of a list may have its own scope, as in SET @a = 1, GLOBAL x = 1.
+func classifySet(tokens []token) (string, Category) {
+	if len(tokens) == 0 {
+		return "SET", Session
+	}
+	switch first := tokens[0].upper(); first {
+	case "GLOBAL", "PERSIST", "PERSIST_ONLY", "PASSWORD":
+		return "SET " + first, Admin
+	case "DEFAULT":
+		return "SET DEFAULT ROLE", Admin
+	case "TRANSACTION":
+		return "SET TRANSACTION", Transaction
+	case "ROLE":
+		return "SET ROLE", Session
+	case "NAMES", "CHARSET":
+		return "SET " + first, Admin
+	case "CHARACTER":
+		return "SET CHARACTER SET", Admin
+	}
+	for i, t := range tokens {
+		// GLOBAL x, PERSIST x and PERSIST_ONLY x after a comma
+		if i > 0 && tokens[i-1].text == "," && t.kind == wordToken {
+			switch scope := t.upper(); scope {
+			case "GLOBAL", "PERSIST", "PERSIST_ONLY":
+				return "SET " + scope, Admin
+			}
+		}
+		// @@global.x and @@persist.x in a variable list
+		if t.kind == variableToken {
+			name := strings.ToUpper(t.text)
+			if strings.HasPrefix(name, "@@GLOBAL.") || strings.HasPrefix(name, "@@PERSIST") {
+				return "SET GLOBAL", Admin
+			}
+		}
+		if name := setVariable(tokens, i); quotingVariables[name] {
+			return "SET " + name, Admin
+		}
+	}
+	return "SET", Session
+}
+
+// setVariable returns the upper-case name of the system variable assigned at
+// index i, without @@ and a SESSION or LOCAL scope, or "" when no variable is
+// assigned there
+func setVariable(tokens []token, i int) string {
+	if i+1 >= len(tokens) || (tokens[i+1].text != "=" && tokens[i+1].text != ":") {
+		return ""
+	}
+	t := tokens[i]
+	switch t.kind {
+	case wordToken, quotedIdentifierToken:
+		return strings.ToUpper(t.text)
+	case variableToken:
+		name := strings.ToUpper(t.text)
+		if !strings.HasPrefix(name, "@@") {
+			return ""
+		}
+		name = strings.TrimPrefix(name, "@@")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "SESSION."), "LOCAL.")
+		return name
+	}
+	return ""
+}
+
+// showCreateObjects are the object types of SHOW CREATE statements naming a
+// [schema.]object; tables and views are also listed in Tables
+var showCreateObjects = map[string]bool{
+	"TABLE": true, "VIEW": true, "TRIGGER": true, "PROCEDURE": true,
+	"FUNCTION": true, "EVENT": true,
+}
+
+// classifyShowCreate classifies SHOW CREATE by its object type. Statements whose
+// object cannot be resolved to a database are Other, so that only administrators
+// may run them.
+func classifyShowCreate(tokens []token) (string, Category) {
+	if len(tokens) == 0 || tokens[0].kind != wordToken {
+		return "SHOW CREATE", Other
+	}
+	object := tokens[0].upper()
+	kind := "SHOW CREATE " + object
+	switch {
+	case object == "USER":
+		// Shows the account's authentication string
+		return kind, Admin
+	case object == "DATABASE" || object == "SCHEMA":
+		i := 1
+		for i < len(tokens) && (tokens[i].upper() == "IF" || tokens[i].upper() == "NOT" || tokens[i].upper() == "EXISTS") {
+			i++
+		}
+		if i+1 == len(tokens) && tokens[i].isIdentifier() {
+			return kind, Read
+		}
+	case showCreateObjects[object]:
+		if _, _, next := tableReference(tokens, 1); next > 1 && next == len(tokens) {
+			return kind, Read
+		}
+	}
+	return kind, Other
+}
+
+// tableContexts are keywords followed by one or more table references
+var tableContexts = map[string]bool{
+	"FROM": true, "JOIN": true, "STRAIGHT_JOIN": true, "INTO": true, "UPDATE": true,
+	"TABLE": true, "TABLES": true, "TRUNCATE": true, "TO": true, "CALL": true,
+	"LIKE": true, "VIEW": true, "HANDLER": true,
+}
+
+// schemaContexts are keywords followed by a database name
+var schemaContexts = map[string]bool{
+	"DATABASE": true, "SCHEMA": true, "USE": true,
+}
+
+// aliasStopWords are keywords that can follow a table reference and are
+// therefore not aliases
+var aliasStopWords = map[string]bool{
+	"WHERE": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true,
+	"OUTER": true, "CROSS": true, "NATURAL": true, "STRAIGHT_JOIN": true, "ON": true,
+	"USING": true, "GROUP": true, "ORDER": true, "LIMIT": true, "HAVING": true,
+	"SET": true, "VALUES": true, "VALUE": true, "SELECT": true, "UNION": true,
+	"FOR": true, "LOCK": true, "WINDOW": true, "INTO": true, "PARTITION": true,
+	"USE": true, "IGNORE": true, "FORCE": true, "TO": true, "READ": true,
+	"WRITE": true, "LOW_PRIORITY": true, "AS": true, "WITH": true, "EXCEPT": true,
+	"INTERSECT": true, "FROM": true, "ADD": true, "DROP": true, "MODIFY": true,
+	"CHANGE": true, "RENAME": true, "ENGINE": true, "LIKE": true, "IF": true,
+	"CASCADE": true, "RESTRICT": true, "WAIT": true, "NOWAIT": true, "FIELDS": true,
+	"COLUMNS": true, "LINES": true, "CHARACTER": true, "ROWS": true, "DEFAULT": true,
+	"COLLATE": true, "COMMENT": true, "ALGORITHM": true, "ON_DUPLICATE": true,
+}
+
+// references collects the databases and tables a statement names and whether it
+// refers to unqualified tables
+func references(tokens []token) ([]string, []Table, bool) {
+	var schemas []string
+	var tables []Table
+	usesDefault := false
+	isShow := len(tokens) > 0 && tokens[0].upper() == "SHOW"
+
+	if len(tokens) > 0 && (tokens[0].upper() == "GRANT" || tokens[0].upper() == "REVOKE") {
+		return privilegeLevel(tokens)
+	}
+
+	// DESCRIBE t, DESC t and EXPLAIN t name a table right away
+	start := 0
+	if len(tokens) > 1 && isDescribe(tokens[0].upper()) && tokens[1].isIdentifier() && !explainedKeywords[tokens[1].upper()] {
+		schema, qualified, next := tableReference(tokens, 1)
+		if qualified {
+			schemas = appendSchema(schemas, schema)
+		} else if next > 1 {
+			usesDefault = true
+		}
+		if next > 1 {
+			tables = appendTable(tables, tokens, 1, schema)
+		}
+		start = next
+	}
+
+	// SHOW CREATE TABLE t and the like name an object right away
+	if isShow && len(tokens) > 3 && tokens[1].upper() == "CREATE" && showCreateObjects[tokens[2].upper()] {
+		schema, qualified, next := tableReference(tokens, 3)
+		if qualified {
+			schemas = appendSchema(schemas, schema)
+		} else if next > 3 {
+			usesDefault = true
+		}
+		if next > 3 && (tokens[2].upper() == "TABLE" || tokens[2].upper() == "VIEW") {
+			tables = appendTable(tables, tokens, 3, schema)
+		}
+		start = next
+	}
+
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		// A qualified routine call such as other.f() names its database
+		if t.isIdentifier() && i+3 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].isIdentifier() && tokens[i+3].text == "(" && (i == 0 || tokens[i-1].text != ".") {
+			schemas = appendSchema(schemas, t.text)
+			i += 3
+			continue
+		}
+		if t.kind != wordToken {
+			continue
+		}
+		word := t.upper()
+
+		if schemaContexts[word] && i+1 < len(tokens) && tokens[i+1].isIdentifier() {
+			// Skip IF [NOT] EXISTS
+			j := i + 1
+			for j < len(tokens) && (tokens[j].upper() == "IF" || tokens[j].upper() == "NOT" || tokens[j].upper() == "EXISTS") {
+				j++
+			}
+			if j < len(tokens) && tokens[j].isIdentifier() {
+				schemas = appendSchema(schemas, tokens[j].text)
+				i = j
+			}
+			continue
+		}
+
+		// SHOW ... FROM db and SHOW ... IN db name a database unless qualified
+		if isShow && (word == "FROM" || word == "IN") {
+			schema, qualified, next := tableReference(tokens, i+1)
+			if next > i+1 {
+				if qualified {
+					schemas = appendSchema(schemas, schema)
+				} else if showNamesDatabase(tokens) || lastFromIn(tokens, i) {
+					schemas = appendSchema(schemas, tokens[i+1].text)
+				} else {
+					usesDefault = true
+				}
+				i = next - 1
+			}
+			continue
+		}
+
+		if !tableContexts[word] || isShow {
+			continue
+		}
+		// INTO OUTFILE 'file' and INTO DUMPFILE 'file' name no table
+		if word == "INTO" && i+1 < len(tokens) && (tokens[i+1].upper() == "OUTFILE" || tokens[i+1].upper() == "DUMPFILE") {
+			continue
+		}
+
+		// Parse a comma-separated list of table references
+		j := i + 1
+		for j < len(tokens) {
+			// Skip IF [NOT] EXISTS and modifiers
+			for j < len(tokens) && tokens[j].kind == wordToken && (tokens[j].upper() == "IF" || tokens[j].upper() == "NOT" || tokens[j].upper() == "EXISTS" || tokens[j].upper() == "TABLE") {
+				j++
+			}
+			schema, qualified, next := tableReference(tokens, j)
+			if next == j {
+				break
+			}
+			if qualified {
+				schemas = appendSchema(schemas, schema)
+			} else {
+				usesDefault = true
+			}
+			if word != "CALL" {
+				tables = appendTable(tables, tokens, j, schema)
+			}
+			j = skipAlias(tokens, next)
+			if j < len(tokens) && tokens[j].text == "," {
+				j++
+				continue
+			}
+			break
+		}
+		i = j - 1
+	}
+	return schemas, tables, usesDefault
+}
+
+// privilegeLevel returns the database and table of the ON clause of GRANT and
+// REVOKE. Privileges on all databases (*.*) are reported as the schema "*", which
+// only a "*" schema pattern allows. Grants of roles and proxies refer to none.
+func privilegeLevel(tokens []token) ([]string, []Table, bool) {
+	i := 1
+	for i < len(tokens) && tokens[i].upper() != "ON" {
+		if tokens[i].upper() == "PROXY" {
+			return nil, nil, false
+		}
+		i++
+	}
+	i++
+	if i < len(tokens) && (tokens[i].upper() == "TABLE" || tokens[i].upper() == "FUNCTION" || tokens[i].upper() == "PROCEDURE") {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil, nil, false
+	}
+
+	qualified := i+2 < len(tokens) && tokens[i+1].text == "."
+	switch {
+	case tokens[i].text == "*" && qualified:
+		return []string{"*"}, nil, false
+	case tokens[i].text == "*":
+		return nil, nil, true
+	case !tokens[i].isIdentifier():
+		return nil, nil, false
+	case qualified && tokens[i+2].text == "*":
+		return []string{tokens[i].text}, nil, false
+	case qualified:
+		return []string{tokens[i].text}, []Table{{Schema: tokens[i].text, Name: tokens[i+2].text}}, false
+	}
+	return nil, []Table{{Name: tokens[i].text}}, true
+}
+
+// explainedKeywords start the statement or options following EXPLAIN rather than
+// a table name
+var explainedKeywords = map[string]bool{
+	"SELECT": true, "TABLE": true, "WITH": true, "INSERT": true, "UPDATE": true,
+	"DELETE": true, "REPLACE": true, "ANALYZE": true, "FORMAT": true, "FOR": true,
+	"EXTENDED": true, "PARTITIONS": true, "VALUES": true,
+}
+
+// isDescribe reports whether a keyword starts a DESCRIBE or EXPLAIN statement
+func isDescribe(word string) bool {
+	return word == "DESCRIBE" || word == "DESC" || word == "EXPLAIN"
+}
+
+// showNamesDatabase reports whether a SHOW statement lists objects of a database,
+// such as SHOW TABLES FROM db, rather than columns of a table
+func showNamesDatabase(tokens []token) bool {
+	for _, t := range tokens[1:] {
+		switch t.upper() {
+		case "TABLES", "TRIGGERS", "EVENTS", "STATUS":
+			return true
+		case "FROM", "IN":
+			return false
+		}
+	}
+	return false
+}
+
+// lastFromIn reports whether a FROM or IN at index i follows an earlier one, as in
+// SHOW COLUMNS FROM t FROM db
+func lastFromIn(tokens []token, i int) bool {
+	for _, t := range tokens[1:i] {
+		if w := t.upper(); w == "FROM" || w == "IN" {
+			return true
+		}
+	}
+	return false
+}
+
+// tableReference parses [schema.]name at index i and returns the schema, whether
+// the name was qualified and the index after the reference. The index is i when
+// there is no reference, e.g. before a subquery or a function call.
+func tableReference(tokens []token, i int) (string, bool, int) {
+	if i >= len(tokens) || !tokens[i].isIdentifier() {
+		return "", false, i
+	}
+	if tokens[i].kind == wordToken && aliasStopWords[tokens[i].upper()] && tokens[i].upper() != "DEFAULT" {
+		return "", false, i
+	}
+	if i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].isIdentifier() {
+		return tokens[i].text, true, i + 3
+	}
+	if i+1 < len(tokens) && tokens[i+1].text == "(" {
+		// A function call such as JSON_TABLE(...) or a column list
+		if i > 0 && (tokens[i-1].upper() == "INTO" || tokens[i-1].upper() == "TABLE") {
+			return "", false, i + 1
+		}
+		return "", false, i
+	}
+	return "", false, i + 1
+}
+
+// skipAlias skips an optional [AS] alias after a table reference
+func skipAlias(tokens []token, i int) int {
+	if i < len(tokens) && tokens[i].upper() == "AS" {
+		i++
+	}
+	if i < len(tokens) && tokens[i].isIdentifier() && !(tokens[i].kind == wordToken && aliasStopWords[tokens[i].upper()]) {
+		i++
+	}
+	// LOCK TABLES t [AS a] READ [LOCAL] | [LOW_PRIORITY] WRITE
+	for i < len(tokens) && tokens[i].kind == wordToken {
+		switch tokens[i].upper() {
+		case "READ", "WRITE", "LOCAL", "LOW_PRIORITY":
+			i++
+			continue
+		}
+		break
+	}
+	return i
+}
+
+// containsSequence reports whether the words appear consecutively
+func containsSequence(tokens []token, words ...string) bool {
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		match := true
+		for j, w := range words {
+			if tokens[i+j].kind != wordToken || tokens[i+j].upper() != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevelWordIn reports whether one of the words appears outside parentheses
+func topLevelWordIn(tokens []token, words ...string) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case depth == 0 && t.kind == wordToken:
+			for _, w := range words {
+				if t.upper() == w {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// appendTable adds the table referenced at index i once
+func appendTable(tables []Table, tokens []token, i int, schema string) []Table {
+	name := tokens[i].text
+	if schema != "" {
+		name = tokens[i+2].text
+	}
+	for _, t := range tables {
+		if t.Schema == schema && t.Name == name {
+			return tables
+		}
+	}
+	return append(tables, Table{Schema: schema, Name: name})
+}
+
+// appendSchema adds a schema name once
+func appendSchema(schemas []string, schema string) []string {
+	for _, s := range schemas {
+		if s == schema {
+			return schemas
+		}
+	}
+	return append(schemas, schema)
+}
@@ -0,0 +1,177 @@
+package sqlstmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		query       string
+		kind        string
+		category    Category
+		schemas     []string
+		usesDefault bool
+	}{
+		{"SELECT * FROM users", "SELECT", Read, nil, true},
+		{"SELECT * FROM shop.orders o JOIN users u ON o.user = u.id", "SELECT", Read, []string{"shop"}, true},
+		{"select 1", "SELECT", Read, nil, false},
+		{"SELECT * FROM t INTO OUTFILE '/tmp/t'", "SELECT", Admin, nil, true},
+		{"SELECT 1 INTO DUMPFILE '/tmp/t'", "SELECT", Admin, nil, false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x INTO OUTFILE '/tmp/x'", "WITH", Admin, nil, true},
+		{"TABLE t INTO OUTFILE '/tmp/t'", "TABLE", Admin, nil, true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "WITH", Read, nil, true},
+		{"WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)", "WITH", Write, nil, true},
+		{"INSERT INTO shop.orders VALUES (1)", "INSERT", Write, []string{"shop"}, false},
+		{"UPDATE t SET a = 1", "UPDATE", Write, nil, true},
+		{"CALL shop.refund(1)", "CALL", Write, []string{"shop"}, false},
+		{"SELECT other.f()", "SELECT", Read, []string{"other"}, false},
+		{"SELECT `other`.f(id) FROM t", "SELECT", Read, []string{"other"}, true},
+		{"DO other.f(1)", "DO", Read, []string{"other"}, false},
+		{"HANDLER other.t OPEN", "HANDLER", Write, []string{"other"}, false},
+		{"HANDLER t READ FIRST", "HANDLER", Write, nil, true},
+		{"USE shop", "USE", Session, []string{"shop"}, false},
+		{"CREATE TABLE IF NOT EXISTS shop.t (id INT)", "CREATE TABLE", DDL, []string{"shop"}, false},
+		{"CREATE OR REPLACE DEFINER = CURRENT_USER VIEW v AS SELECT 1", "CREATE VIEW", DDL, nil, true},
+		{"DROP DATABASE IF EXISTS shop", "DROP DATABASE", DDL, []string{"shop"}, false},
+		{"RENAME TABLE a.t TO b.t", "RENAME TABLE", DDL, []string{"a", "b"}, false},
+		{"CREATE USER 'bob'@'%' IDENTIFIED BY 'secret'", "CREATE USER", Admin, nil, false},
+		{"ALTER USER bob ACCOUNT LOCK", "ALTER USER", Admin, nil, false},
+		{"GRANT SELECT ON shop.* TO bob", "GRANT", Admin, []string{"shop"}, false},
+		{"GRANT SELECT ON TABLE shop.orders TO bob", "GRANT", Admin, []string{"shop"}, false},
+		{"GRANT ALL ON *.* TO bob", "GRANT", Admin, []string{"*"}, false},
+		{"GRANT SELECT (`id`, `name`) ON `shop`.`orders` TO 'bob'@'%'", "GRANT", Admin, []string{"shop"}, false},
+		{"REVOKE INSERT ON `shop`.* FROM 'bob'@'%'", "REVOKE", Admin, []string{"shop"}, false},
+		{"REVOKE INSERT ON orders FROM bob", "REVOKE", Admin, nil, true},
+		{"GRANT reader TO bob", "GRANT", Admin, nil, false},
+		{"KILL 42", "KILL", Admin, nil, false},
+		{"START TRANSACTION", "START TRANSACTION", Transaction, nil, false},
+		{"START REPLICA", "START", Admin, nil, false},
+		{"LOCK TABLES t READ, shop.u WRITE", "LOCK", Transaction, []string{"shop"}, true},
+		{"LOCK INSTANCE FOR BACKUP", "LOCK INSTANCE", Admin, nil, false},
+		{"PREPARE s FROM 'DROP TABLE t'", "PREPARE", Other, nil, false},
+		{"EXPLAIN SELECT * FROM t", "EXPLAIN", Read, nil, true},
+		{"EXPLAIN ANALYZE DELETE FROM t", "EXPLAIN ANALYZE", Write, nil, true},
+		{"DESCRIBE shop.orders", "DESCRIBE", Read, []string{"shop"}, false},
+		{"DESC orders", "DESC", Read, nil, true},
+		{"SHOW TABLES FROM shop", "SHOW", Read, []string{"shop"}, false},
+		// The table is also looked for in the default database, which errs on the
+		// safe side
+		{"SHOW COLUMNS FROM orders FROM shop", "SHOW", Read, []string{"shop"}, true},
+		{"SHOW COLUMNS FROM shop.orders", "SHOW", Read, []string{"shop"}, false},
+		{"SHOW INDEX FROM orders", "SHOW", Read, nil, true},
+		{"SHOW DATABASES", "SHOW", Read, nil, false},
+		{"SELECT 1 /*! ; DROP TABLE t */", "SELECT", Read, nil, false},
+		{"", "", Other, nil, false},
+		{"(SELECT 1)", "", Other, nil, false},
+
+		// SHOW CREATE names its object; objects that cannot be resolved fail closed
+		{"SHOW CREATE TABLE secret.users", "SHOW CREATE TABLE", Read, []string{"secret"}, false},
+		{"SHOW CREATE TABLE users", "SHOW CREATE TABLE", Read, nil, true},
+		{"SHOW CREATE VIEW `secret`.`v`", "SHOW CREATE VIEW", Read, []string{"secret"}, false},
+		{"SHOW CREATE TRIGGER secret.t", "SHOW CREATE TRIGGER", Read, []string{"secret"}, false},
+		{"SHOW CREATE PROCEDURE secret.p", "SHOW CREATE PROCEDURE", Read, []string{"secret"}, false},
+		{"SHOW CREATE DATABASE secret", "SHOW CREATE DATABASE", Read, []string{"secret"}, false},
+		{"SHOW CREATE USER root", "SHOW CREATE USER", Admin, nil, false},
+		{"SHOW CREATE TABLE", "SHOW CREATE TABLE", Other, nil, false},
+		{"SHOW CREATE TABLE a b", "SHOW CREATE TABLE", Other, nil, true},
+		{"SHOW CREATE SOMETHING x", "SHOW CREATE SOMETHING", Other, nil, false},
+
+		// SET is classified by scope and by the variables it changes
+		{"SET autocommit = 0", "SET", Session, nil, false},
+		{"SET @a = 'sql_mode'", "SET", Session, nil, false},
+		{"SET GLOBAL max_connections = 10", "SET GLOBAL", Admin, nil, false},
+		{"SET @@global.max_connections = 10", "SET GLOBAL", Admin, nil, false},
+		{"SET PERSIST max_connections = 10", "SET PERSIST", Admin, nil, false},
+		{"SET PASSWORD = 'x'", "SET PASSWORD", Admin, nil, false},
+		{"SET TRANSACTION ISOLATION LEVEL READ COMMITTED", "SET TRANSACTION", Transaction, nil, false},
+		{"SET sql_mode = 'ANSI_QUOTES'", "SET SQL_MODE", Admin, nil, false},
+		{"SET SESSION sql_mode = ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET LOCAL sql_mode = ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET @@sql_mode = ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET @@session.sql_mode := ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET `sql_mode` = ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET @a = 1, sql_mode = ''", "SET SQL_MODE", Admin, nil, false},
+		{"SET @a=1, GLOBAL max_connections=1", "SET GLOBAL", Admin, nil, false},
+		{"SET sql_select_limit=1, PERSIST x=1", "SET PERSIST", Admin, nil, false},
+		{"SET autocommit=0, PERSIST_ONLY x=1", "SET PERSIST_ONLY", Admin, nil, false},
+		{"SET @a=1, SESSION sql_mode=''", "SET SQL_MODE", Admin, nil, false},
+		{"SET NAMES gbk", "SET NAMES", Admin, nil, false},
+		{"SET CHARACTER SET gbk", "SET CHARACTER SET", Admin, nil, false},
+		{"SET character_set_client = gbk", "SET CHARACTER_SET_CLIENT", Admin, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Classify(tt.query)
+			if got.Kind != tt.kind || got.Category != tt.category {
+				t.Errorf("Classify(%q) = %q %s, want %q %s", tt.query, got.Kind, got.Category, tt.kind, tt.category)
+			}
+			if !reflect.DeepEqual(got.Schemas, tt.schemas) || got.UsesDefault != tt.usesDefault {
+				t.Errorf("Classify(%q) schemas = %q default %v, want %q default %v", tt.query, got.Schemas, got.UsesDefault, tt.schemas, tt.usesDefault)
+			}
+		})
+	}
+}
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []Table
+	}{
+		{"SELECT * FROM shop.orders o JOIN users u ON o.user = u.id", []Table{{"shop", "orders"}, {"", "users"}}},
+		{"INSERT INTO logs (msg) VALUES ('x')", []Table{{"", "logs"}}},
+		{"SELECT * FROM t INTO OUTFILE '/tmp/t'", []Table{{"", "t"}}},
+		{"TABLE shop.t INTO DUMPFILE '/tmp/t'", []Table{{"shop", "t"}}},
+		{"SELECT a INTO @a FROM t", []Table{{"", "t"}}},
+		{"HANDLER other.t OPEN AS h", []Table{{"other", "t"}}},
+		{"SELECT other.f(), t.a FROM t", []Table{{"", "t"}}},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.query).Tables; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Classify(%q).Tables = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseUse(t *testing.T) {
+	statements := Parse("SELECT * FROM a; USE shop; SELECT * FROM orders JOIN other.t; DELETE FROM logs")
+	if len(statements) != 4 {
+		t.Fatalf("Parse returned %d statements, want 4", len(statements))
+	}
+
+	// Statements after USE refer to that database rather than the default one
+	want := []struct {
+		schemas     []string
+		usesDefault bool
+		tables      []Table
+	}{
+		{nil, true, []Table{{"", "a"}}},
+		{[]string{"shop"}, false, nil},
+		{[]string{"other", "shop"}, false, []Table{{"shop", "orders"}, {"other", "t"}}},
+		{[]string{"shop"}, false, []Table{{"shop", "logs"}}},
+	}
+	for i, w := range want {
+		got := statements[i]
+		if !reflect.DeepEqual(got.Schemas, w.schemas) || got.UsesDefault != w.usesDefault || !reflect.DeepEqual(got.Tables, w.tables) {
+			t.Errorf("statement %d %q: schemas %q default %v tables %v, want %q %v %v", i, got.Text, got.Schemas, got.UsesDefault, got.Tables, w.schemas, w.usesDefault, w.tables)
+		}
+	}
+}
+
+func TestHasWhere(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"DELETE FROM t WHERE id = 1", true},
+		{"DELETE FROM t", false},
+		{"UPDATE t SET a = (SELECT b FROM u WHERE u.id = t.id)", false},
+		{"UPDATE t SET a = 1 WHERE id IN (SELECT id FROM u)", true},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.query).HasWhere; got != tt.want {
+			t.Errorf("Classify(%q).HasWhere = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
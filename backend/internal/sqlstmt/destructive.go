@@ -0,0 +1,46 @@
+package sqlstmt
+
+// alterDataClauses are ALTER TABLE clauses that can lose rows or column data
+var alterDataClauses = map[string]bool{
+	"MODIFY": true, "CHANGE": true, "TRUNCATE": true, "CONVERT": true,
+	"DISCARD": true, "REORGANIZE": true, "COALESCE": true, "REMOVE": true,
+}
+
+// alterDropKeepsData are the objects whose removal in ALTER TABLE keeps all data
+var alterDropKeepsData = map[string]bool{
+	"INDEX": true, "KEY": true, "FOREIGN": true, "PRIMARY": true, "CONSTRAINT": true,
+	"CHECK": true, "DEFAULT": true, "UNIQUE": true, "FULLTEXT": true, "SPATIAL": true,
+}
+
+// DropsData reports whether a statement removes table rows or column data for
+// good: DROP TABLE, TRUNCATE and ALTER TABLE statements that drop, convert or
+// modify columns or partitions
+func (s Statement) DropsData() bool {
+	switch s.Kind {
+	case "DROP TABLE", "TRUNCATE":
+		return true
+	case "ALTER TABLE":
+	default:
+		return false
+	}
+
+	tokens := tokenize(s.Text)
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case depth > 0 || t.kind != wordToken:
+		case alterDataClauses[t.upper()]:
+			return true
+		case t.upper() == "DROP":
+			// DROP [COLUMN] c and DROP PARTITION p lose data, DROP INDEX i does not
+			if i+1 >= len(tokens) || !alterDropKeepsData[tokens[i+1].upper()] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package sqlstmt
+
+import "testing"
+
+func TestDropsData(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"DROP TABLE t", true},
+		{"TRUNCATE TABLE t", true},
+		{"DROP VIEW v", false},
+		{"DROP DATABASE shop", false},
+		{"DELETE FROM t", false},
+		{"ALTER TABLE t DROP COLUMN c", true},
+		{"ALTER TABLE t DROP c", true},
+		{"ALTER TABLE t DROP PARTITION p0", true},
+		{"ALTER TABLE t DROP INDEX i", false},
+		{"ALTER TABLE t DROP FOREIGN KEY fk, DROP PRIMARY KEY", false},
+		{"ALTER TABLE t DROP INDEX i, DROP COLUMN c", true},
+		{"ALTER TABLE t MODIFY c VARCHAR(10)", true},
+		{"ALTER TABLE t CHANGE c d INT", true},
+		{"ALTER TABLE t CONVERT TO CHARACTER SET latin1", true},
+		{"ALTER TABLE t ADD COLUMN c INT", false},
+		{"ALTER TABLE t ADD CONSTRAINT c CHECK (a > 0)", false},
+		{"ALTER TABLE t ADD INDEX i (c), RENAME TO u", false},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.query).DropsData(); got != tt.want {
+			t.Errorf("DropsData(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
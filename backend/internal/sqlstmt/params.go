@@ -0,0 +1,102 @@
+package sqlstmt
+
+import "strings"
+
+// Parameters returns the names of the :name placeholders of a query, each once,
+// in order of first appearance
+func Parameters(query string) []string {
+	_, names := BindNamed(query)
+
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// BindNamed replaces the :name placeholders of a query with ? and returns the
+// name of each placeholder in order, for binding values positionally. Strings,
+// quoted identifiers, comments, user variables and := are left alone.
+func BindNamed(query string) (string, []string) {
+	bound, names, _ := scanPlaceholders(query)
+	return bound, names
+}
+
+// CountPositional returns the number of ? placeholders of a query outside
+// strings, quoted identifiers and comments
+func CountPositional(query string) int {
+	_, _, positional := scanPlaceholders(query)
+	return positional
+}
+
+// scanPlaceholders replaces the :name placeholders of a query with ? and returns
+// their names in order and the number of ? placeholders the query already had
+func scanPlaceholders(query string) (string, []string, int) {
+	var b strings.Builder
+	b.Grow(len(query))
+	var names []string
+	positional := 0
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || isSpace(query[i+2]))):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case strings.HasPrefix(query[i:], "/*") && !strings.HasPrefix(query[i:], "/*!") && !strings.HasPrefix(query[i:], "/*M!"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case c == '\'' || c == '"' || c == '`':
+			_, next := readQuoted(query, i, c)
+			b.WriteString(query[i:next])
+			i = next
+		case c == '@':
+			start := i
+			i++
+			for i < len(query) && (isWordChar(query[i]) || query[i] == '@' || query[i] == '.') {
+				i++
+			}
+			b.WriteString(query[start:i])
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]) && (i == 0 || !isWordChar(query[i-1]) && query[i-1] != ':'):
+			start := i + 1
+			i = start
+			for i < len(query) && isNameChar(query[i]) {
+				i++
+			}
+			names = append(names, query[start:i])
+			b.WriteByte('?')
+		case c == '?':
+			positional++
+			b.WriteByte(c)
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), names, positional
+}
+
+// isNameStart reports whether c can start a placeholder name
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isNameChar reports whether c can be part of a placeholder name
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
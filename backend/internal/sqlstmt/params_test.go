@@ -0,0 +1,77 @@
+package sqlstmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		bound      string
+		names      []string
+		positional int
+	}{
+		{
+			name:  "named placeholders",
+			query: "SELECT * FROM t WHERE a = :a AND b IN (:b, :a)",
+			bound: "SELECT * FROM t WHERE a = ? AND b IN (?, ?)",
+			names: []string{"a", "b", "a"},
+		},
+		{
+			name:       "positional placeholders",
+			query:      "SELECT * FROM t WHERE a = ? AND b = ?",
+			bound:      "SELECT * FROM t WHERE a = ? AND b = ?",
+			positional: 2,
+		},
+		{
+			name:  "strings, identifiers and comments are left alone",
+			query: "SELECT ':a', `:b`, \"?\" -- :c ?\n/* :d ? */ FROM t WHERE x = :x",
+			bound: "SELECT ':a', `:b`, \"?\" -- :c ?\n/* :d ? */ FROM t WHERE x = ?",
+			names: []string{"x"},
+		},
+		{
+			name:  "escaped quotes do not end strings",
+			query: `SELECT 'it\'s :a' WHERE b = :b`,
+			bound: `SELECT 'it\'s :a' WHERE b = ?`,
+			names: []string{"b"},
+		},
+		{
+			name:  "user variables, := and times are not placeholders",
+			query: "SELECT @a := 1, @x:y, '10:30', a::int, t.b:c FROM t",
+			bound: "SELECT @a := 1, @x:y, '10:30', a::int, t.b:c FROM t",
+		},
+		{
+			name:  "executable comments are scanned",
+			query: "SELECT /*! :a */ 1",
+			bound: "SELECT /*! ? */ 1",
+			names: []string{"a"},
+		},
+		{
+			name:  "MariaDB executable comments are scanned",
+			query: "SELECT /*M!100100 :a */ 1",
+			bound: "SELECT /*M!100100 ? */ 1",
+			names: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound, names := BindNamed(tt.query)
+			if bound != tt.bound || !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("BindNamed(%q) = %q, %q; want %q, %q", tt.query, bound, names, tt.bound, tt.names)
+			}
+			if got := CountPositional(tt.query); got != tt.positional {
+				t.Errorf("CountPositional(%q) = %d, want %d", tt.query, got, tt.positional)
+			}
+		})
+	}
+}
+
+func TestParameters(t *testing.T) {
+	got := Parameters("SELECT :b, :a, :b, :a_1")
+	if want := []string{"b", "a", "a_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package sqlstmt
+
+import "strings"
+
+// Redact replaces the string and number literals of a query with ? so that the
+// text can be kept without the data values it contains. Comments are dropped and
+// whitespace is normalized.
+func Redact(query string) string {
+	statements := splitStatements(tokenize(query))
+	texts := make([]string, len(statements))
+	for i, tokens := range statements {
+		redacted := make([]token, len(tokens))
+		for j, t := range tokens {
+			if t.kind == stringToken || t.kind == numberToken {
+				t = token{kind: punctuationToken, text: "?"}
+			}
+			redacted[j] = t
+		}
+		texts[i] = joinTokens(redacted)
+	}
+	return strings.Join(texts, "; ")
+}
+
+// RedactPasswords replaces the passwords of a query with ?: the strings after
+// IDENTIFIED BY, AS or REPLACE, after words ending in PASSWORD such as
+// MASTER_PASSWORD = or PASSWORD(, and the value of SET PASSWORD. The query is
+// returned unchanged, comments and whitespace included, when it has none; the
+// second result reports whether any were found.
+func RedactPasswords(query string) (string, bool) {
+	statements := splitStatements(tokenize(query))
+	texts := make([]string, len(statements))
+	found := false
+	for i, tokens := range statements {
+		redacted := make([]token, len(tokens))
+		copy(redacted, tokens)
+		setPassword := len(tokens) > 1 && tokens[0].upper() == "SET" && tokens[1].upper() == "PASSWORD"
+		identified, assigned := false, false
+		for j, t := range tokens {
+			switch {
+			case t.upper() == "IDENTIFIED":
+				identified = true
+			case t.kind == punctuationToken && t.text == "=":
+				assigned = true
+			case t.kind == stringToken && j > 0 && isPassword(tokens[:j], identified, setPassword && assigned):
+				redacted[j] = token{kind: punctuationToken, text: "?"}
+				found = true
+			}
+		}
+		texts[i] = joinTokens(redacted)
+	}
+	if !found {
+		return query, false
+	}
+	return strings.Join(texts, "; "), true
+}
+
+// isPassword reports whether the string following tokens is a password
+func isPassword(tokens []token, identified, setPassword bool) bool {
+	prev := tokens[len(tokens)-1]
+	switch {
+	case setPassword, strings.HasSuffix(prev.upper(), "PASSWORD"):
+		return true
+	case identified && (prev.upper() == "BY" || prev.upper() == "AS" || prev.upper() == "REPLACE"):
+		return true
+	case prev.kind == punctuationToken && (prev.text == "=" || prev.text == "(") && len(tokens) > 1:
+		return strings.HasSuffix(tokens[len(tokens)-2].upper(), "PASSWORD")
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package sqlstmt
+
+import "strings"
+
+// tokenKind distinguishes the lexical tokens of a statement
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	quotedIdentifierToken
+	stringToken
+	numberToken
+	variableToken
+	punctuationToken
+)
+
+// token is a lexical token of a statement. Quoted identifiers and strings hold
+// their unquoted content.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// upper returns the text of a word in upper case for keyword comparisons
+func (t token) upper() string {
+	if t.kind != wordToken {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+// isIdentifier reports whether the token can name a schema object
+func (t token) isIdentifier() bool {
+	return t.kind == wordToken || t.kind == quotedIdentifierToken
+}
+
+// tokenize splits a query into tokens, dropping comments. The content of
+// executable comments (/*! ... */, and /*M! ... */ on MariaDB) is kept since the
+// server runs it.
+func tokenize(query string) []token {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || isSpace(query[i+2]))):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*!") || strings.HasPrefix(query[i:], "/*M!"):
+			// Executable comment: skip the marker and the optional version number
+			i += strings.Index(query[i:], "!") + 1
+			for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+		case strings.HasPrefix(query[i:], "*/"):
+			// End of an executable comment
+			i += 2
+		case c == '\'' || c == '"':
+			text, next := readQuoted(query, i, c)
+			tokens = append(tokens, token{kind: stringToken, text: text})
+			i = next
+		case c == '`':
+			text, next := readQuoted(query, i, c)
+			tokens = append(tokens, token{kind: quotedIdentifierToken, text: text})
+			i = next
+		case c == '@':
+			start := i
+			i++
+			for i < len(query) && (isWordChar(query[i]) || query[i] == '@' || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: variableToken, text: query[start:i]})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(query) && (isWordChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: numberToken, text: query[start:i]})
+		case isWordChar(c):
+			start := i
+			for i < len(query) && isWordChar(query[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: wordToken, text: query[start:i]})
+		default:
+			tokens = append(tokens, token{kind: punctuationToken, text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// readQuoted reads a quoted string or identifier starting at i and returns its
+// content and the index after the closing quote. Doubled quotes and, except in
+// identifiers, backslash escapes are unescaped.
+func readQuoted(query string, i int, quote byte) (string, int) {
+	var b strings.Builder
+	i++
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == '\\' && quote != '`' && i+1 < len(query):
+			b.WriteByte(query[i+1])
+			i += 2
+		case c == quote && i+1 < len(query) && query[i+1] == quote:
+			b.WriteByte(quote)
+			i += 2
+		case c == quote:
+			return b.String(), i + 1
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), i
+}
+
+// splitStatements splits tokens at semicolons, dropping empty statements
+func splitStatements(tokens []token) [][]token {
+	var statements [][]token
+	start := 0
+	for i, t := range tokens {
+		if t.kind == punctuationToken && t.text == ";" {
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+	return statements
+}
+
+// joinTokens rebuilds a normalized statement text from its tokens
+func joinTokens(tokens []token) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		switch t.kind {
+		case stringToken:
+			parts[i] = "'" + strings.ReplaceAll(t.text, "'", "''") + "'"
+		case quotedIdentifierToken:
+			parts[i] = "`" + strings.ReplaceAll(t.text, "`", "``") + "`"
+		default:
+			parts[i] = t.text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isWordChar reports whether c can be part of an unquoted word
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// isSpace reports whether c is whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,109 @@
+package sqlstmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []token
+	}{
+		{
+			name:  "words, numbers and punctuation",
+			query: "SELECT a, 1.5 FROM t",
+			want: []token{
+				{wordToken, "SELECT"}, {wordToken, "a"}, {punctuationToken, ","},
+				{numberToken, "1.5"}, {wordToken, "FROM"}, {wordToken, "t"},
+			},
+		},
+		{
+			name:  "strings are unescaped",
+			query: `SELECT 'it''s', "a\"b", 'c\\'`,
+			want: []token{
+				{wordToken, "SELECT"}, {stringToken, "it's"}, {punctuationToken, ","},
+				{stringToken, `a"b`}, {punctuationToken, ","}, {stringToken, `c\`},
+			},
+		},
+		{
+			name:  "backslashes do not escape in quoted identifiers",
+			query: "SELECT `a\\`, `b``c`",
+			want: []token{
+				{wordToken, "SELECT"}, {quotedIdentifierToken, `a\`}, {punctuationToken, ","},
+				{quotedIdentifierToken, "b`c"},
+			},
+		},
+		{
+			name:  "comments are dropped",
+			query: "SELECT 1 -- DROP TABLE t\n# DROP TABLE u\n/* DROP TABLE v */",
+			want:  []token{{wordToken, "SELECT"}, {numberToken, "1"}},
+		},
+		{
+			name:  "executable comments are kept",
+			query: "SELECT 1 /*!50000 ; DROP TABLE t */",
+			want: []token{
+				{wordToken, "SELECT"}, {numberToken, "1"}, {punctuationToken, ";"},
+				{wordToken, "DROP"}, {wordToken, "TABLE"}, {wordToken, "t"},
+			},
+		},
+		{
+			name:  "MariaDB executable comments are kept",
+			query: "SELECT 1 /*M!100100 ; DROP TABLE t */",
+			want: []token{
+				{wordToken, "SELECT"}, {numberToken, "1"}, {punctuationToken, ";"},
+				{wordToken, "DROP"}, {wordToken, "TABLE"}, {wordToken, "t"},
+			},
+		},
+		{
+			name:  "a double dash without a space is not a comment",
+			query: "SELECT 1--1",
+			want: []token{
+				{wordToken, "SELECT"}, {numberToken, "1"}, {punctuationToken, "-"},
+				{punctuationToken, "-"}, {numberToken, "1"},
+			},
+		},
+		{
+			name:  "variables",
+			query: "SET @a = @@session.sql_mode",
+			want: []token{
+				{wordToken, "SET"}, {variableToken, "@a"}, {punctuationToken, "="},
+				{variableToken, "@@session.sql_mode"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenize(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{";; SELECT 1 ;;", []string{"SELECT 1"}},
+		// Semicolons in strings, identifiers and comments do not split
+		{`SELECT '\'; DROP TABLE t; -- '`, []string{`SELECT '''; DROP TABLE t; -- '`}},
+		{"SELECT `;` FROM t /* ; */", []string{"SELECT `;` FROM t"}},
+		{"SELECT 1 /*! ; DROP TABLE t */", []string{"SELECT 1", "DROP TABLE t"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, tokens := range splitStatements(tokenize(tt.query)) {
+			got = append(got, joinTokens(tokens))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
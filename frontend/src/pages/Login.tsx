@@ -10,6 +10,7 @@ export default function Login() {
   const [username, setUsername] = useState('')
   const [password, setPassword] = useState('')
   const [database, setDatabase] = useState('mysql')
+  const [readOnly, setReadOnly] = useState(false)
   const navigate = useNavigate()
 
   const loginMutation = useMutation({
@@ -29,6 +30,7 @@ export default function Login() {
         username,
         password,
         database,
+        readOnly,
       })
     }
   }
@@ -123,6 +125,16 @@ export default function Login() {
             />
           </div>
 
+          <label className="flex items-center gap-2 text-sm">
+            <input
+              type="checkbox"
+              checked={readOnly}
+              onChange={(e) => setReadOnly(e.target.checked)}
+              className="rounded border-input"
+            />
+            Read-only connection
+          </label>
+
           {loginMutation.isError && (
             <div className="p-3 bg-destructive/10 border border-destructive/20 rounded-md text-destructive text-sm">
               {loginMutation.error instanceof Error
@@ -1,7 +1,7 @@
 import { useState } from 'react'
 import { useOutletContext } from 'react-router-dom'
 import { useMutation, useQuery } from '@tanstack/react-query'
-import { databaseApi } from '@/services/api'
+import { databaseApi, withConfirmation } from '@/services/api'
 import Editor from '@monaco-editor/react'
 import { Play, Loader2 } from 'lucide-react'
 
@@ -27,7 +27,9 @@ export default function QueryPage() {
       if (!currentDb) {
         throw new Error('Please select a database')
       }
-      return databaseApi.executeQuery({ database: currentDb, query: q })
+      return withConfirmation((confirmToken) =>
+        databaseApi.executeQuery({ database: currentDb, query: q }, confirmToken)
+      )
     },
   })
 
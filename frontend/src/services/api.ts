@@ -1,6 +1,6 @@
 import axios from 'axios'
 
-const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8090/api'
+const API_BASE_URL = import.meta.env.VITE_API_URL || '/api'
 
 const api = axios.create({
   baseURL: API_BASE_URL,
@@ -36,6 +36,7 @@ export interface LoginRequest {
   username: string
   password: string
   database: string
+  readOnly?: boolean
 }
 
 export interface LoginResponse {
@@ -75,9 +76,65 @@ export interface TableStructure {
   indexes: Index[]
 }
 
+export type QueryParamValue = string | number | boolean | null
+
+export type QueryParamType =
+  | 'string'
+  | 'int'
+  | 'decimal'
+  | 'float'
+  | 'bool'
+  | 'date'
+  | 'datetime'
+  | 'time'
+  | 'binary'
+
+export interface QueryParam {
+  name?: string
+  type?: QueryParamType
+  value: QueryParamValue
+}
+
 export interface QueryRequest {
   database: string
   query: string
+  params?: (QueryParam | QueryParamValue)[]
+  // ID of an open transaction to run the query in
+  transaction?: string
+}
+
+export type IsolationLevel =
+  | 'READ UNCOMMITTED'
+  | 'READ COMMITTED'
+  | 'REPEATABLE READ'
+  | 'SERIALIZABLE'
+
+export interface Console {
+  id: string
+  name: string
+  connection: string
+  // Current default database, changed by USE
+  database: string
+  statements: number
+  openedAt: string
+  lastUsedAt: string
+  expiresAt: string
+}
+
+export interface ConsoleQueryResponse extends QueryResponse {
+  console: Console
+}
+
+export interface Transaction {
+  id: string
+  connection: string
+  database: string
+  isolation?: IsolationLevel
+  readOnly: boolean
+  statements: number
+  startedAt: string
+  lastUsedAt: string
+  expiresAt: string
 }
 
 export interface QueryResponse {
@@ -85,6 +142,81 @@ export interface QueryResponse {
   rows: any[][]
   affected: number
   error?: string
+  snapshots?: BackupSnapshot[]
+}
+
+export interface BackupSnapshot {
+  id: string
+  server: string
+  database: string
+  table: string
+  user: string
+  reason: string
+  statement: string
+  rows: number
+  sizeBytes: number
+  createdAt: string
+}
+
+export interface HistoryEntry {
+  id: number
+  query: string
+  database: string
+  connection: string
+  connectionName: string
+  executedAt: string
+  durationMs: number
+  rows: number
+  success: boolean
+  error?: string
+  pinned: boolean
+}
+
+export interface HistoryPage {
+  entries: HistoryEntry[]
+  total: number
+  page: number
+  pageSize: number
+}
+
+export interface HistoryFilter {
+  q?: string
+  database?: string
+  connection?: string
+  pinned?: boolean
+  errors?: boolean
+  page?: number
+  pageSize?: number
+}
+
+export interface QuerySharing {
+  users: string[]
+  roles: string[]
+  everyone: boolean
+}
+
+export interface SavedQuery {
+  id: string
+  name: string
+  description: string
+  tags: string[]
+  query: string
+  database: string
+  parameters: string[]
+  owner: string
+  sharedWith: QuerySharing
+  owned: boolean
+  createdAt: string
+  updatedAt: string
+}
+
+export interface SavedQueryRequest {
+  name: string
+  description?: string
+  tags?: string[]
+  query: string
+  database?: string
+  sharedWith?: Partial<QuerySharing>
 }
 
 export interface TableDataRequest {
@@ -104,6 +236,60 @@ export interface TableDataResponse {
   pageSize: number
 }
 
+// Destructive statements are answered with 428 and a token to repeat them with
+export interface ImpactSummary {
+  statement: string
+  kind: string
+  reason: string
+  objects: {
+    type: string
+    database: string
+    name?: string
+    exists: boolean
+    tables?: number
+    estimatedRows: number
+    sizeBytes: number
+    foreignKeys: string[]
+    views: string[]
+  }[]
+}
+
+export interface ConfirmationRequired {
+  error: string
+  token: string
+  expiresAt: string
+  impact: ImpactSummary[]
+}
+
+const confirmHeaders = (confirmToken?: string) =>
+  confirmToken ? { headers: { 'X-Confirm-Token': confirmToken } } : undefined
+
+// withConfirmation runs a request and, when the server asks for confirmation,
+// shows the impact and repeats the request with the token if the user agrees
+export async function withConfirmation<T>(request: (confirmToken?: string) => Promise<T>): Promise<T> {
+  try {
+    return await request()
+  } catch (error) {
+    if (!axios.isAxiosError(error) || error.response?.status !== 428) {
+      throw error
+    }
+    const confirmation = error.response.data as ConfirmationRequired
+    const lines = confirmation.impact.map((impact) => {
+      const objects = impact.objects.map((o) => {
+        const name = o.name ? `${o.database}.${o.name}` : o.database
+        const deps = [...o.foreignKeys.map((fk) => `FK ${fk}`), ...o.views.map((v) => `view ${v}`)]
+        return `  ${o.type} ${name}: ~${o.estimatedRows} rows, ${o.sizeBytes} bytes` +
+          (deps.length ? `, used by ${deps.join(', ')}` : '')
+      })
+      return [`${impact.kind} ${impact.reason}`, ...objects].join('\n')
+    })
+    if (!window.confirm(`This cannot be undone:\n\n${lines.join('\n\n')}\n\nContinue?`)) {
+      throw new Error('Cancelled')
+    }
+    return request(confirmation.token)
+  }
+}
+
 export const authApi = {
   login: (data: LoginRequest) => api.post<LoginResponse>('/auth/login', data),
 }
@@ -115,11 +301,63 @@ export const databaseApi = {
     api.get<TableStructure>(`/databases/${dbName}/tables/${tableName}`),
   getTableData: (dbName: string, tableName: string, params: { page: number; pageSize: number; sortBy?: string; sortDir?: string }) =>
     api.get<TableDataResponse>(`/databases/${dbName}/tables/${tableName}/data`, { params }),
-  executeQuery: (data: QueryRequest) => api.post<QueryResponse>('/query', data),
+  executeQuery: (data: QueryRequest, confirmToken?: string) =>
+    api.post<QueryResponse>('/query', data, confirmHeaders(confirmToken)),
   createTable: (dbName: string, data: { tableName: string; createSQL: string }) =>
     api.post(`/databases/${dbName}/tables`, data),
-  dropTable: (dbName: string, tableName: string) =>
-    api.delete(`/databases/${dbName}/tables/${tableName}`),
+  dropTable: (dbName: string, tableName: string, confirmToken?: string) =>
+    api.delete(`/databases/${dbName}/tables/${tableName}`, confirmHeaders(confirmToken)),
+  getBackups: (dbName: string, table?: string) =>
+    api.get<BackupSnapshot[]>(`/databases/${dbName}/backups`, { params: { table } }),
+  restoreBackup: (dbName: string, id: string, table?: string) =>
+    api.post(`/databases/${dbName}/backups/${id}/restore`, { table }),
+}
+
+export const historyApi = {
+  getHistory: (filter: HistoryFilter = {}) =>
+    api.get<HistoryPage>('/history', { params: filter }),
+  pin: (id: number) => api.post<HistoryEntry>(`/history/${id}/pin`),
+  unpin: (id: number) => api.post<HistoryEntry>(`/history/${id}/unpin`),
+  deleteEntry: (id: number) => api.delete(`/history/${id}`),
+  clear: (includePinned = false) =>
+    api.delete('/history', { params: { pinned: includePinned } }),
+}
+
+export const savedQueryApi = {
+  list: (filter: { tag?: string; q?: string } = {}) =>
+    api.get<SavedQuery[]>('/saved-queries', { params: filter }),
+  get: (id: string) => api.get<SavedQuery>(`/saved-queries/${id}`),
+  create: (data: SavedQueryRequest) => api.post<SavedQuery>('/saved-queries', data),
+  update: (id: string, data: SavedQueryRequest) =>
+    api.put<SavedQuery>(`/saved-queries/${id}`, data),
+  delete: (id: string) => api.delete(`/saved-queries/${id}`),
+  execute: (
+    id: string,
+    data: { params?: Record<string, QueryParam | QueryParamValue>; database?: string },
+    confirmToken?: string
+  ) =>
+    api.post<QueryResponse>(`/saved-queries/${id}/execute`, data, confirmHeaders(confirmToken)),
+}
+
+export const transactionApi = {
+  list: () => api.get<Transaction[]>('/transactions'),
+  begin: (data: { database: string; isolation?: IsolationLevel; readOnly?: boolean }) =>
+    api.post<Transaction>('/transactions', data),
+  commit: (id: string) => api.post<Transaction>(`/transactions/${id}/commit`),
+  rollback: (id: string) => api.post<Transaction>(`/transactions/${id}/rollback`),
+}
+
+export const consoleApi = {
+  list: () => api.get<Console[]>('/consoles'),
+  open: (data: { name?: string; database?: string } = {}) => api.post<Console>('/consoles', data),
+  get: (id: string) => api.get<Console>(`/consoles/${id}`),
+  close: (id: string) => api.delete(`/consoles/${id}`),
+  execute: (
+    id: string,
+    data: { query: string; params?: (QueryParam | QueryParamValue)[] },
+    confirmToken?: string
+  ) =>
+    api.post<ConsoleQueryResponse>(`/consoles/${id}/query`, data, confirmHeaders(confirmToken)),
 }
 
 export default api